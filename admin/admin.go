@@ -43,7 +43,7 @@ func (d *PostgresDB) Ping() bool {
 // RegisterUser insert new User in Databse
 func (d *PostgresDB) RegisterUser(user *models.User) error {
 	tx := d.database.MustBegin()
-	resInsert, resErr := tx.NamedExec("INSERT INTO public.users (id, username, email, passwd, token, token_expires) VALUES (:id, :username, :email, :passwd, :token, :token_expires) on conflict (email) do nothing ", &user)
+	resInsert, resErr := tx.NamedExec("INSERT INTO public.users (id, username, email, passwd) VALUES (:id, :username, :email, :passwd) on conflict (email) do nothing ", &user)
 	if resErr != nil {
 		return resErr
 	}
@@ -61,7 +61,7 @@ func (d *PostgresDB) RegisterUser(user *models.User) error {
 // LoginUser insert new User in Databse
 func (d *PostgresDB) LoginUser(email string) (*models.User, error) {
 	var user models.User
-	err := d.database.Get(&user, "SELECT id, username, email, passwd, token, token_expires FROM public.users WHERE email=$1", email)
+	err := d.database.Get(&user, "SELECT id, username, email, passwd, disabled FROM public.users WHERE email=$1", email)
 	if err != nil {
 		log.Println(err)
 		return &models.User{}, err
@@ -70,12 +70,12 @@ func (d *PostgresDB) LoginUser(email string) (*models.User, error) {
 }
 
 // GetUserInfo get user credentials from database by username
-func (d *PostgresDB) GetUserInfo(userID uuid.UUID) (*models.Token, error) {
-	var userInfo models.Token
-	err := d.database.Get(&userInfo, "SELECT username, email, token, token_expires FROM public.users WHERE id=$1", userID)
+func (d *PostgresDB) GetUserInfo(userID uuid.UUID) (*models.UserInfo, error) {
+	var userInfo models.UserInfo
+	err := d.database.Get(&userInfo, "SELECT id, username, email, role FROM public.users WHERE id=$1", userID)
 	if err != nil {
 		log.Println(err)
-		return &models.Token{}, err
+		return &models.UserInfo{}, err
 	}
 	return &userInfo, err
 }
@@ -7,8 +7,30 @@ CREATE TABLE if not exists public.users (
     username text,
     email text unique,
     passwd text,
+    -- token and token_expires are left from plain access tokens and are not used
     token text,
     token_expires timestamp,
     created timestamptz default now()
 );
+-- users registered before roles keep access to the full graph, new users are viewers
+ALTER TABLE public.users ADD COLUMN if not exists role text not null default 'analyst';
+ALTER TABLE public.users ALTER COLUMN role SET DEFAULT 'viewer';
+ALTER TABLE public.users ADD COLUMN if not exists disabled timestamptz;
+CREATE TABLE if not exists public.sessions (
+    id uuid primary key,
+    user_id uuid not null references public.users (id) on delete cascade,
+    created timestamptz default now(),
+    expires timestamptz not null,
+    revoked timestamptz
+);
+CREATE INDEX if not exists sessions_user_idx ON public.sessions (user_id);
+CREATE TABLE if not exists public.refresh_tokens (
+    token_hash text primary key,
+    user_id uuid not null references public.users (id) on delete cascade,
+    expires timestamptz not null,
+    used timestamptz,
+    created timestamptz default now()
+);
+CREATE INDEX if not exists refresh_tokens_user_idx ON public.refresh_tokens (user_id);
+ALTER TABLE public.refresh_tokens ADD COLUMN if not exists session_id uuid references public.sessions (id) on delete cascade;
 `
@@ -0,0 +1,188 @@
+package admin
+
+import (
+	"AlexSarva/media/models"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNotValidRefresh error that occurs when refresh token is unknown, expired or its session is revoked
+var ErrNotValidRefresh = errors.New("refresh token is not valid")
+
+// ErrRefreshReused error that occurs when already rotated refresh token is presented again,
+// the session of the token is revoked then
+var ErrRefreshReused = errors.New("refresh token is already used")
+
+// ErrSessionRevoked error that occurs when token belongs to session which is logged out or expired
+var ErrSessionRevoked = errors.New("session is revoked")
+
+// CreateSession starts new session of the user with its first refresh token
+func (d *PostgresDB) CreateSession(userID uuid.UUID, tokenHash string, expires time.Time) (uuid.UUID, error) {
+	tx, txErr := d.database.Beginx()
+	if txErr != nil {
+		return uuid.UUID{}, txErr
+	}
+	defer tx.Rollback()
+
+	sessionID := uuid.New()
+	if _, err := tx.Exec("INSERT INTO public.sessions (id, user_id, expires) VALUES ($1, $2, $3)", sessionID, userID, expires); err != nil {
+		return uuid.UUID{}, err
+	}
+	if _, err := tx.Exec("INSERT INTO public.refresh_tokens (token_hash, user_id, session_id, expires) VALUES ($1, $2, $3, $4)", tokenHash, userID, sessionID, expires); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.UUID{}, err
+	}
+	return sessionID, nil
+}
+
+// RotateRefreshToken replaces refresh token with the new one and returns its session,
+// each refresh token can be used only once. With ErrRefreshReused the revoked session is returned,
+// so callers can forget it
+func (d *PostgresDB) RotateRefreshToken(tokenHash string, newTokenHash string, expires time.Time) (models.Session, error) {
+	tx, txErr := d.database.Beginx()
+	if txErr != nil {
+		return models.Session{}, txErr
+	}
+	defer tx.Rollback()
+
+	var token struct {
+		Expires time.Time    `db:"expires"`
+		Used    sql.NullTime `db:"used"`
+	}
+	var session models.Session
+	err := tx.QueryRowx(`SELECT t.expires, t.used, s.id, s.user_id, s.created, s.expires, s.revoked
+		FROM public.refresh_tokens t
+		JOIN public.sessions s ON s.id = t.session_id
+		WHERE t.token_hash=$1 and s.revoked is null
+		FOR UPDATE`, tokenHash).
+		Scan(&token.Expires, &token.Used, &session.ID, &session.UserID, &session.Created, &session.Expires, &session.Revoked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Session{}, ErrNotValidRefresh
+		}
+		return models.Session{}, err
+	}
+
+	switch checkErr := checkRefresh(token.Expires, token.Used, time.Now()); {
+	case errors.Is(checkErr, ErrRefreshReused):
+		// the token was stolen or replayed, the session is not trusted anymore
+		if err := revokeSessions(tx, "id=$1", session.ID); err != nil {
+			return models.Session{}, err
+		}
+		if err := tx.Commit(); err != nil {
+			return models.Session{}, err
+		}
+		return session, ErrRefreshReused
+	case checkErr != nil:
+		return models.Session{}, checkErr
+	}
+
+	// used tokens are kept with the session to detect a replay of any of them
+	if _, err := tx.Exec("DELETE FROM public.refresh_tokens WHERE session_id=$1 and used is null and token_hash<>$2", session.ID, tokenHash); err != nil {
+		return models.Session{}, err
+	}
+	if _, err := tx.Exec("UPDATE public.refresh_tokens SET used=now() WHERE token_hash=$1", tokenHash); err != nil {
+		return models.Session{}, err
+	}
+	if _, err := tx.Exec(`INSERT INTO public.refresh_tokens (token_hash, user_id, session_id, expires)
+		VALUES ($1, $2, $3, $4)`, newTokenHash, session.UserID, session.ID, expires); err != nil {
+		return models.Session{}, err
+	}
+	if _, err := tx.Exec("UPDATE public.sessions SET expires=$2 WHERE id=$1", session.ID, expires); err != nil {
+		return models.Session{}, err
+	}
+	session.Expires = expires
+
+	if err := tx.Commit(); err != nil {
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+// checkRefresh state of refresh token at now: ErrRefreshReused when the token is already rotated,
+// even if it is expired, ErrNotValidRefresh when it is expired
+func checkRefresh(expires time.Time, used sql.NullTime, now time.Time) error {
+	if used.Valid {
+		return ErrRefreshReused
+	}
+	if !expires.After(now) {
+		return ErrNotValidRefresh
+	}
+	return nil
+}
+
+// CheckSession checks that the session of the user is neither logged out nor expired
+func (d *PostgresDB) CheckSession(userID uuid.UUID, sessionID uuid.UUID) error {
+	var active bool
+	err := d.database.Get(&active, "SELECT revoked is null and expires > now() FROM public.sessions WHERE id=$1 and user_id=$2", sessionID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionRevoked
+		}
+		return err
+	}
+	if !active {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// GetSessionUser user of the session which is neither logged out nor expired, disabled users have no sessions
+func (d *PostgresDB) GetSessionUser(userID uuid.UUID, sessionID uuid.UUID) (*models.UserInfo, error) {
+	var userInfo models.UserInfo
+	err := d.database.Get(&userInfo, `SELECT u.id, u.username, u.email, u.role
+		FROM public.sessions s
+		JOIN public.users u ON u.id = s.user_id
+		WHERE s.id=$1 and s.user_id=$2 and s.revoked is null and s.expires > now() and u.disabled is null`, sessionID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &models.UserInfo{}, ErrSessionRevoked
+		}
+		return &models.UserInfo{}, err
+	}
+	return &userInfo, nil
+}
+
+// RevokeSession logs out one session of the user
+func (d *PostgresDB) RevokeSession(userID uuid.UUID, sessionID uuid.UUID) error {
+	tx, txErr := d.database.Beginx()
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	if err := revokeSessions(tx, "id=$1 and user_id=$2", sessionID, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RevokeSessions logs out all sessions of the user
+func (d *PostgresDB) RevokeSessions(userID uuid.UUID) error {
+	tx, txErr := d.database.Beginx()
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	if err := revokeSessions(tx, "user_id=$1", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// revokeSessions marks sessions matching the condition as revoked and drops their refresh tokens
+func revokeSessions(tx *sqlx.Tx, condition string, args ...interface{}) error {
+	if _, err := tx.Exec("UPDATE public.sessions SET revoked=now() WHERE revoked is null and "+condition, args...); err != nil {
+		return err
+	}
+	_, err := tx.Exec("DELETE FROM public.refresh_tokens WHERE session_id in (SELECT id FROM public.sessions WHERE "+condition+")", args...)
+	return err
+}
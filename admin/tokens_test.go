@@ -0,0 +1,215 @@
+package admin
+
+import (
+	"AlexSarva/media/crypto"
+	"AlexSarva/media/models"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRefresh(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		expires time.Time
+		used    sql.NullTime
+		want    error
+	}{
+		{name: "fresh token", expires: now.Add(time.Hour), want: nil},
+		{name: "expired token", expires: now.Add(-time.Second), want: ErrNotValidRefresh},
+		{name: "token expires right now", expires: now, want: ErrNotValidRefresh},
+		{name: "rotated token", expires: now.Add(time.Hour), used: sql.NullTime{Time: now.Add(-time.Minute), Valid: true}, want: ErrRefreshReused},
+		{name: "rotated and expired token", expires: now.Add(-time.Hour), used: sql.NullTime{Time: now.Add(-2 * time.Hour), Valid: true}, want: ErrRefreshReused},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, checkRefresh(tt.expires, tt.used, now), tt.want)
+		})
+	}
+}
+
+// testDB admin database of DATABASE_PG_URI with a new user, the test is skipped without it
+func testDB(t *testing.T) (*PostgresDB, uuid.UUID) {
+	uri := os.Getenv("DATABASE_PG_URI")
+	if uri == "" {
+		t.Skip("DATABASE_PG_URI is not set")
+	}
+	database := NewAdminDBConnection(uri)
+	user := models.User{ID: uuid.New(), Username: "tokens-test", Password: "-"}
+	user.Email = user.ID.String() + "@tokens.test"
+	if err := database.RegisterUser(&user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		database.database.MustExec("DELETE FROM public.users WHERE id=$1", user.ID)
+		database.database.Close()
+	})
+	return database, user.ID
+}
+
+// newHash hash of a new refresh token
+func newHash(t *testing.T) string {
+	_, hash, err := crypto.NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestSessions(t *testing.T) {
+	database, userID := testDB(t)
+	expires := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name string
+		// run returns the error of the last step and the session it worked with
+		run  func(t *testing.T) (uuid.UUID, error)
+		want error
+		// active the session is still valid after the run
+		active bool
+	}{
+		{
+			name: "new session",
+			run: func(t *testing.T) (uuid.UUID, error) {
+				return database.CreateSession(userID, newHash(t), expires)
+			},
+			active: true,
+		},
+		{
+			name: "rotation keeps the session",
+			run: func(t *testing.T) (uuid.UUID, error) {
+				first := newHash(t)
+				sessionID, err := database.CreateSession(userID, first, expires)
+				assert.NoError(t, err)
+				session, err := database.RotateRefreshToken(first, newHash(t), expires.Add(time.Hour))
+				assert.Equal(t, sessionID, session.ID)
+				assert.Equal(t, userID, session.UserID)
+				return sessionID, err
+			},
+			active: true,
+		},
+		{
+			name: "reuse revokes the session",
+			run: func(t *testing.T) (uuid.UUID, error) {
+				first := newHash(t)
+				second := newHash(t)
+				sessionID, err := database.CreateSession(userID, first, expires)
+				assert.NoError(t, err)
+				_, err = database.RotateRefreshToken(first, second, expires)
+				assert.NoError(t, err)
+				revoked, err := database.RotateRefreshToken(first, newHash(t), expires)
+				assert.ErrorIs(t, err, ErrRefreshReused)
+				assert.Equal(t, sessionID, revoked.ID)
+				// the token issued by the rotation dies with the session
+				_, err = database.RotateRefreshToken(second, newHash(t), expires)
+				return sessionID, err
+			},
+			want: ErrNotValidRefresh,
+		},
+		{
+			name: "reuse of an older token revokes the session",
+			run: func(t *testing.T) (uuid.UUID, error) {
+				first := newHash(t)
+				second := newHash(t)
+				third := newHash(t)
+				sessionID, err := database.CreateSession(userID, first, expires)
+				assert.NoError(t, err)
+				_, err = database.RotateRefreshToken(first, second, expires)
+				assert.NoError(t, err)
+				_, err = database.RotateRefreshToken(second, third, expires)
+				assert.NoError(t, err)
+				revoked, err := database.RotateRefreshToken(first, newHash(t), expires)
+				assert.Equal(t, sessionID, revoked.ID)
+				return sessionID, err
+			},
+			want: ErrRefreshReused,
+		},
+		{
+			name: "expired refresh token",
+			run: func(t *testing.T) (uuid.UUID, error) {
+				token := newHash(t)
+				sessionID, err := database.CreateSession(userID, token, time.Now().Add(-time.Minute))
+				assert.NoError(t, err)
+				_, err = database.RotateRefreshToken(token, newHash(t), expires)
+				return sessionID, err
+			},
+			want: ErrNotValidRefresh,
+		},
+		{
+			name: "unknown refresh token",
+			run: func(t *testing.T) (uuid.UUID, error) {
+				_, err := database.RotateRefreshToken(newHash(t), newHash(t), expires)
+				return uuid.New(), err
+			},
+			want: ErrNotValidRefresh,
+		},
+		{
+			name: "logout",
+			run: func(t *testing.T) (uuid.UUID, error) {
+				token := newHash(t)
+				sessionID, err := database.CreateSession(userID, token, expires)
+				assert.NoError(t, err)
+				assert.NoError(t, database.RevokeSession(userID, sessionID))
+				_, err = database.RotateRefreshToken(token, newHash(t), expires)
+				return sessionID, err
+			},
+			want: ErrNotValidRefresh,
+		},
+		{
+			name: "logout of all sessions",
+			run: func(t *testing.T) (uuid.UUID, error) {
+				other, err := database.CreateSession(userID, newHash(t), expires)
+				assert.NoError(t, err)
+				sessionID, err := database.CreateSession(userID, newHash(t), expires)
+				assert.NoError(t, err)
+				assert.NoError(t, database.RevokeSessions(userID))
+				assert.ErrorIs(t, database.CheckSession(userID, other), ErrSessionRevoked)
+				return sessionID, nil
+			},
+		},
+		{
+			name: "session of another user",
+			run: func(t *testing.T) (uuid.UUID, error) {
+				sessionID, err := database.CreateSession(userID, newHash(t), expires)
+				assert.NoError(t, err)
+				return sessionID, database.CheckSession(uuid.New(), sessionID)
+			},
+			want:   ErrSessionRevoked,
+			active: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionID, err := tt.run(t)
+			assert.ErrorIs(t, err, tt.want)
+
+			checkErr := database.CheckSession(userID, sessionID)
+			_, userErr := database.GetSessionUser(userID, sessionID)
+			if tt.active {
+				assert.NoError(t, checkErr)
+				assert.NoError(t, userErr)
+			} else {
+				assert.ErrorIs(t, checkErr, ErrSessionRevoked)
+				assert.ErrorIs(t, userErr, ErrSessionRevoked)
+			}
+		})
+	}
+}
+
+func TestSessionsOfDisabledUser(t *testing.T) {
+	database, userID := testDB(t)
+	sessionID, err := database.CreateSession(userID, newHash(t), time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	_, err = database.SetUserDisabled(userID, true)
+	assert.NoError(t, err)
+
+	_, userErr := database.GetSessionUser(userID, sessionID)
+	assert.ErrorIs(t, userErr, ErrSessionRevoked)
+	assert.ErrorIs(t, database.CheckSession(userID, sessionID), ErrSessionRevoked)
+}
@@ -0,0 +1,97 @@
+package admin
+
+import (
+	"AlexSarva/media/models"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ListUsers users filtered by role and search in username and email, ordered by registration
+// returns the page of users and their total number
+func (d *PostgresDB) ListUsers(query models.UsersQuery) ([]models.UserAccount, int, error) {
+	var conditions []string
+	var args []interface{}
+	if len(query.Role) != 0 {
+		args = append(args, query.Role)
+		conditions = append(conditions, fmt.Sprintf("role = $%d", len(args)))
+	}
+	if len(query.Search) != 0 {
+		args = append(args, strings.ToLower(query.Search))
+		conditions = append(conditions, fmt.Sprintf("(strpos(lower(email), $%[1]d) > 0 or strpos(lower(coalesce(username, '')), $%[1]d) > 0)", len(args)))
+	}
+	where := ""
+	if len(conditions) != 0 {
+		where = " WHERE " + strings.Join(conditions, " and ")
+	}
+
+	var total int
+	if err := d.database.Get(&total, "SELECT count(*) FROM public.users"+where, args...); err != nil {
+		return nil, 0, err
+	}
+
+	page := ""
+	if query.Limit > 0 {
+		page += fmt.Sprintf(" LIMIT %d", query.Limit)
+	}
+	if query.Offset > 0 {
+		page += fmt.Sprintf(" OFFSET %d", query.Offset)
+	}
+	users := []models.UserAccount{}
+	err := d.database.Select(&users, `SELECT id, coalesce(username, '') username, email, role, created, disabled
+		FROM public.users`+where+" ORDER BY created, id"+page, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// SetUserRole changes role of the user
+func (d *PostgresDB) SetUserRole(userID uuid.UUID, role string) (*models.UserAccount, error) {
+	var user models.UserAccount
+	err := d.database.Get(&user, `UPDATE public.users SET role=$2 WHERE id=$1
+		RETURNING id, coalesce(username, '') username, email, role, created, disabled`, userID, role)
+	if err != nil {
+		return &models.UserAccount{}, err
+	}
+	return &user, nil
+}
+
+// SetUserDisabled disables or enables account of the user, sessions of disabled user are logged out
+func (d *PostgresDB) SetUserDisabled(userID uuid.UUID, disabled bool) (*models.UserAccount, error) {
+	tx, txErr := d.database.Beginx()
+	if txErr != nil {
+		return &models.UserAccount{}, txErr
+	}
+	defer tx.Rollback()
+
+	var user models.UserAccount
+	err := tx.Get(&user, `UPDATE public.users
+		SET disabled=case when $2 then coalesce(disabled, now()) end
+		WHERE id=$1
+		RETURNING id, coalesce(username, '') username, email, role, created, disabled`, userID, disabled)
+	if err != nil {
+		return &models.UserAccount{}, err
+	}
+	if disabled {
+		if err := revokeSessions(tx, "user_id=$1", userID); err != nil {
+			return &models.UserAccount{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &models.UserAccount{}, err
+	}
+	return &user, nil
+}
+
+// GrantAdmins gives admin role to registered users with the emails
+func (d *PostgresDB) GrantAdmins(emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+	_, err := d.database.Exec("UPDATE public.users SET role=$1 WHERE email = any($2) and role <> $1", models.RoleAdmin, pq.Array(emails))
+	return err
+}
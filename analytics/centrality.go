@@ -0,0 +1,195 @@
+package analytics
+
+import (
+	"container/heap"
+	"math"
+)
+
+const (
+	// pageRankDamping probability to follow a link instead of jumping to a random source
+	pageRankDamping = 0.85
+	// pageRankIterations upper bound of power iterations
+	pageRankIterations = 100
+	// pageRankTolerance total change of ranks that stops iterations
+	pageRankTolerance = 1e-9
+)
+
+// Centrality influence measures of a source in the link graph
+type Centrality struct {
+	PageRank    float64
+	Betweenness float64
+	InDegree    int64
+	OutDegree   int64
+}
+
+// Centralities calculates weighted PageRank, weighted in/out degree
+// and weighted betweenness of every node of the network
+func (n *Network) Centralities() map[int64]Centrality {
+	result := make(map[int64]Centrality, len(n.Nodes))
+	ranks := n.PageRank()
+	betweenness := n.Betweenness()
+	for _, id := range n.Nodes {
+		var in, out int64
+		for _, arc := range n.In[id] {
+			in += int64(arc.Links)
+		}
+		for _, arc := range n.Out[id] {
+			out += int64(arc.Links)
+		}
+		result[id] = Centrality{
+			PageRank:    ranks[id],
+			Betweenness: betweenness[id],
+			InDegree:    in,
+			OutDegree:   out,
+		}
+	}
+	return result
+}
+
+// PageRank weighted by the number of links, rank of sources without outgoing links
+// is spread evenly over the network. Ranks sum up to 1
+func (n *Network) PageRank() map[int64]float64 {
+	size := float64(len(n.Nodes))
+	ranks := make(map[int64]float64, len(n.Nodes))
+	if size == 0 {
+		return ranks
+	}
+
+	outWeight := make(map[int64]float64, len(n.Out))
+	for from, arcs := range n.Out {
+		for _, arc := range arcs {
+			outWeight[from] += arcWeight(arc)
+		}
+	}
+
+	for _, id := range n.Nodes {
+		ranks[id] = 1 / size
+	}
+	for iteration := 0; iteration < pageRankIterations; iteration++ {
+		var dangling float64
+		for _, id := range n.Nodes {
+			if outWeight[id] == 0 {
+				dangling += ranks[id]
+			}
+		}
+
+		next := make(map[int64]float64, len(n.Nodes))
+		base := (1-pageRankDamping)/size + pageRankDamping*dangling/size
+		for _, id := range n.Nodes {
+			next[id] = base
+		}
+		for from, arcs := range n.Out {
+			for _, arc := range arcs {
+				next[arc.To] += pageRankDamping * ranks[from] * arcWeight(arc) / outWeight[from]
+			}
+		}
+
+		var change float64
+		for _, id := range n.Nodes {
+			change += math.Abs(next[id] - ranks[id])
+		}
+		ranks = next
+		if change < pageRankTolerance {
+			break
+		}
+	}
+	return ranks
+}
+
+// Betweenness weighted betweenness centrality by Brandes algorithm,
+// arcs are as long as Arc.Distance so paths prefer strong links
+func (n *Network) Betweenness() map[int64]float64 {
+	size := len(n.Nodes)
+	index := make(map[int64]int, size)
+	for i, id := range n.Nodes {
+		index[id] = i
+	}
+	out := make([][]Arc, size)
+	for from, arcs := range n.Out {
+		out[index[from]] = arcs
+	}
+
+	centrality := make([]float64, size)
+	dist := make([]float64, size)
+	sigma := make([]float64, size)
+	delta := make([]float64, size)
+	preds := make([][]int, size)
+	for source := 0; source < size; source++ {
+		for i := 0; i < size; i++ {
+			dist[i] = math.Inf(1)
+			sigma[i] = 0
+			delta[i] = 0
+			preds[i] = preds[i][:0]
+		}
+		dist[source] = 0
+		sigma[source] = 1
+
+		var stack []int
+		queue := &distanceQueue{{node: source}}
+		for queue.Len() > 0 {
+			item := heap.Pop(queue).(distanceItem)
+			if item.dist > dist[item.node] {
+				continue
+			}
+			stack = append(stack, item.node)
+			for _, arc := range out[item.node] {
+				next := index[arc.To]
+				alt := dist[item.node] + arc.Distance()
+				switch {
+				case alt < dist[next]-1e-12:
+					dist[next] = alt
+					sigma[next] = sigma[item.node]
+					preds[next] = append(preds[next][:0], item.node)
+					heap.Push(queue, distanceItem{node: next, dist: alt})
+				case math.Abs(alt-dist[next]) <= 1e-12:
+					sigma[next] += sigma[item.node]
+					preds[next] = append(preds[next], item.node)
+				}
+			}
+		}
+
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range preds[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != source {
+				centrality[w] += delta[w]
+			}
+		}
+	}
+
+	result := make(map[int64]float64, size)
+	for i, id := range n.Nodes {
+		result[id] = centrality[i]
+	}
+	return result
+}
+
+// arcWeight weight of the arc in PageRank
+func arcWeight(arc Arc) float64 {
+	if arc.Links <= 0 {
+		return 1
+	}
+	return float64(arc.Links)
+}
+
+// distanceItem node waiting in Dijkstra queue
+type distanceItem struct {
+	node int
+	dist float64
+}
+
+// distanceQueue min-heap of nodes by distance
+type distanceQueue []distanceItem
+
+func (q distanceQueue) Len() int            { return len(q) }
+func (q distanceQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q distanceQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *distanceQueue) Push(x interface{}) { *q = append(*q, x.(distanceItem)) }
+func (q *distanceQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
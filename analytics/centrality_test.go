@@ -0,0 +1,101 @@
+package analytics
+
+import (
+	"AlexSarva/media/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// links edges of equal weight between consecutive ids
+func links(ids ...int64) []models.GraphEdge {
+	var edges []models.GraphEdge
+	for i := 1; i < len(ids); i++ {
+		edges = append(edges, models.GraphEdge{From: ids[i-1], To: ids[i], Value: 10})
+	}
+	return edges
+}
+
+func TestCentralities(t *testing.T) {
+	// center 1 links both ways with leaves 2..5
+	var star []models.GraphEdge
+	for leaf := int64(2); leaf <= 5; leaf++ {
+		star = append(star, links(1, leaf, 1)...)
+	}
+	// rank of the center c solves c = 0.15/5 + 0.85*(1-c)
+	starCenter := 0.88 / 1.85
+
+	tests := []struct {
+		name        string
+		edges       []models.GraphEdge
+		pageRank    map[int64]float64
+		betweenness map[int64]float64
+		inDegree    map[int64]int64
+	}{
+		{
+			name:        "star",
+			edges:       star,
+			pageRank:    map[int64]float64{1: starCenter, 2: (1 - starCenter) / 4, 5: (1 - starCenter) / 4},
+			betweenness: map[int64]float64{1: 12, 2: 0, 3: 0, 4: 0, 5: 0},
+			inDegree:    map[int64]int64{1: 40, 2: 10},
+		},
+		{
+			name:        "path",
+			edges:       links(1, 2, 3, 4),
+			betweenness: map[int64]float64{1: 0, 2: 2, 3: 2, 4: 0},
+			inDegree:    map[int64]int64{1: 0, 2: 10, 4: 10},
+		},
+		{
+			name:        "cycle",
+			edges:       links(1, 2, 3, 4, 1),
+			pageRank:    map[int64]float64{1: 0.25, 2: 0.25, 3: 0.25, 4: 0.25},
+			betweenness: map[int64]float64{1: 3, 2: 3, 3: 3, 4: 3},
+			inDegree:    map[int64]int64{1: 10, 3: 10},
+		},
+		{
+			name:        "equal shortest paths share betweenness",
+			edges:       append(links(1, 2, 4), links(1, 3, 4)...),
+			betweenness: map[int64]float64{1: 0, 2: 0.5, 3: 0.5, 4: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			centralities := NewNetwork(tt.edges).Centralities()
+
+			var total float64
+			for _, centrality := range centralities {
+				total += centrality.PageRank
+			}
+			assert.InDelta(t, 1, total, 1e-6)
+			for id, want := range tt.pageRank {
+				assert.InDelta(t, want, centralities[id].PageRank, 1e-6, "pagerank of %d", id)
+			}
+			for id, want := range tt.betweenness {
+				assert.InDelta(t, want, centralities[id].Betweenness, 1e-9, "betweenness of %d", id)
+			}
+			for id, want := range tt.inDegree {
+				assert.Equal(t, want, centralities[id].InDegree, "in degree of %d", id)
+			}
+		})
+	}
+}
+
+func TestPageRankDangling(t *testing.T) {
+	// rank of the sink is spread back evenly, so it is still ranked highest
+	ranks := NewNetwork(links(1, 2, 3)).PageRank()
+
+	assert.InDelta(t, 1, ranks[1]+ranks[2]+ranks[3], 1e-6)
+	assert.Less(t, ranks[1], ranks[2])
+	assert.Less(t, ranks[2], ranks[3])
+}
+
+func TestPageRankFollowsStrongLinks(t *testing.T) {
+	ranks := NewNetwork([]models.GraphEdge{
+		{From: 1, To: 2, Value: 90},
+		{From: 1, To: 3, Value: 10},
+		{From: 2, To: 1, Value: 10},
+		{From: 3, To: 1, Value: 10},
+	}).PageRank()
+
+	assert.Greater(t, ranks[2], ranks[3])
+}
@@ -0,0 +1,301 @@
+package analytics
+
+import (
+	"math"
+	"math/rand"
+)
+
+const (
+	// DefaultLayoutIterations number of ForceAtlas2 steps when the caller does not set it
+	DefaultLayoutIterations = 300
+	// layoutGravity strength of the pull towards the centre, keeps components together
+	layoutGravity = 1.0
+	// layoutTheta accuracy of Barnes-Hut approximation of repulsion
+	layoutTheta = 1.2
+	// layoutTolerance jitter tolerance of adaptive speed
+	layoutTolerance = 1.0
+	// quadMaxDepth stops splitting of space when many nodes share one point
+	quadMaxDepth = 24
+)
+
+// Position coordinates of a node on the plane
+type Position struct {
+	X float64
+	Y float64
+}
+
+// AddNodes adds nodes without links to the network
+func (n *Network) AddNodes(ids ...int64) {
+	known := make(map[int64]bool, len(n.Nodes))
+	for _, id := range n.Nodes {
+		known[id] = true
+	}
+	for _, id := range ids {
+		if !known[id] {
+			known[id] = true
+			n.Nodes = append(n.Nodes, id)
+		}
+	}
+}
+
+// body state of a node during layout
+type body struct {
+	x, y     float64
+	dx, dy   float64
+	oldDx    float64
+	oldDy    float64
+	mass     float64
+	fixed    bool
+	adjacent []spring
+}
+
+// spring attraction between two linked nodes
+type spring struct {
+	to     int
+	weight float64
+}
+
+// ForceAtlas2 lays out the network with ForceAtlas2 force-directed algorithm.
+// Links are undirected springs with weight log(1+links), repulsion is approximated by Barnes-Hut.
+// Nodes from fixed keep their positions, the others start from a position derived from the node id,
+// so the same network always gets the same layout
+func (n *Network) ForceAtlas2(iterations int, fixed map[int64]Position) map[int64]Position {
+	if iterations <= 0 {
+		iterations = DefaultLayoutIterations
+	}
+
+	index := make(map[int64]int, len(n.Nodes))
+	bodies := make([]body, len(n.Nodes))
+	for i, id := range n.Nodes {
+		index[id] = i
+	}
+	for _, from := range n.Nodes {
+		for _, arc := range n.Out[from] {
+			i, j := index[from], index[arc.To]
+			weight := math.Log1p(arcWeight(arc))
+			bodies[i].adjacent = append(bodies[i].adjacent, spring{to: j, weight: weight})
+			bodies[j].adjacent = append(bodies[j].adjacent, spring{to: i, weight: weight})
+		}
+	}
+
+	spread := 10 * math.Sqrt(float64(len(bodies)))
+	for i, id := range n.Nodes {
+		b := &bodies[i]
+		b.mass = float64(len(b.adjacent)) + 1
+		if position, ok := fixed[id]; ok {
+			b.x, b.y, b.fixed = position.X, position.Y, true
+			continue
+		}
+		random := rand.New(rand.NewSource(id))
+		b.x = (random.Float64() - 0.5) * spread
+		b.y = (random.Float64() - 0.5) * spread
+	}
+	// new nodes linked to fixed ones start next to them
+	for i := range bodies {
+		b := &bodies[i]
+		if b.fixed {
+			continue
+		}
+		var sumX, sumY, count float64
+		for _, s := range b.adjacent {
+			if bodies[s.to].fixed {
+				sumX += bodies[s.to].x
+				sumY += bodies[s.to].y
+				count++
+			}
+		}
+		if count > 0 {
+			b.x = sumX/count + b.x/spread
+			b.y = sumY/count + b.y/spread
+		}
+	}
+
+	scaling := 10.0
+	if len(bodies) > 100 {
+		scaling = 2.0
+	}
+	speed, speedEfficiency := 1.0, 1.0
+	for iteration := 0; iteration < iterations; iteration++ {
+		for i := range bodies {
+			bodies[i].oldDx, bodies[i].oldDy = bodies[i].dx, bodies[i].dy
+			bodies[i].dx, bodies[i].dy = 0, 0
+		}
+
+		tree := buildQuad(bodies, allIndexes(len(bodies)), 0)
+		for i := range bodies {
+			tree.repulse(bodies, i, scaling)
+		}
+		for i := range bodies {
+			b := &bodies[i]
+			distance := math.Hypot(b.x, b.y)
+			if distance > 0 {
+				factor := scaling * layoutGravity * b.mass / distance
+				b.dx -= b.x * factor
+				b.dy -= b.y * factor
+			}
+			for _, s := range b.adjacent {
+				other := &bodies[s.to]
+				b.dx -= (b.x - other.x) * s.weight
+				b.dy -= (b.y - other.y) * s.weight
+			}
+		}
+
+		speed, speedEfficiency = adjustSpeed(bodies, speed, speedEfficiency)
+		for i := range bodies {
+			b := &bodies[i]
+			if b.fixed {
+				continue
+			}
+			swinging := b.mass * math.Hypot(b.oldDx-b.dx, b.oldDy-b.dy)
+			factor := speed / (1 + math.Sqrt(speed*swinging))
+			b.x += b.dx * factor
+			b.y += b.dy * factor
+		}
+	}
+
+	positions := make(map[int64]Position, len(bodies))
+	for i, id := range n.Nodes {
+		positions[id] = Position{X: bodies[i].x, Y: bodies[i].y}
+	}
+	return positions
+}
+
+// adjustSpeed adaptive global speed of ForceAtlas2 from swinging and traction of nodes
+func adjustSpeed(bodies []body, speed, speedEfficiency float64) (float64, float64) {
+	var swinging, traction float64
+	for _, b := range bodies {
+		if b.fixed {
+			continue
+		}
+		swinging += b.mass * math.Hypot(b.oldDx-b.dx, b.oldDy-b.dy)
+		traction += b.mass * math.Hypot(b.oldDx+b.dx, b.oldDy+b.dy) / 2
+	}
+	if swinging == 0 || traction == 0 {
+		return speed, speedEfficiency
+	}
+
+	size := float64(len(bodies))
+	estimatedJitter := 0.05 * math.Sqrt(size)
+	minJitter := math.Sqrt(estimatedJitter)
+	jitter := layoutTolerance * math.Max(minJitter, math.Min(10, estimatedJitter*traction/(size*size)))
+
+	const minSpeedEfficiency = 0.05
+	if swinging/traction > 2 {
+		if speedEfficiency > minSpeedEfficiency {
+			speedEfficiency *= 0.5
+		}
+		jitter = math.Max(jitter, layoutTolerance)
+	}
+
+	targetSpeed := jitter * speedEfficiency * traction / swinging
+	if swinging > jitter*traction {
+		if speedEfficiency > minSpeedEfficiency {
+			speedEfficiency *= 0.7
+		}
+	} else if speed < 1000 {
+		speedEfficiency *= 1.3
+	}
+
+	const maxRise = 0.5
+	speed += math.Min(targetSpeed-speed, maxRise*speed)
+	return speed, speedEfficiency
+}
+
+// quad region of Barnes-Hut tree with the total mass and its centre
+type quad struct {
+	size       float64
+	mass       float64
+	x, y       float64
+	minX, minY float64
+	maxX, maxY float64
+	bodies     []int
+	children   []*quad
+}
+
+func allIndexes(size int) []int {
+	indexes := make([]int, size)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	return indexes
+}
+
+// buildQuad splits bodies into quadrants until every region holds a single body
+func buildQuad(bodies []body, indexes []int, depth int) *quad {
+	q := &quad{}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, i := range indexes {
+		b := bodies[i]
+		q.mass += b.mass
+		q.x += b.x * b.mass
+		q.y += b.y * b.mass
+		minX, maxX = math.Min(minX, b.x), math.Max(maxX, b.x)
+		minY, maxY = math.Min(minY, b.y), math.Max(maxY, b.y)
+	}
+	if q.mass > 0 {
+		q.x /= q.mass
+		q.y /= q.mass
+	}
+	q.minX, q.minY, q.maxX, q.maxY = minX, minY, maxX, maxY
+	q.size = math.Max(maxX-minX, maxY-minY)
+	if len(indexes) <= 1 || depth >= quadMaxDepth || q.size == 0 {
+		q.bodies = indexes
+		return q
+	}
+
+	midX, midY := (minX+maxX)/2, (minY+maxY)/2
+	var parts [4][]int
+	for _, i := range indexes {
+		part := 0
+		if bodies[i].x > midX {
+			part++
+		}
+		if bodies[i].y > midY {
+			part += 2
+		}
+		parts[part] = append(parts[part], i)
+	}
+	for _, part := range parts {
+		if len(part) > 0 {
+			q.children = append(q.children, buildQuad(bodies, part, depth+1))
+		}
+	}
+	return q
+}
+
+// repulse adds repulsion of the region to the body
+func (q *quad) repulse(bodies []body, i int, scaling float64) {
+	b := &bodies[i]
+	if q.children == nil {
+		for _, j := range q.bodies {
+			if j != i {
+				applyRepulsion(b, bodies[j].x, bodies[j].y, bodies[j].mass, scaling)
+			}
+		}
+		return
+	}
+
+	// the region holding the body itself is never approximated
+	inside := b.x >= q.minX && b.x <= q.maxX && b.y >= q.minY && b.y <= q.maxY
+	distance := math.Hypot(b.x-q.x, b.y-q.y)
+	if !inside && distance > 0 && q.size/distance < layoutTheta {
+		applyRepulsion(b, q.x, q.y, q.mass, scaling)
+		return
+	}
+	for _, child := range q.children {
+		child.repulse(bodies, i, scaling)
+	}
+}
+
+// applyRepulsion ForceAtlas2 repulsion proportional to masses and inverse to distance
+func applyRepulsion(b *body, x, y, mass, scaling float64) {
+	dx, dy := b.x-x, b.y-y
+	distance2 := dx*dx + dy*dy
+	if distance2 == 0 {
+		return
+	}
+	factor := scaling * b.mass * mass / distance2
+	b.dx += dx * factor
+	b.dy += dy * factor
+}
@@ -0,0 +1,94 @@
+package analytics
+
+import (
+	"AlexSarva/media/models"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// distance between two positions
+func distance(a, b Position) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
+
+// twoClusters two cliques of five nodes joined by a single weak link
+func twoClusters() *Network {
+	var edges []models.GraphEdge
+	clique := func(ids ...int64) {
+		for _, from := range ids {
+			for _, to := range ids {
+				if from < to {
+					edges = append(edges, models.GraphEdge{From: from, To: to, Value: 50})
+				}
+			}
+		}
+	}
+	clique(1, 2, 3, 4, 5)
+	clique(6, 7, 8, 9, 10)
+	edges = append(edges, models.GraphEdge{From: 5, To: 6, Value: 1})
+	return NewNetwork(edges)
+}
+
+func TestForceAtlas2(t *testing.T) {
+	network := twoClusters()
+	positions := network.ForceAtlas2(0, nil)
+
+	assert.Len(t, positions, len(network.Nodes))
+	for id, position := range positions {
+		assert.False(t, math.IsNaN(position.X) || math.IsInf(position.X, 0), "x of %d", id)
+		assert.False(t, math.IsNaN(position.Y) || math.IsInf(position.Y, 0), "y of %d", id)
+	}
+	for _, other := range []int64{2, 3, 4, 5} {
+		for _, stranger := range []int64{7, 8, 9, 10} {
+			assert.Less(t, distance(positions[1], positions[other]), distance(positions[1], positions[stranger]),
+				"1 is closer to %d than to %d", other, stranger)
+		}
+	}
+	// distinct nodes never collapse into one point
+	assert.Greater(t, distance(positions[1], positions[2]), 0.0)
+}
+
+func TestForceAtlas2IsDeterministic(t *testing.T) {
+	first := twoClusters().ForceAtlas2(50, nil)
+	second := twoClusters().ForceAtlas2(50, nil)
+
+	assert.Equal(t, first, second)
+}
+
+func TestForceAtlas2KeepsFixed(t *testing.T) {
+	network := twoClusters()
+	network.AddNodes(11)
+	fixed := map[int64]Position{1: {X: 100, Y: -40}, 6: {X: -100, Y: 40}}
+	positions := network.ForceAtlas2(100, fixed)
+
+	for id, position := range fixed {
+		assert.Equal(t, position, positions[id], "node %d", id)
+	}
+	assert.Contains(t, positions, int64(11))
+	// new neighbours of a fixed node are laid out on its side
+	assert.Less(t, distance(positions[2], fixed[1]), distance(positions[2], fixed[6]))
+	assert.Less(t, distance(positions[7], fixed[6]), distance(positions[7], fixed[1]))
+}
+
+func TestForceAtlas2Small(t *testing.T) {
+	tests := []struct {
+		name    string
+		network *Network
+		want    int
+	}{
+		{name: "empty", network: NewNetwork(nil), want: 0},
+		{name: "single node", network: func() *Network { n := NewNetwork(nil); n.AddNodes(7); return n }(), want: 1},
+		{name: "single link", network: NewNetwork([]models.GraphEdge{{From: 1, To: 2, Value: 10}}), want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positions := tt.network.ForceAtlas2(20, nil)
+			assert.Len(t, positions, tt.want)
+			for _, position := range positions {
+				assert.False(t, math.IsNaN(position.X) || math.IsNaN(position.Y))
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package analytics
+
+import "sort"
+
+// maxLouvainPasses limits local moving passes on one level of Louvain
+const maxLouvainPasses = 50
+
+// undirected weighted graph used by Louvain, self-loops keep weight inside aggregated communities
+type undirected struct {
+	adj    []map[int]float64
+	degree []float64
+	total  float64
+}
+
+func newUndirected(size int) *undirected {
+	adj := make([]map[int]float64, size)
+	for i := range adj {
+		adj[i] = make(map[int]float64)
+	}
+	return &undirected{
+		adj:    adj,
+		degree: make([]float64, size),
+	}
+}
+
+func (g *undirected) add(i, j int, weight float64) {
+	g.adj[i][j] += weight
+	g.degree[i] += weight
+	g.total += weight
+}
+
+// moveNodes local moving phase of Louvain, returns community of every node and whether anything moved
+func (g *undirected) moveNodes() ([]int, bool) {
+	size := len(g.adj)
+	community := make([]int, size)
+	tot := make([]float64, size)
+	for i := 0; i < size; i++ {
+		community[i] = i
+		tot[i] = g.degree[i]
+	}
+	if g.total == 0 {
+		return community, false
+	}
+
+	improved := false
+	for pass := 0; pass < maxLouvainPasses; pass++ {
+		moved := false
+		for i := 0; i < size; i++ {
+			current := community[i]
+			toCommunity := make(map[int]float64)
+			for j, weight := range g.adj[i] {
+				if j != i {
+					toCommunity[community[j]] += weight
+				}
+			}
+
+			tot[current] -= g.degree[i]
+			best := current
+			bestGain := toCommunity[current] - tot[current]*g.degree[i]/g.total
+			candidates := make([]int, 0, len(toCommunity))
+			for c := range toCommunity {
+				candidates = append(candidates, c)
+			}
+			sort.Ints(candidates)
+			for _, c := range candidates {
+				gain := toCommunity[c] - tot[c]*g.degree[i]/g.total
+				if gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+			tot[best] += g.degree[i]
+			if best != current {
+				community[i] = best
+				moved = true
+				improved = true
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	return community, improved
+}
+
+// renumber makes communities consecutive numbers starting from 0
+func renumber(community []int) ([]int, int) {
+	numbers := make(map[int]int)
+	result := make([]int, len(community))
+	for i, c := range community {
+		num, ok := numbers[c]
+		if !ok {
+			num = len(numbers)
+			numbers[c] = num
+		}
+		result[i] = num
+	}
+	return result, len(numbers)
+}
+
+// aggregate collapses every community into a single node
+func (g *undirected) aggregate(community []int, size int) *undirected {
+	next := newUndirected(size)
+	for i, neighbours := range g.adj {
+		for j, weight := range neighbours {
+			next.add(community[i], community[j], weight)
+		}
+	}
+	return next
+}
+
+// Communities detects communities of sources by Louvain modularity optimisation.
+// Direction of links is ignored, the number of links is the weight of the edge.
+// Communities are numbered from 0 by descending size, returns community of every node
+// and modularity of the partition
+func (n *Network) Communities() (map[int64]int, float64) {
+	index := make(map[int64]int, len(n.Nodes))
+	for i, id := range n.Nodes {
+		index[id] = i
+	}
+
+	graph := newUndirected(len(n.Nodes))
+	for from, arcs := range n.Out {
+		for _, arc := range arcs {
+			weight := float64(arc.Links)
+			if weight <= 0 {
+				weight = 1
+			}
+			graph.add(index[from], index[arc.To], weight)
+			graph.add(index[arc.To], index[from], weight)
+		}
+	}
+
+	membership := make([]int, len(n.Nodes))
+	for i := range membership {
+		membership[i] = i
+	}
+	level := graph
+	for {
+		community, improved := level.moveNodes()
+		if !improved {
+			break
+		}
+		community, size := renumber(community)
+		for i := range membership {
+			membership[i] = community[membership[i]]
+		}
+		level = level.aggregate(community, size)
+	}
+
+	// the largest communities get the smallest numbers
+	sizes := make(map[int]int)
+	for _, c := range membership {
+		sizes[c]++
+	}
+	order := make([]int, 0, len(sizes))
+	for c := range sizes {
+		order = append(order, c)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if sizes[order[i]] != sizes[order[j]] {
+			return sizes[order[i]] > sizes[order[j]]
+		}
+		return order[i] < order[j]
+	})
+	numbers := make(map[int]int, len(order))
+	for num, c := range order {
+		numbers[c] = num
+	}
+
+	result := make(map[int64]int, len(n.Nodes))
+	for i, id := range n.Nodes {
+		membership[i] = numbers[membership[i]]
+		result[id] = membership[i]
+	}
+	return result, graph.modularity(membership)
+}
+
+// modularity quality of the partition of the graph into communities
+func (g *undirected) modularity(community []int) float64 {
+	if g.total == 0 {
+		return 0
+	}
+	inside := make(map[int]float64)
+	tot := make(map[int]float64)
+	for i, neighbours := range g.adj {
+		tot[community[i]] += g.degree[i]
+		for j, weight := range neighbours {
+			if community[i] == community[j] {
+				inside[community[i]] += weight
+			}
+		}
+	}
+	var q float64
+	for c, t := range tot {
+		q += inside[c]/g.total - (t/g.total)*(t/g.total)
+	}
+	return q
+}
@@ -0,0 +1,34 @@
+package analytics
+
+import (
+	"AlexSarva/media/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommunities(t *testing.T) {
+	var edges []models.GraphEdge
+	clique := func(ids ...int64) {
+		for _, from := range ids {
+			for _, to := range ids {
+				if from < to {
+					edges = append(edges, models.GraphEdge{From: from, To: to, Value: 10})
+				}
+			}
+		}
+	}
+	clique(1, 2, 3, 4, 5)
+	clique(6, 7, 8, 9)
+	edges = append(edges, models.GraphEdge{From: 5, To: 6, Value: 1})
+
+	communities, modularity := NewNetwork(edges).Communities()
+
+	for _, id := range []int64{1, 2, 3, 4, 5} {
+		assert.Equal(t, 0, communities[id], "node %d", id)
+	}
+	for _, id := range []int64{6, 7, 8, 9} {
+		assert.Equal(t, 1, communities[id], "node %d", id)
+	}
+	assert.Greater(t, modularity, 0.4)
+}
@@ -0,0 +1,57 @@
+package analytics
+
+import (
+	"AlexSarva/media/models"
+	"sort"
+)
+
+// Arc weighted link from one source to another
+type Arc struct {
+	To    int64
+	Links int32
+}
+
+// Network directed weighted graph of sources built from analytics.graph_edges
+type Network struct {
+	Nodes []int64
+	Out   map[int64][]Arc
+	In    map[int64][]Arc
+}
+
+// NewNetwork builds network from edges, duplicated edges and loops are skipped
+func NewNetwork(edges []models.GraphEdge) *Network {
+	network := &Network{
+		Out: make(map[int64][]Arc),
+		In:  make(map[int64][]Arc),
+	}
+	known := make(map[int64]bool)
+	seen := make(map[[2]int64]bool, len(edges))
+	for _, edge := range edges {
+		for _, id := range []int64{edge.From, edge.To} {
+			if !known[id] {
+				known[id] = true
+				network.Nodes = append(network.Nodes, id)
+			}
+		}
+		key := [2]int64{edge.From, edge.To}
+		if edge.From == edge.To || seen[key] {
+			continue
+		}
+		seen[key] = true
+		network.Out[edge.From] = append(network.Out[edge.From], Arc{To: edge.To, Links: edge.Value})
+		network.In[edge.To] = append(network.In[edge.To], Arc{To: edge.From, Links: edge.Value})
+	}
+	sort.Slice(network.Nodes, func(i, j int) bool {
+		return network.Nodes[i] < network.Nodes[j]
+	})
+	return network
+}
+
+// Distance length of the arc for weighted path searches,
+// the more links between sources the closer they are
+func (a Arc) Distance() float64 {
+	if a.Links <= 0 {
+		return 1
+	}
+	return 1 / float64(a.Links)
+}
@@ -0,0 +1,154 @@
+package analytics
+
+import (
+	"container/heap"
+	"math"
+)
+
+// maxExpandedPaths protects path search from combinatorial explosion on dense networks,
+// the search stops and reports truncation when this many partial paths are expanded
+const maxExpandedPaths = 100000
+
+// Path chain of sources from the first node to the last one
+type Path struct {
+	Nodes  []int64
+	Weight float64
+}
+
+// FindPaths returns k simple paths from one source to another with the smallest weight
+// that are not longer than maxHops, ordered by weight and then by hops.
+//
+// Partial paths are expanded best-first by their weight plus the shortest distance
+// left to the target, so complete paths come out in the order of their weight
+// and the search stops as soon as k of them are found.
+// Truncated is true when the search hit maxExpandedPaths before finding k paths,
+// the returned paths are then the shortest ones found but not necessarily the k shortest
+func (n *Network) FindPaths(from, to int64, maxHops, k int) (paths []Path, truncated bool) {
+	if from == to || maxHops <= 0 || k <= 0 {
+		return nil, false
+	}
+
+	toTarget := n.distancesTo(to, maxHops)
+	if _, ok := toTarget[from]; !ok {
+		return nil, false
+	}
+
+	queue := &pathQueue{{nodes: []int64{from}, estimate: toTarget[from].weight}}
+	for expanded := 0; queue.Len() > 0; expanded++ {
+		if expanded >= maxExpandedPaths {
+			return paths, true
+		}
+		path := heap.Pop(queue).(partialPath)
+		last := path.nodes[len(path.nodes)-1]
+		if last == to {
+			paths = append(paths, Path{Nodes: path.nodes, Weight: path.weight})
+			if len(paths) == k {
+				return paths, false
+			}
+			continue
+		}
+		hopsLeft := maxHops - (len(path.nodes) - 1)
+		for _, arc := range n.Out[last] {
+			dist, ok := toTarget[arc.To]
+			if !ok || dist.hops > hopsLeft-1 || path.contains(arc.To) {
+				continue
+			}
+			nodes := make([]int64, len(path.nodes)+1)
+			copy(nodes, path.nodes)
+			nodes[len(path.nodes)] = arc.To
+			weight := path.weight + arc.Distance()
+			heap.Push(queue, partialPath{nodes: nodes, weight: weight, estimate: weight + dist.weight})
+		}
+	}
+	return paths, false
+}
+
+// targetDistance fewest hops and smallest weight from the node to the target,
+// both are lower bounds for any path through the node
+type targetDistance struct {
+	hops   int
+	weight float64
+}
+
+// distancesTo lower bounds of hops and weight to the target of nodes that reach it within maxHops
+func (n *Network) distancesTo(to int64, maxHops int) map[int64]targetDistance {
+	// hops by backward breadth-first search
+	distances := map[int64]targetDistance{to: {}}
+	frontier := []int64{to}
+	for hop := 1; hop <= maxHops && len(frontier) > 0; hop++ {
+		var next []int64
+		for _, id := range frontier {
+			for _, arc := range n.In[id] {
+				if _, ok := distances[arc.To]; !ok {
+					distances[arc.To] = targetDistance{hops: hop, weight: math.Inf(1)}
+					next = append(next, arc.To)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	// weights by backward Bellman-Ford relaxation limited to the nodes found above,
+	// maxHops rounds are enough as weights are searched only within the hop limit
+	for round := 0; round < maxHops; round++ {
+		changed := false
+		for id, dist := range distances {
+			for _, arc := range n.Out[id] {
+				next, ok := distances[arc.To]
+				if !ok {
+					continue
+				}
+				if weight := next.weight + arc.Distance(); weight < dist.weight {
+					dist.weight = weight
+					changed = true
+				}
+			}
+			distances[id] = dist
+		}
+		if !changed {
+			break
+		}
+	}
+	return distances
+}
+
+// partialPath path from the first source in the search queue,
+// estimate is its weight plus the lower bound of the weight left to the target
+type partialPath struct {
+	nodes    []int64
+	weight   float64
+	estimate float64
+}
+
+// contains the node is already on the path
+func (p partialPath) contains(id int64) bool {
+	for _, node := range p.nodes {
+		if node == id {
+			return true
+		}
+	}
+	return false
+}
+
+// pathQueue min-heap of partial paths by estimate and then by hops
+type pathQueue []partialPath
+
+func (q pathQueue) Len() int { return len(q) }
+
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].estimate != q[j].estimate {
+		return q[i].estimate < q[j].estimate
+	}
+	return len(q[i].nodes) < len(q[j].nodes)
+}
+
+func (q pathQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(partialPath)) }
+
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,144 @@
+package analytics
+
+import (
+	"AlexSarva/media/models"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindPaths(t *testing.T) {
+	network := NewNetwork([]models.GraphEdge{
+		{From: 1, To: 2, Value: 10},
+		{From: 2, To: 4, Value: 10},
+		{From: 1, To: 3, Value: 100},
+		{From: 3, To: 4, Value: 100},
+		{From: 1, To: 4, Value: 1},
+		{From: 4, To: 1, Value: 50},
+		{From: 3, To: 5, Value: 100},
+		{From: 5, To: 6, Value: 100},
+		{From: 6, To: 4, Value: 100},
+	})
+
+	type args struct {
+		from    int64
+		to      int64
+		maxHops int
+		k       int
+	}
+	tests := []struct {
+		name string
+		args args
+		want [][]int64
+	}{
+		{
+			name: "strongest links first",
+			args: args{from: 1, to: 4, maxHops: 2, k: 5},
+			want: [][]int64{{1, 3, 4}, {1, 2, 4}, {1, 4}},
+		},
+		{
+			name: "hop limit lets longer paths in",
+			args: args{from: 1, to: 4, maxHops: 4, k: 2},
+			want: [][]int64{{1, 3, 4}, {1, 3, 5, 6, 4}},
+		},
+		{
+			name: "direction of links is respected",
+			args: args{from: 4, to: 2, maxHops: 4, k: 5},
+			want: [][]int64{{4, 1, 2}},
+		},
+		{
+			name: "unreachable target",
+			args: args{from: 6, to: 5, maxHops: 1, k: 5},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, truncated := network.FindPaths(tt.args.from, tt.args.to, tt.args.maxHops, tt.args.k)
+			var got [][]int64
+			for _, path := range paths {
+				got = append(got, path.Nodes)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.False(t, truncated)
+		})
+	}
+}
+
+// allPathWeights weights of all simple paths within maxHops by exhaustive search, sorted
+func allPathWeights(network *Network, from, to int64, maxHops int) []float64 {
+	var weights []float64
+	onPath := map[int64]bool{from: true}
+	var walk func(id int64, hops int, weight float64)
+	walk = func(id int64, hops int, weight float64) {
+		if id == to {
+			weights = append(weights, weight)
+			return
+		}
+		if hops == maxHops {
+			return
+		}
+		for _, arc := range network.Out[id] {
+			if onPath[arc.To] {
+				continue
+			}
+			onPath[arc.To] = true
+			walk(arc.To, hops+1, weight+arc.Distance())
+			onPath[arc.To] = false
+		}
+	}
+	walk(from, 0, 0)
+	sort.Float64s(weights)
+	return weights
+}
+
+func TestFindPathsAreShortest(t *testing.T) {
+	random := rand.New(rand.NewSource(7))
+	var edges []models.GraphEdge
+	for from := int64(1); from <= 12; from++ {
+		for to := int64(1); to <= 12; to++ {
+			if from != to && random.Intn(3) == 0 {
+				edges = append(edges, models.GraphEdge{From: from, To: to, Value: int32(1 + random.Intn(50))})
+			}
+		}
+	}
+	network := NewNetwork(edges)
+
+	for _, k := range []int{1, 5, 50} {
+		for _, maxHops := range []int{2, 4, 6} {
+			want := allPathWeights(network, 1, 12, maxHops)
+			assert.NotEmpty(t, want)
+			if len(want) > k {
+				want = want[:k]
+			}
+			paths, truncated := network.FindPaths(1, 12, maxHops, k)
+			assert.False(t, truncated)
+			assert.Len(t, paths, len(want))
+			for i, path := range paths {
+				assert.InDelta(t, want[i], path.Weight, 1e-9, "k=%d max_hops=%d path %d", k, maxHops, i)
+				assert.LessOrEqual(t, len(path.Nodes)-1, maxHops)
+			}
+		}
+	}
+}
+
+func TestFindPathsTruncated(t *testing.T) {
+	var edges []models.GraphEdge
+	for from := int64(1); from <= 14; from++ {
+		for to := int64(1); to <= 14; to++ {
+			if from != to {
+				edges = append(edges, models.GraphEdge{From: from, To: to, Value: 10})
+			}
+		}
+	}
+	network := NewNetwork(edges)
+
+	paths, truncated := network.FindPaths(1, 14, 12, maxExpandedPaths)
+	assert.True(t, truncated)
+	assert.NotEmpty(t, paths)
+	for i := 1; i < len(paths); i++ {
+		assert.LessOrEqual(t, paths[i-1].Weight, paths[i].Weight)
+	}
+}
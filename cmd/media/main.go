@@ -2,9 +2,11 @@ package main
 
 import (
 	"AlexSarva/media/admin"
+	"AlexSarva/media/crypto"
 	"AlexSarva/media/internal/app"
 	"AlexSarva/media/models"
 	"AlexSarva/media/server"
+	"context"
 	"flag"
 	"log"
 
@@ -23,17 +25,37 @@ func main() {
 	flag.StringVar(&cfg.ServerAddress, "a", cfg.ServerAddress, "host:port to listen on")
 	flag.StringVar(&cfg.DatabasePG, "dbpg", cfg.DatabasePG, "postgresql database config")
 	flag.StringVar(&cfg.DatabaseClick, "dbclick", cfg.DatabaseClick, "clickhouse database config")
+	flag.DurationVar(&cfg.WriteTimeout, "wt", cfg.WriteTimeout, "write timeout of regular routes")
+	flag.DurationVar(&cfg.GraphRetention, "gr", cfg.GraphRetention, "how long deleted graphs stay in trash, 0 keeps them forever")
+	flag.DurationVar(&cfg.AccessTokenTTL, "at", cfg.AccessTokenTTL, "lifetime of access tokens")
+	flag.DurationVar(&cfg.RefreshTokenTTL, "rt", cfg.RefreshTokenTTL, "lifetime of refresh tokens")
+	flag.DurationVar(&cfg.AuthCacheTTL, "ac", cfg.AuthCacheTTL, "how long users of sessions are cached, 0 disables the cache")
+	flag.Func("sk", "signing keys of tokens as kid:secret,kid:secret, the first one signs new tokens", func(keys string) error {
+		cfg.SigningKeys = models.Secret(keys)
+		return nil
+	})
 	flag.Parse()
 	log.Printf("%+v\n", cfg)
 	log.Printf("ServerAddress: %v", cfg.ServerAddress)
+	keys, keysErr := crypto.NewKeyring(string(cfg.SigningKeys))
+	if keysErr != nil {
+		log.Fatal("signing keys: " + keysErr.Error())
+	}
+	// users table must exist before saved graphs referencing it
+	adminPG := admin.NewAdminDBConnection(cfg.DatabasePG)
+	if grantErr := adminPG.GrantAdmins(cfg.AdminEmails); grantErr != nil {
+		log.Fatal("admin emails: " + grantErr.Error())
+	}
 	workDB, dbErr := app.NewStorage("PG", cfg)
 	if dbErr != nil {
 		log.Fatal(dbErr.Error() + "говно")
 	}
-	adminPG := admin.NewAdminDBConnection(cfg.DatabasePG)
 	ping := workDB.Repo.Ping()
 	log.Println(ping)
-	MainApp := server.NewServer(&cfg, workDB, adminPG)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go workDB.RunRetention(ctx, cfg.GraphRetention)
+	MainApp := server.NewServer(&cfg, workDB, adminPG, keys)
 	if runErr := MainApp.Run(); runErr != nil {
 		log.Printf("%s", runErr.Error())
 	}
@@ -2,17 +2,17 @@ package crypto
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"log"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-// SecretKey - secret key of encrypt and decrypt
-var SecretKey = []byte("Ag@th@")
-
 // ErrNotValidSing - error that occurs when it is impossible to recover UserID
 var ErrNotValidSing = errors.New("sign is not valid")
 
@@ -41,6 +41,9 @@ func Decrypt(hashString string, secret []byte) (uuid.UUID, error) {
 		log.Println(err)
 		return uuid.UUID{}, ErrNotValidSing
 	}
+	if len(data) < 16 {
+		return uuid.UUID{}, ErrNotValidSing
+	}
 	id, idErr := uuid.FromBytes(data[:16])
 	if idErr != nil {
 		log.Println(idErr)
@@ -55,3 +58,60 @@ func Decrypt(hashString string, secret []byte) (uuid.UUID, error) {
 		return uuid.UUID{}, ErrNotValidSing
 	}
 }
+
+// ErrTokenExpired - error that occurs when the signature of token is valid but its time is over
+var ErrTokenExpired = errors.New("token expired")
+
+// tokenLength length of user uuid, session uuid and time of expiration in signed token
+const tokenLength = 16 + 16 + 8
+
+// SignToken convert user uuid, session uuid and time of expiration to signed token
+// secret key should be the same for SignToken and ParseToken
+func SignToken(userID uuid.UUID, sessionID uuid.UUID, expires time.Time, secret []byte) string {
+	data := make([]byte, tokenLength, tokenLength+sha256.Size)
+	copy(data, userID[:])
+	copy(data[16:], sessionID[:])
+	binary.BigEndian.PutUint64(data[32:], uint64(expires.Unix()))
+	h := hmac.New(sha256.New, secret)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(data))
+}
+
+// ParseToken convert signed token to user uuid and session uuid, expired tokens are refused
+func ParseToken(token string, secret []byte) (uuid.UUID, uuid.UUID, error) {
+	data, err := hex.DecodeString(token)
+	if err != nil || len(data) != tokenLength+sha256.Size {
+		return uuid.UUID{}, uuid.UUID{}, ErrNotValidSing
+	}
+	h := hmac.New(sha256.New, secret)
+	h.Write(data[:tokenLength])
+	if !hmac.Equal(h.Sum(nil), data[tokenLength:]) {
+		return uuid.UUID{}, uuid.UUID{}, ErrNotValidSing
+	}
+	expires := time.Unix(int64(binary.BigEndian.Uint64(data[32:tokenLength])), 0)
+	if !time.Now().Before(expires) {
+		return uuid.UUID{}, uuid.UUID{}, ErrTokenExpired
+	}
+	userID, userErr := uuid.FromBytes(data[:16])
+	sessionID, sessionErr := uuid.FromBytes(data[16:32])
+	if userErr != nil || sessionErr != nil {
+		return uuid.UUID{}, uuid.UUID{}, ErrNotValidSing
+	}
+	return userID, sessionID, nil
+}
+
+// NewRefreshToken random refresh token and its hash, only the hash is kept in database
+func NewRefreshToken() (string, string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(data)
+	return token, HashToken(token), nil
+}
+
+// HashToken hash of refresh token to look it up in database
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
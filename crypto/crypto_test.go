@@ -52,6 +52,17 @@ func TestDecrypt(t *testing.T) {
 				equal:   false,
 			},
 		},
+		{
+			name: "short hash",
+			args: args{
+				hashString: "abcd",
+				secret:     secretKey,
+			},
+			want: want{
+				wantErr: ErrNotValidSing,
+				equal:   false,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -68,6 +79,87 @@ func TestDecrypt(t *testing.T) {
 	}
 }
 
+func TestParseToken(t *testing.T) {
+	secretKey := []byte("S@rV@")
+	myUUID, sessionID := uuid.New(), uuid.New()
+	token := SignToken(myUUID, sessionID, time.Now().Add(time.Minute), secretKey)
+
+	tests := []struct {
+		name        string
+		token       string
+		secret      []byte
+		want        uuid.UUID
+		wantSession uuid.UUID
+		wantErr     error
+	}{
+		{name: "valid token", token: token, secret: secretKey, want: myUUID, wantSession: sessionID},
+		{name: "expired token", token: SignToken(myUUID, sessionID, time.Now().Add(-time.Minute), secretKey), secret: secretKey, wantErr: ErrTokenExpired},
+		{name: "other secret", token: token, secret: []byte("Ag@th@"), wantErr: ErrNotValidSing},
+		{name: "other user", token: Encrypt(uuid.New(), secretKey)[:32] + token[32:], secret: secretKey, wantErr: ErrNotValidSing},
+		{name: "legacy token", token: Encrypt(myUUID, secretKey), secret: secretKey, wantErr: ErrNotValidSing},
+		{name: "not hex", token: "token", secret: secretKey, wantErr: ErrNotValidSing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotSession, err := ParseToken(tt.token, tt.secret)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantSession, gotSession)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		current string
+		wantErr bool
+	}{
+		{name: "one key", spec: "k1:0123456789abcdef", current: "k1"},
+		{name: "rotation", spec: " k2:0123456789abcdef , k1:fedcba9876543210", current: "k2"},
+		{name: "no keys", spec: "", wantErr: true},
+		{name: "no kid", spec: "0123456789abcdef", wantErr: true},
+		{name: "dot in kid", spec: "k.1:0123456789abcdef", wantErr: true},
+		{name: "short secret", spec: "k1:secret", wantErr: true},
+		{name: "duplicated kid", spec: "k1:0123456789abcdef,k1:fedcba9876543210", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := NewKeyring(tt.spec)
+			assert.Equal(t, tt.wantErr, err != nil)
+			if !tt.wantErr {
+				assert.Equal(t, tt.current, keyring.current)
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old, _ := NewKeyring("k1:0123456789abcdef")
+	rotated, _ := NewKeyring("k2:fedcba9876543210,k1:0123456789abcdef")
+	retired, _ := NewKeyring("k2:fedcba9876543210")
+	myUUID, sessionID := uuid.New(), uuid.New()
+
+	oldToken := old.SignToken(myUUID, sessionID, time.Now().Add(time.Minute))
+	gotUser, gotSession, err := rotated.ParseToken(oldToken)
+	assert.NoError(t, err)
+	assert.Equal(t, myUUID, gotUser)
+	assert.Equal(t, sessionID, gotSession)
+
+	_, _, err = retired.ParseToken(oldToken)
+	assert.Equal(t, ErrNotValidSing, err)
+
+	newHash := rotated.Encrypt(myUUID)
+	assert.Equal(t, "k2.", newHash[:3])
+	got, err := retired.Decrypt(newHash)
+	assert.NoError(t, err)
+	assert.Equal(t, myUUID, got)
+
+	_, err = rotated.Decrypt(Encrypt(myUUID, []byte("0123456789abcdef")))
+	assert.Equal(t, ErrNotValidSing, err)
+}
+
 func BenchmarkCrypt(b *testing.B) {
 	secretKey := []byte("S@rV@")
 	rand.Seed(time.Now().UnixNano())
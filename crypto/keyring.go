@@ -0,0 +1,106 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoKeys - error that occurs when no signing key is configured
+var ErrNoKeys = errors.New("no signing keys configured")
+
+// minKeyLength minimal length of signing secret
+const minKeyLength = 16
+
+// Keyring signing keys by their ids, the first configured key signs new tokens
+// and all of them verify, so old keys stay in the list until their tokens expire
+type Keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyring parses keys in format "kid:secret,kid:secret", the first key is current
+func NewKeyring(spec string) (*Keyring, error) {
+	keyring := Keyring{keys: make(map[string][]byte)}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if len(pair) == 0 {
+			continue
+		}
+		kid, secret, found := strings.Cut(pair, ":")
+		if !found || !validKeyID(kid) {
+			return nil, fmt.Errorf("signing key %q must be kid:secret with kid of letters, digits, - and _", kid)
+		}
+		if len(secret) < minKeyLength {
+			return nil, fmt.Errorf("signing key %q must be at least %d characters", kid, minKeyLength)
+		}
+		if _, ok := keyring.keys[kid]; ok {
+			return nil, fmt.Errorf("signing key %q is duplicated", kid)
+		}
+		keyring.keys[kid] = []byte(secret)
+		if len(keyring.current) == 0 {
+			keyring.current = kid
+		}
+	}
+	if len(keyring.current) == 0 {
+		return nil, ErrNoKeys
+	}
+	return &keyring, nil
+}
+
+// Encrypt convert uuid to hash signed by current key
+func (k *Keyring) Encrypt(id uuid.UUID) string {
+	return k.current + "." + Encrypt(id, k.keys[k.current])
+}
+
+// Decrypt convert hash signed by any of the keys to uuid
+func (k *Keyring) Decrypt(hashString string) (uuid.UUID, error) {
+	secret, data, err := k.split(hashString)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	return Decrypt(data, secret)
+}
+
+// SignToken convert user uuid, session uuid and time of expiration to token signed by current key
+func (k *Keyring) SignToken(userID uuid.UUID, sessionID uuid.UUID, expires time.Time) string {
+	return k.current + "." + SignToken(userID, sessionID, expires, k.keys[k.current])
+}
+
+// ParseToken convert token signed by any of the keys to user uuid and session uuid
+func (k *Keyring) ParseToken(token string) (uuid.UUID, uuid.UUID, error) {
+	secret, data, err := k.split(token)
+	if err != nil {
+		return uuid.UUID{}, uuid.UUID{}, err
+	}
+	return ParseToken(data, secret)
+}
+
+// split secret of the key id prefixing the value and the rest of the value
+func (k *Keyring) split(value string) ([]byte, string, error) {
+	kid, data, found := strings.Cut(value, ".")
+	if !found {
+		return nil, "", ErrNotValidSing
+	}
+	secret, ok := k.keys[kid]
+	if !ok {
+		return nil, "", ErrNotValidSing
+	}
+	return secret, data, nil
+}
+
+// validKeyID key id is not empty and consists of letters, digits, - and _
+func validKeyID(kid string) bool {
+	if len(kid) == 0 {
+		return false
+	}
+	for _, c := range kid {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
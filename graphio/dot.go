@@ -0,0 +1,71 @@
+package graphio
+
+import (
+	"AlexSarva/media/models"
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// dotEscaper escapes strings for double-quoted DOT identifiers
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+
+// quote makes DOT double-quoted identifier
+func quote(value string) string {
+	return `"` + dotEscaper.Replace(value) + `"`
+}
+
+// WriteDOT serialises graph as Graphviz digraph,
+// edges are as wide as the logarithm of their links
+func WriteDOT(w io.Writer, nodes []models.GraphNode, edges []models.GraphEdge) error {
+	buf := bufio.NewWriter(w)
+	fmt.Fprintln(buf, "digraph media {")
+	fmt.Fprintln(buf, `  node [shape=ellipse, style=filled, fontname="Helvetica"];`)
+
+	for _, node := range nodes {
+		attributes := []string{
+			"label=" + quote(node.Title),
+			"URL=" + quote(node.Label),
+			"tooltip=" + quote(node.Label),
+		}
+		if color, ok := NodeColor(node); ok {
+			attributes = append(attributes, "fillcolor="+quote(color.Hex()))
+		}
+		if node.X != nil && node.Y != nil {
+			attributes = append(attributes, "pos="+quote(fmt.Sprintf("%g,%g!", *node.X, *node.Y)))
+		}
+		if node.Community != nil {
+			attributes = append(attributes, "community="+strconv.Itoa(*node.Community))
+		}
+		fmt.Fprintf(buf, "  %s [%s];\n", nodeID(node.ID), strings.Join(attributes, ", "))
+	}
+
+	for _, edge := range edges {
+		width := 1.0
+		if edge.Value > 1 {
+			width += logWidth(edge.Value)
+		}
+		attributes := []string{
+			"weight=" + strconv.Itoa(int(edge.Value)),
+			"penwidth=" + strconv.FormatFloat(width, 'f', 2, 64),
+		}
+		if edge.Dashes {
+			attributes = append(attributes, "style=dashed")
+		}
+		fmt.Fprintf(buf, "  %s -> %s [%s];\n", nodeID(edge.From), nodeID(edge.To), strings.Join(attributes, ", "))
+	}
+
+	fmt.Fprintln(buf, "}")
+	return buf.Flush()
+}
+
+// logWidth grows edge width slowly with the number of links
+func logWidth(links int32) float64 {
+	width := 0.0
+	for value := links; value > 1; value /= 2 {
+		width += 0.5
+	}
+	return width
+}
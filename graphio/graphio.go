@@ -0,0 +1,193 @@
+package graphio
+
+import (
+	"AlexSarva/media/models"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Formats of graph serialisation
+const (
+	FormatJSON    = "json"
+	FormatGraphML = "graphml"
+	FormatGEXF    = "gexf"
+	FormatDOT     = "dot"
+)
+
+// ErrUnknownFormat error that occurs when the graph format is not supported
+var ErrUnknownFormat = errors.New("unknown graph format")
+
+// contentTypes media types of formats, the first one is used in responses
+var contentTypes = map[string][]string{
+	FormatJSON:    {"application/json"},
+	FormatGraphML: {"application/graphml+xml", "application/x-graphml"},
+	FormatGEXF:    {"application/gexf+xml", "application/x-gexf"},
+	FormatDOT:     {"text/vnd.graphviz", "text/x-dot"},
+}
+
+// ContentType media type of the response in the format
+func ContentType(format string) string {
+	return contentTypes[format][0]
+}
+
+// FormatByName checks the name of the format passed by the client
+func FormatByName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if _, ok := contentTypes[name]; ok {
+		return name, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// FormatByAccept picks the first format from Accept header, JSON when nothing matches
+func FormatByAccept(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType := strings.ToLower(strings.TrimSpace(strings.Split(part, ";")[0]))
+		for format, types := range contentTypes {
+			for _, contentType := range types {
+				if mediaType == contentType {
+					return format
+				}
+			}
+		}
+	}
+	return FormatJSON
+}
+
+// Write serialises graph in one of the exchange formats
+func Write(w io.Writer, format string, nodes []models.GraphNode, edges []models.GraphEdge) error {
+	switch format {
+	case FormatGraphML:
+		return WriteGraphML(w, nodes, edges)
+	case FormatGEXF:
+		return WriteGEXF(w, nodes, edges)
+	case FormatDOT:
+		return WriteDOT(w, nodes, edges)
+	}
+	return ErrUnknownFormat
+}
+
+// RGBA colour components parsed from the colours used by the frontend
+type RGBA struct {
+	R, G, B uint8
+	A       float64
+}
+
+// Hex colour in #rrggbb notation
+func (c RGBA) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// NodeColor background colour of the node, false when the node has no colour
+func NodeColor(node models.GraphNode) (RGBA, bool) {
+	switch color := node.Color.(type) {
+	case models.GraphNodeColor:
+		return ParseColor(color.Background)
+	case *models.GraphNodeColor:
+		if color != nil {
+			return ParseColor(color.Background)
+		}
+	case string:
+		return ParseColor(color)
+	}
+	return RGBA{}, false
+}
+
+// ParseColor parses rgb(...), rgba(...) and #rrggbb colours
+func ParseColor(value string) (RGBA, bool) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "#") && len(value) == 7 {
+		rgb, err := strconv.ParseUint(value[1:], 16, 32)
+		if err != nil {
+			return RGBA{}, false
+		}
+		return RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 1}, true
+	}
+
+	open, closing := strings.Index(value, "("), strings.LastIndex(value, ")")
+	if open < 0 || closing < open {
+		return RGBA{}, false
+	}
+	parts := strings.Split(value[open+1:closing], ",")
+	if len(parts) != 3 && len(parts) != 4 {
+		return RGBA{}, false
+	}
+	var components [3]uint8
+	for i := 0; i < 3; i++ {
+		component, err := strconv.ParseUint(strings.TrimSpace(parts[i]), 10, 8)
+		if err != nil {
+			return RGBA{}, false
+		}
+		components[i] = uint8(component)
+	}
+	color := RGBA{R: components[0], G: components[1], B: components[2], A: 1}
+	if len(parts) == 4 {
+		alpha, err := strconv.ParseFloat(strings.TrimSpace(parts[3]), 64)
+		if err != nil {
+			return RGBA{}, false
+		}
+		color.A = alpha
+	}
+	return color, true
+}
+
+// attribute value of a node attribute that is present on the node
+type attribute struct {
+	key   string
+	value string
+}
+
+// nodeAttributes optional attributes of the node in the order of nodeKeys
+func nodeAttributes(node models.GraphNode) []attribute {
+	attributes := []attribute{
+		{"url", node.Label},
+		{"title", node.Title},
+		{"links", strconv.FormatFloat(node.Value, 'f', -1, 64)},
+	}
+	if color, ok := NodeColor(node); ok {
+		attributes = append(attributes, attribute{"color", color.Hex()})
+	}
+	if node.Community != nil {
+		attributes = append(attributes, attribute{"community", strconv.Itoa(*node.Community)})
+	}
+	if node.PageRank != nil {
+		attributes = append(attributes, attribute{"pagerank", strconv.FormatFloat(*node.PageRank, 'g', -1, 64)})
+	}
+	if node.Betweenness != nil {
+		attributes = append(attributes, attribute{"betweenness", strconv.FormatFloat(*node.Betweenness, 'g', -1, 64)})
+	}
+	if node.InDegree != nil {
+		attributes = append(attributes, attribute{"in_degree", strconv.FormatInt(*node.InDegree, 10)})
+	}
+	if node.OutDegree != nil {
+		attributes = append(attributes, attribute{"out_degree", strconv.FormatInt(*node.OutDegree, 10)})
+	}
+	return attributes
+}
+
+// nodeKey declaration of node attribute shared by GraphML and GEXF
+type nodeKey struct {
+	name     string
+	dataType string
+}
+
+// nodeKeys all node attributes that may appear in exported graphs
+var nodeKeys = []nodeKey{
+	{"url", "string"},
+	{"title", "string"},
+	{"links", "double"},
+	{"color", "string"},
+	{"community", "int"},
+	{"pagerank", "double"},
+	{"betweenness", "double"},
+	{"in_degree", "long"},
+	{"out_degree", "long"},
+}
+
+// nodeID string identifier of a node in exported graphs
+func nodeID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,156 @@
+package graphio
+
+import (
+	"AlexSarva/media/models"
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testGraph() ([]models.GraphNode, []models.GraphEdge) {
+	community := 2
+	x, y := 1.5, -3.0
+	nodes := []models.GraphNode{
+		{ID: 1, Label: "https://a.ru", Title: `A & "B"`, Value: 10, Color: models.GraphNodeColor{Background: "rgba(8, 217, 174, 0.9)"}, Community: &community, X: &x, Y: &y},
+		{ID: 2, Label: "https://b.ru", Title: "B", Value: 3},
+	}
+	edges := []models.GraphEdge{{From: 1, To: 2, Value: 7, Dashes: true, Direction: models.DirectionOut}}
+	return nodes, edges
+}
+
+// wellFormed reads the whole XML document and reports the first syntax error
+func wellFormed(data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	nodes, edges := testGraph()
+	tests := []struct {
+		format   string
+		xml      bool
+		contains []string
+	}{
+		{FormatGraphML, true, []string{`<node id="1">`, `<data key="community">2</data>`, `<data key="color">#08d9ae</data>`, `<edge source="1" target="2">`, `<data key="weight">7</data>`}},
+		{FormatGEXF, true, []string{`<node id="1" label="A &amp; &#34;B&#34;">`, `<viz:color r="8" g="217" b="174" a="0.9">`, `<viz:position x="1.5" y="-3" z="0">`, `weight="7"`}},
+		{FormatDOT, false, []string{`1 [label="A & \"B\"", URL="https://a.ru"`, `fillcolor="#08d9ae"`, `pos="1.5,-3!"`, `1 -> 2 [weight=7`, `style=dashed`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, Write(&buf, tt.format, nodes, edges))
+			if tt.xml {
+				assert.NoError(t, wellFormed(buf.Bytes()))
+			}
+			for _, part := range tt.contains {
+				assert.True(t, strings.Contains(buf.String(), part), "%s not found in\n%s", part, buf.String())
+			}
+		})
+	}
+	assert.ErrorIs(t, Write(io.Discard, "svg", nodes, edges), ErrUnknownFormat)
+}
+
+func TestFormatByAccept(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", FormatJSON},
+		{"*/*", FormatJSON},
+		{"application/gexf+xml", FormatGEXF},
+		{"text/html, application/graphml+xml;q=0.9", FormatGraphML},
+		{"text/vnd.graphviz", FormatDOT},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, FormatByAccept(tt.accept), tt.accept)
+	}
+}
+
+func TestWriteTables(t *testing.T) {
+	country := "Россия"
+	tables := GraphTables(models.GraphTables{
+		Nodes: []models.GraphTableNode{
+			{ID: 1, URL: "https://a.ru", Title: "A <&>", Links: 10, Neighbours: 1, SourceMetrics: models.SourceMetrics{Country: &country}},
+			{ID: 2, URL: "https://b.ru", Title: "B", Links: 3, Neighbours: 1},
+		},
+		Edges: []models.GraphTableEdge{{From: 1, FromURL: "https://a.ru", FromTitle: "A <&>", To: 2, ToURL: "https://b.ru", ToTitle: "B", Links: 7}},
+	})
+
+	var csvBuf bytes.Buffer
+	assert.NoError(t, WriteCSV(&csvBuf, tables))
+	files := unzip(t, csvBuf.Bytes())
+	assert.Equal(t, utf8BOM+"id,url,title,links,neighbours,country,category,subscribers,posts,forwarded_posts,forwarded_reactions,avg_daily_subscribers,err,citation_index\n"+
+		"1,https://a.ru,A <&>,10,1,Россия,,,,,,,,\n"+
+		"2,https://b.ru,B,3,1,,,,,,,,,\n", files["nodes.csv"])
+	assert.Equal(t, utf8BOM+"from_id,from_url,from_title,to_id,to_url,to_title,links\n1,https://a.ru,A <&>,2,https://b.ru,B,7\n", files["edges.csv"])
+
+	var xlsxBuf bytes.Buffer
+	assert.NoError(t, WriteXLSX(&xlsxBuf, tables))
+	files = unzip(t, xlsxBuf.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		assert.NoError(t, wellFormed([]byte(files[name])), name)
+	}
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="edges" sheetId="2" r:id="rId2"/>`)
+	assert.Contains(t, files["xl/worksheets/sheet1.xml"], `<c r="A2"><v>1</v></c><c r="B2" t="inlineStr"><is><t>https://a.ru</t></is></c><c r="C2" t="inlineStr"><is><t>A &lt;&amp;&gt;</t></is></c>`)
+	assert.Contains(t, files["xl/worksheets/sheet2.xml"], `<c r="G2"><v>7</v></c>`)
+}
+
+func TestColumnName(t *testing.T) {
+	for column, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 702: "AAA"} {
+		assert.Equal(t, want, columnName(column))
+	}
+}
+
+// unzip contents of archive files by their names
+func unzip(t *testing.T, data []byte) map[string]string {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		reader, openErr := file.Open()
+		assert.NoError(t, openErr)
+		content, readErr := io.ReadAll(reader)
+		assert.NoError(t, readErr)
+		files[file.Name] = string(content)
+	}
+	return files
+}
+
+func TestReadSources(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   []models.ImportRow
+		err    error
+	}{
+		{"csv with header", ImportCSV, utf8BOM + "title;URL\nA;https://a.ru\nB;\nC;t.me/c\n", []models.ImportRow{{Row: 2, URL: "https://a.ru"}, {Row: 4, URL: "t.me/c"}}, nil},
+		{"csv without header", ImportCSV, "https://a.ru,1\nb.ru,2\n", []models.ImportRow{{Row: 1, URL: "https://a.ru"}, {Row: 2, URL: "b.ru"}}, nil},
+		{"csv unknown header", ImportCSV, "канал\nhttps://a.ru\n", []models.ImportRow{{Row: 2, URL: "https://a.ru"}}, nil},
+		{"graphml url attribute", ImportGraphML, `<graphml><key id="d0" for="node" attr.name="url" attr.type="string"/><graph><node id="1"><data key="d0">https://a.ru</data></node><node id="b.ru"/></graph></graphml>`, []models.ImportRow{{Row: 1, URL: "https://a.ru"}, {Row: 2, URL: "b.ru"}}, nil},
+		{"json strings", ImportJSON, `["https://a.ru", " ", "b.ru"]`, []models.ImportRow{{Row: 1, URL: "https://a.ru"}, {Row: 3, URL: "b.ru"}}, nil},
+		{"json graph nodes", ImportJSON, `{"nodes": [{"id": 1, "title": "https://a.ru"}, {"url": "b.ru"}]}`, []models.ImportRow{{Row: 1, URL: "https://a.ru"}, {Row: 2, URL: "b.ru"}}, nil},
+		{"empty json", ImportJSON, `{"sources": []}`, nil, ErrNoSources},
+		{"unknown format", "xls", "", nil, ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ReadSources(strings.NewReader(tt.data), tt.format)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, rows)
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package graphio
+
+import (
+	"AlexSarva/media/models"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Formats of imported source lists
+const (
+	ImportCSV     = "csv"
+	ImportGraphML = "graphml"
+	ImportJSON    = "json"
+)
+
+// ErrNoSources error that occurs when imported file has no source URLs
+var ErrNoSources = errors.New("no sources in file")
+
+// urlColumns names of CSV columns with source URL in order of preference
+var urlColumns = []string{"url", "base_url", "source", "link", "ссылка", "источник"}
+
+// ImportFormat format of imported file by its name, empty when unknown
+func ImportFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv", ".txt":
+		return ImportCSV
+	case ".graphml", ".xml":
+		return ImportGraphML
+	case ".json":
+		return ImportJSON
+	}
+	return ""
+}
+
+// ReadSources reads source URLs from CSV, GraphML or JSON file
+func ReadSources(r io.Reader, format string) ([]models.ImportRow, error) {
+	var rows []models.ImportRow
+	var err error
+	switch format {
+	case ImportCSV:
+		rows, err = readCSVSources(r)
+	case ImportGraphML:
+		rows, err = readGraphMLSources(r)
+	case ImportJSON:
+		rows, err = readJSONSources(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrNoSources
+	}
+	return rows, nil
+}
+
+// readCSVSources reads URL column of CSV with comma or semicolon delimiter.
+// The column is found by header, without known header the first column is used
+func readCSVSources(r io.Reader) ([]models.ImportRow, error) {
+	buffered := bufio.NewReader(r)
+	if bom, _ := buffered.Peek(len(utf8BOM)); string(bom) == utf8BOM {
+		buffered.Discard(len(utf8BOM))
+	}
+	reader := csv.NewReader(buffered)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	head, _ := buffered.Peek(4096)
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+	if bytes.Count(head, []byte(";")) > bytes.Count(head, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	column, start := 0, 0
+	if found := headerColumn(records[0]); found >= 0 {
+		column, start = found, 1
+	} else if len(records[0]) > 0 && !strings.Contains(records[0][0], ".") {
+		// first row is a header without known column names
+		start = 1
+	}
+
+	var rows []models.ImportRow
+	for i := start; i < len(records); i++ {
+		if column >= len(records[i]) {
+			continue
+		}
+		if url := strings.TrimSpace(records[i][column]); url != "" {
+			rows = append(rows, models.ImportRow{Row: i + 1, URL: url})
+		}
+	}
+	return rows, nil
+}
+
+// headerColumn index of URL column in the header, -1 when there is no such column
+func headerColumn(header []string) int {
+	for _, name := range urlColumns {
+		for i, cell := range header {
+			if strings.EqualFold(strings.TrimSpace(cell), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+type graphmlImport struct {
+	Keys []graphmlKey `xml:"key"`
+	// nodes of the first graph
+	Nodes []graphmlNode `xml:"graph>node"`
+}
+
+// readGraphMLSources reads nodes of GraphML, URL is taken from node attribute
+// url, label or title, node id is used when there are no such attributes
+func readGraphMLSources(r io.Reader) ([]models.ImportRow, error) {
+	var document graphmlImport
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]string, len(document.Keys))
+	for _, key := range document.Keys {
+		if key.For == "node" || key.For == "all" || key.For == "" {
+			keys[strings.ToLower(key.AttrName)] = key.ID
+		}
+	}
+	var urlKey string
+	for _, name := range []string{"url", "label", "title"} {
+		if id, ok := keys[name]; ok {
+			urlKey = id
+			break
+		}
+	}
+
+	var rows []models.ImportRow
+	for i, node := range document.Nodes {
+		url := node.ID
+		for _, data := range node.Data {
+			if urlKey != "" && data.Key == urlKey {
+				url = data.Value
+			}
+		}
+		if url = strings.TrimSpace(url); url != "" {
+			rows = append(rows, models.ImportRow{Row: i + 1, URL: url})
+		}
+	}
+	return rows, nil
+}
+
+// readJSONSources reads list of URLs or objects with url field,
+// the list may be wrapped into object with sources or nodes field
+func readJSONSources(r io.Reader) ([]models.ImportRow, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '{' {
+		var wrapper struct {
+			Sources json.RawMessage `json:"sources"`
+			Nodes   json.RawMessage `json:"nodes"`
+		}
+		if err := json.Unmarshal(raw, &wrapper); err != nil {
+			return nil, err
+		}
+		raw = wrapper.Sources
+		if raw == nil {
+			raw = wrapper.Nodes
+		}
+		if raw == nil {
+			return nil, nil
+		}
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	var rows []models.ImportRow
+	for i, item := range items {
+		var url string
+		if err := json.Unmarshal(item, &url); err != nil {
+			// graph responses keep source URL in title field
+			var object struct {
+				URL   string `json:"url"`
+				Title string `json:"title"`
+			}
+			if objectErr := json.Unmarshal(item, &object); objectErr != nil {
+				return nil, objectErr
+			}
+			url = object.URL
+			if url == "" {
+				url = object.Title
+			}
+		}
+		if url = strings.TrimSpace(url); url != "" {
+			rows = append(rows, models.ImportRow{Row: i + 1, URL: url})
+		}
+	}
+	return rows, nil
+}
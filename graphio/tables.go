@@ -0,0 +1,115 @@
+package graphio
+
+import (
+	"AlexSarva/media/models"
+	"archive/zip"
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// Content types of exported tables
+const (
+	ContentTypeZIP  = "application/zip"
+	ContentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// utf8BOM makes Excel open CSV with cyrillic titles in UTF-8
+const utf8BOM = "\xef\xbb\xbf"
+
+// Table sheet of exported graph, Numeric marks columns written as numbers
+type Table struct {
+	Name    string
+	Header  []string
+	Numeric []bool
+	Rows    [][]string
+}
+
+// GraphTables converts saved graph into nodes and edges tables
+func GraphTables(graph models.GraphTables) []Table {
+	nodes := Table{
+		Name: "nodes",
+		Header: []string{"id", "url", "title", "links", "neighbours", "country", "category", "subscribers", "posts",
+			"forwarded_posts", "forwarded_reactions", "avg_daily_subscribers", "err", "citation_index"},
+		Numeric: []bool{true, false, false, true, true, false, false, true, true, true, true, true, true, true},
+	}
+	for _, node := range graph.Nodes {
+		nodes.Rows = append(nodes.Rows, []string{
+			strconv.FormatInt(node.ID, 10),
+			node.URL,
+			node.Title,
+			strconv.FormatInt(node.Links, 10),
+			strconv.Itoa(node.Neighbours),
+			optionalString(node.Country),
+			optionalString(node.Category),
+			optionalInt(node.Subscribers),
+			optionalInt(node.Posts),
+			optionalInt(node.ForwardedPosts),
+			optionalInt(node.ForwardedReactions),
+			optionalFloat(node.AvgDailySubscribers),
+			optionalFloat(node.ERR),
+			optionalFloat(node.CitationIndex),
+		})
+	}
+
+	edges := Table{
+		Name:    "edges",
+		Header:  []string{"from_id", "from_url", "from_title", "to_id", "to_url", "to_title", "links"},
+		Numeric: []bool{true, false, false, true, false, false, true},
+	}
+	for _, edge := range graph.Edges {
+		edges.Rows = append(edges.Rows, []string{
+			strconv.FormatInt(edge.From, 10),
+			edge.FromURL,
+			edge.FromTitle,
+			strconv.FormatInt(edge.To, 10),
+			edge.ToURL,
+			edge.ToTitle,
+			strconv.Itoa(int(edge.Links)),
+		})
+	}
+	return []Table{nodes, edges}
+}
+
+// WriteCSV writes one CSV file per table packed into zip archive
+func WriteCSV(w io.Writer, tables []Table) error {
+	archive := zip.NewWriter(w)
+	for _, table := range tables {
+		file, err := archive.Create(table.Name + ".csv")
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(file, utf8BOM); err != nil {
+			return err
+		}
+		writer := csv.NewWriter(file)
+		if err = writer.Write(table.Header); err != nil {
+			return err
+		}
+		if err = writer.WriteAll(table.Rows); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func optionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func optionalInt(value *int64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatInt(*value, 10)
+}
+
+func optionalFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
@@ -0,0 +1,214 @@
+package graphio
+
+import (
+	"AlexSarva/media/models"
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+type graphmlKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphmlNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphmlData `xml:"data"`
+}
+
+type graphmlEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphmlData `xml:"data"`
+}
+
+type graphmlGraph struct {
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+
+type graphmlDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphmlKey `xml:"key"`
+	Graph   graphmlGraph `xml:"graph"`
+}
+
+// WriteGraphML serialises graph as GraphML with node attributes and edge weights
+func WriteGraphML(w io.Writer, nodes []models.GraphNode, edges []models.GraphEdge) error {
+	graphmlTypes := map[string]string{"string": "string", "double": "double", "int": "int", "long": "long"}
+	document := graphmlDocument{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Graph: graphmlGraph{EdgeDefault: "directed"},
+	}
+	for _, key := range nodeKeys {
+		document.Keys = append(document.Keys, graphmlKey{ID: key.name, For: "node", AttrName: key.name, AttrType: graphmlTypes[key.dataType]})
+	}
+	document.Keys = append(document.Keys,
+		graphmlKey{ID: "x", For: "node", AttrName: "x", AttrType: "double"},
+		graphmlKey{ID: "y", For: "node", AttrName: "y", AttrType: "double"},
+		graphmlKey{ID: "weight", For: "edge", AttrName: "weight", AttrType: "int"},
+		graphmlKey{ID: "direction", For: "edge", AttrName: "direction", AttrType: "string"},
+	)
+
+	for _, node := range nodes {
+		element := graphmlNode{ID: nodeID(node.ID)}
+		for _, attr := range nodeAttributes(node) {
+			element.Data = append(element.Data, graphmlData{Key: attr.key, Value: attr.value})
+		}
+		if node.X != nil && node.Y != nil {
+			element.Data = append(element.Data,
+				graphmlData{Key: "x", Value: strconv.FormatFloat(*node.X, 'f', -1, 64)},
+				graphmlData{Key: "y", Value: strconv.FormatFloat(*node.Y, 'f', -1, 64)},
+			)
+		}
+		document.Graph.Nodes = append(document.Graph.Nodes, element)
+	}
+	for _, edge := range edges {
+		element := graphmlEdge{
+			Source: nodeID(edge.From),
+			Target: nodeID(edge.To),
+			Data:   []graphmlData{{Key: "weight", Value: strconv.Itoa(int(edge.Value))}},
+		}
+		if edge.Direction != "" {
+			element.Data = append(element.Data, graphmlData{Key: "direction", Value: edge.Direction})
+		}
+		document.Graph.Edges = append(document.Graph.Edges, element)
+	}
+
+	return writeXML(w, document)
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfColor struct {
+	R uint8   `xml:"r,attr"`
+	G uint8   `xml:"g,attr"`
+	B uint8   `xml:"b,attr"`
+	A float64 `xml:"a,attr"`
+}
+
+type gexfPosition struct {
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+	Z float64 `xml:"z,attr"`
+}
+
+type gexfSize struct {
+	Value float64 `xml:"value,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+	Color     *gexfColor     `xml:"viz:color,omitempty"`
+	Position  *gexfPosition  `xml:"viz:position,omitempty"`
+	Size      *gexfSize      `xml:"viz:size,omitempty"`
+}
+
+type gexfEdge struct {
+	ID     string  `xml:"id,attr"`
+	Source string  `xml:"source,attr"`
+	Target string  `xml:"target,attr"`
+	Weight float64 `xml:"weight,attr"`
+	Label  string  `xml:"label,attr,omitempty"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string         `xml:"defaultedgetype,attr"`
+	Attributes      gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode     `xml:"nodes>node"`
+	Edges           []gexfEdge     `xml:"edges>edge"`
+}
+
+type gexfDocument struct {
+	XMLName xml.Name  `xml:"gexf"`
+	XMLNS   string    `xml:"xmlns,attr"`
+	VizNS   string    `xml:"xmlns:viz,attr"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+// WriteGEXF serialises graph as GEXF 1.3 with node attributes, colours, positions and sizes
+func WriteGEXF(w io.Writer, nodes []models.GraphNode, edges []models.GraphEdge) error {
+	document := gexfDocument{
+		XMLNS:   "http://gexf.net/1.3",
+		VizNS:   "http://gexf.net/1.3/viz",
+		Version: "1.3",
+		Graph: gexfGraph{
+			DefaultEdgeType: "directed",
+			Attributes:      gexfAttributes{Class: "node"},
+		},
+	}
+	for _, key := range nodeKeys {
+		document.Graph.Attributes.Attributes = append(document.Graph.Attributes.Attributes,
+			gexfAttribute{ID: key.name, Title: key.name, Type: key.dataType})
+	}
+
+	for _, node := range nodes {
+		element := gexfNode{
+			ID:    nodeID(node.ID),
+			Label: node.Title,
+			Size:  &gexfSize{Value: node.Value},
+		}
+		for _, attr := range nodeAttributes(node) {
+			element.AttValues = append(element.AttValues, gexfAttValue{For: attr.key, Value: attr.value})
+		}
+		if color, ok := NodeColor(node); ok {
+			element.Color = &gexfColor{R: color.R, G: color.G, B: color.B, A: color.A}
+		}
+		if node.X != nil && node.Y != nil {
+			element.Position = &gexfPosition{X: *node.X, Y: *node.Y}
+		}
+		document.Graph.Nodes = append(document.Graph.Nodes, element)
+	}
+	for i, edge := range edges {
+		document.Graph.Edges = append(document.Graph.Edges, gexfEdge{
+			ID:     strconv.Itoa(i),
+			Source: nodeID(edge.From),
+			Target: nodeID(edge.To),
+			Weight: float64(edge.Value),
+			Label:  edge.Direction,
+		})
+	}
+
+	return writeXML(w, document)
+}
+
+// writeXML writes indented XML document with the header
+func writeXML(w io.Writer, document interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
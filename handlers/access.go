@@ -0,0 +1,548 @@
+package handlers
+
+import (
+	"AlexSarva/media/crypto"
+	"AlexSarva/media/graphio"
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/storage/storagepg"
+	"AlexSarva/media/styling"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetGraphAccess - list of users with access to saved graph
+//
+// Handler POST /api/graph/share/list
+//
+// The handler is available only to the owner of the graph.
+// Request format:
+//
+//	{"graph_id": "5b3a8c2e-..."}
+//
+// Possible response codes:
+// 200 - owner and users the graph is shared with;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is not the owner of the graph;
+// 404 - graph not found;
+// 500 - an internal server error.
+func GetGraphAccess(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var query models.GraphRef
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		access, accessErr := database.Repo.GetGraphAccess(r.Context(), userID, query.GraphID)
+		if accessErr != nil {
+			if errors.Is(accessErr, storagepg.ErrForbidden) {
+				messageResponse(w, accessErr.Error(), "application/json", http.StatusForbidden)
+				return
+			}
+			if errors.Is(accessErr, sql.ErrNoRows) {
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(accessErr)
+			messageResponse(w, "Internal Server Error: "+accessErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		accessRes, accessResErr := json.Marshal(access)
+		if accessResErr != nil {
+			panic(accessResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(accessRes)
+	}
+}
+
+// ShareGraph - granting of access to saved graph to another user
+//
+// Handler POST /api/graph/share
+//
+// The handler is available only to the owner of the graph.
+// Editors can change the graph and restore its revisions, viewers can only read it.
+// Repeated request changes the role of the user. Request format:
+//
+//	{"graph_id": "5b3a8c2e-...", "email": "colleague@example.com", "role": "viewer"}
+//
+// Possible response codes:
+// 200 - access is granted, users with access in response;
+// 400 - invalid request format or graph is shared with its owner;
+// 401 - user not authenticated;
+// 403 - user is not the owner of the graph;
+// 404 - graph or user with the email not found;
+// 500 - an internal server error.
+func ShareGraph(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var share models.GraphShare
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&share)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if len(share.Email) == 0 {
+			messageResponse(w, "Bad Request. email must be set", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		if share.Role != models.GraphRoleEditor && share.Role != models.GraphRoleViewer {
+			messageResponse(w, "Bad Request. role must be editor or viewer", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		access, accessErr := database.Repo.ShareGraph(r.Context(), userID, share)
+		if accessErr != nil {
+			switch {
+			case errors.Is(accessErr, storagepg.ErrForbidden):
+				messageResponse(w, accessErr.Error(), "application/json", http.StatusForbidden)
+			case errors.Is(accessErr, sql.ErrNoRows):
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+			case errors.Is(accessErr, storagepg.ErrUnknownUser):
+				messageResponse(w, accessErr.Error(), "application/json", http.StatusNotFound)
+			case errors.Is(accessErr, storagepg.ErrNotValidShare):
+				messageResponse(w, "Bad Request. "+accessErr.Error(), "application/json", http.StatusBadRequest)
+			default:
+				log.Println(accessErr)
+				messageResponse(w, "Internal Server Error: "+accessErr.Error(), "application/json", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		accessRes, accessResErr := json.Marshal(access)
+		if accessResErr != nil {
+			panic(accessResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(accessRes)
+	}
+}
+
+// UnshareGraph - revoking of access to saved graph from another user
+//
+// Handler DELETE /api/graph/share
+//
+// The handler is available only to the owner of the graph.
+// Request format:
+//
+//	{"graph_id": "5b3a8c2e-...", "email": "colleague@example.com"}
+//
+// Possible response codes:
+// 200 - access is revoked, users with access in response;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is not the owner of the graph;
+// 404 - graph not found or not shared with the user;
+// 500 - an internal server error.
+func UnshareGraph(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var share models.GraphShare
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&share)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if len(share.Email) == 0 {
+			messageResponse(w, "Bad Request. email must be set", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		access, accessErr := database.Repo.UnshareGraph(r.Context(), userID, share)
+		if accessErr != nil {
+			switch {
+			case errors.Is(accessErr, storagepg.ErrForbidden):
+				messageResponse(w, accessErr.Error(), "application/json", http.StatusForbidden)
+			case errors.Is(accessErr, sql.ErrNoRows):
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+			case errors.Is(accessErr, storagepg.ErrNoData):
+				messageResponse(w, "graph is not shared with the user", "application/json", http.StatusNotFound)
+			default:
+				log.Println(accessErr)
+				messageResponse(w, "Internal Server Error: "+accessErr.Error(), "application/json", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		accessRes, accessResErr := json.Marshal(access)
+		if accessResErr != nil {
+			panic(accessResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(accessRes)
+	}
+}
+
+// CreateGraphLink - creation of read-only public link to saved graph
+//
+// Handler POST /api/graph/links
+//
+// The handler is available only to the owner of the graph.
+// Token of the link is signed and opens the graph in /api/public/graph/{token}
+// until the link is revoked. Request format:
+//
+//	{"graph_id": "5b3a8c2e-..."}
+//
+// Possible response codes:
+// 201 - link is created;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is not the owner of the graph;
+// 404 - graph not found;
+// 500 - an internal server error.
+func CreateGraphLink(database *app.Database, keys *crypto.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var query models.GraphRef
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		link, linkErr := database.Repo.CreateGraphLink(r.Context(), userID, query.GraphID)
+		if linkErr != nil {
+			if errors.Is(linkErr, storagepg.ErrForbidden) {
+				messageResponse(w, linkErr.Error(), "application/json", http.StatusForbidden)
+				return
+			}
+			if errors.Is(linkErr, sql.ErrNoRows) {
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(linkErr)
+			messageResponse(w, "Internal Server Error: "+linkErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+		link.Token = keys.Encrypt(link.LinkID)
+
+		linkRes, linkResErr := json.Marshal(link)
+		if linkResErr != nil {
+			panic(linkResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(linkRes)
+	}
+}
+
+// GetGraphLinks - list of public links to saved graph
+//
+// Handler POST /api/graph/links/list
+//
+// The handler is available only to the owner of the graph.
+// Revoked links are listed with the time of revocation. Request format:
+//
+//	{"graph_id": "5b3a8c2e-..."}
+//
+// Possible response codes:
+// 200 - links, the latest first;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is not the owner of the graph;
+// 404 - graph not found;
+// 500 - an internal server error.
+func GetGraphLinks(database *app.Database, keys *crypto.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var query models.GraphRef
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		links, linksErr := database.Repo.GetGraphLinks(r.Context(), userID, query.GraphID)
+		if linksErr != nil {
+			if errors.Is(linksErr, storagepg.ErrForbidden) {
+				messageResponse(w, linksErr.Error(), "application/json", http.StatusForbidden)
+				return
+			}
+			if errors.Is(linksErr, sql.ErrNoRows) {
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(linksErr)
+			messageResponse(w, "Internal Server Error: "+linksErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+		for i := range links {
+			links[i].Token = keys.Encrypt(links[i].LinkID)
+		}
+
+		linksRes, linksResErr := json.Marshal(links)
+		if linksResErr != nil {
+			panic(linksResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(linksRes)
+	}
+}
+
+// RevokeGraphLink - revoking of public link to saved graph
+//
+// Handler DELETE /api/graph/links
+//
+// The handler is available only to the owner of the graph.
+// Request format:
+//
+//	{"graph_id": "5b3a8c2e-...", "link_id": "0f4c61d2-..."}
+//
+// Possible response codes:
+// 204 - link is revoked;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is not the owner of the graph;
+// 404 - graph or active link not found;
+// 500 - an internal server error.
+func RevokeGraphLink(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var ref models.GraphLinkRef
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&ref)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		revokeErr := database.Repo.RevokeGraphLink(r.Context(), userID, ref)
+		if revokeErr != nil {
+			switch {
+			case errors.Is(revokeErr, storagepg.ErrForbidden):
+				messageResponse(w, revokeErr.Error(), "application/json", http.StatusForbidden)
+			case errors.Is(revokeErr, sql.ErrNoRows):
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+			case errors.Is(revokeErr, storagepg.ErrNoData):
+				messageResponse(w, "link not found or already revoked", "application/json", http.StatusNotFound)
+			default:
+				log.Println(revokeErr)
+				messageResponse(w, "Internal Server Error: "+revokeErr.Error(), "application/json", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetSharedGraph - saved graph opened by public link
+//
+// Handler GET /api/public/graph/{token}
+//
+// The handler does not require authorization, token is issued by /api/graph/links.
+// Optional query parameter min_links sets the threshold of edges,
+// the response format and the style of nodes are chosen as in /api/graph/uuid.
+//
+// Possible response codes:
+// 200 - graph of the link;
+// 400 - invalid query parameters;
+// 404 - link is not valid, revoked or its graph is deleted;
+// 406 - requested format is not supported;
+// 500 - an internal server error.
+func GetSharedGraph(database *app.Database, keys *crypto.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, formatErr := graphFormat(r)
+		if formatErr != nil {
+			messageResponse(w, "Not Acceptable. "+formatErr.Error(), "application/json", http.StatusNotAcceptable)
+			return
+		}
+
+		style, styleErr := graphStyle(r, styling.RuleKind)
+		if styleErr != nil {
+			messageResponse(w, "Bad Request. "+styleErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		linkID, linkErr := keys.Decrypt(chi.URLParam(r, "token"))
+		if linkErr != nil {
+			messageResponse(w, "link not found", "application/json", http.StatusNotFound)
+			return
+		}
+
+		minLinks, minLinksErr := queryInt(r, "min_links")
+		if minLinksErr != nil {
+			messageResponse(w, "Bad Request. "+minLinksErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+		params := models.GraphParams{MinLinks: minLinks}
+		if paramsErr := validateGraphParams(params); paramsErr != nil {
+			messageResponse(w, "Bad Request. "+paramsErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		graphInfo, graphInfoErr := database.Repo.GetGraphByLink(r.Context(), linkID, params)
+		if graphInfoErr != nil {
+			if errors.Is(graphInfoErr, sql.ErrNoRows) {
+				messageResponse(w, "link not found", "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(graphInfoErr)
+			messageResponse(w, "Internal Server Error: "+graphInfoErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		style.ApplyExtended(&graphInfo)
+		if format != graphio.FormatJSON {
+			writeGraphFile(w, format, graphInfo.Nodes, graphInfo.Edges)
+			return
+		}
+
+		graphRes, graphResErr := json.Marshal(graphInfo)
+		if graphResErr != nil {
+			panic(graphResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(graphRes)
+	}
+}
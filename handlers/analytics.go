@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// DetectCommunities - start of background recalculation of communities of the link graph
+//
+// Handler POST /api/analytics/communities
+//
+// The handler is available only to admins.
+// Louvain takes long on the full graph, so the handler only starts the recalculation,
+// its state is returned by GET /api/analytics/communities.
+// Request format:
+//
+//	{"min_links": 5}
+//
+// Possible response codes:
+// 202 - recalculation is started, its state in response;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is not admin;
+// 409 - a recalculation is already running, its state in response.
+func DetectCommunities(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		var query models.AnalyticsQuery
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if query.MinLinks < 0 {
+			messageResponse(w, "Bad Request. min_links must be 0 (default) or positive", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		job, started := database.StartCommunities(query.MinLinks)
+		status := http.StatusAccepted
+		if !started {
+			status = http.StatusConflict
+		}
+
+		jobRes, jobResErr := json.Marshal(job)
+		if jobResErr != nil {
+			panic(jobResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(jobRes)
+	}
+}
+
+// GetCommunitiesStatus - state of the recalculation of communities
+//
+// Handler GET /api/analytics/communities
+//
+// The handler is available only to admins.
+//
+// Possible response codes:
+// 200 - success, state of the running or the last recalculation in response;
+// 401 - user not authenticated;
+// 403 - user is not admin.
+func GetCommunitiesStatus(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobRes, jobResErr := json.Marshal(database.CommunitiesStatus())
+		if jobResErr != nil {
+			panic(jobResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(jobRes)
+	}
+}
+
+// CalculateMetrics - start of background recalculation of centrality metrics of the link graph
+//
+// Handler POST /api/analytics/metrics
+//
+// The handler is available only to admins.
+// Betweenness takes long on the full graph, so the handler only starts the recalculation,
+// its state is returned by GET /api/analytics/metrics.
+// Request format:
+//
+//	{"min_links": 5}
+//
+// Possible response codes:
+// 202 - recalculation is started, its state in response;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is not admin;
+// 409 - a recalculation is already running, its state in response.
+func CalculateMetrics(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		var query models.AnalyticsQuery
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if query.MinLinks < 0 {
+			messageResponse(w, "Bad Request. min_links must be 0 (default) or positive", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		job, started := database.StartMetrics(query.MinLinks)
+		status := http.StatusAccepted
+		if !started {
+			status = http.StatusConflict
+		}
+
+		jobRes, jobResErr := json.Marshal(job)
+		if jobResErr != nil {
+			panic(jobResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(jobRes)
+	}
+}
+
+// GetMetricsStatus - state of the recalculation of centrality metrics
+//
+// Handler GET /api/analytics/metrics
+//
+// The handler is available only to admins.
+//
+// Possible response codes:
+// 200 - success, state of the running or the last recalculation in response;
+// 401 - user not authenticated;
+// 403 - user is not admin.
+func GetMetricsStatus(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobRes, jobResErr := json.Marshal(database.MetricsStatus())
+		if jobResErr != nil {
+			panic(jobResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(jobRes)
+	}
+}
+
+// CalculateLayout - start of background recalculation of ForceAtlas2 layout of the full graph
+//
+// Handler POST /api/analytics/layout
+//
+// The handler is available only to admins.
+// ForceAtlas2 takes long on the full graph, so the handler only starts the recalculation,
+// its state is returned by GET /api/analytics/layout.
+// Request format:
+//
+//	{"min_links": 5}
+//
+// Possible response codes:
+// 202 - recalculation is started, its state in response;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is not admin;
+// 409 - a recalculation is already running, its state in response.
+func CalculateLayout(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		var query models.AnalyticsQuery
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if query.MinLinks < 0 {
+			messageResponse(w, "Bad Request. min_links must be 0 (default) or positive", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		job, started := database.StartLayout(query.MinLinks)
+		status := http.StatusAccepted
+		if !started {
+			status = http.StatusConflict
+		}
+
+		jobRes, jobResErr := json.Marshal(job)
+		if jobResErr != nil {
+			panic(jobResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(jobRes)
+	}
+}
+
+// GetLayoutStatus - state of the recalculation of the full graph layout
+//
+// Handler GET /api/analytics/layout
+//
+// The handler is available only to admins.
+//
+// Possible response codes:
+// 200 - success, state of the running or the last recalculation in response;
+// 401 - user not authenticated;
+// 403 - user is not admin.
+func GetLayoutStatus(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobRes, jobResErr := json.Marshal(database.LayoutStatus())
+		if jobResErr != nil {
+			panic(jobResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(jobRes)
+	}
+}
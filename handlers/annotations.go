@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"AlexSarva/media/graphio"
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/storage/storagepg"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// SetGraphAnnotation - setting of note, labels and colour of a node of saved graph
+//
+// Handler PUT /api/graph/annotations
+//
+// The handler is available to the owner and editors of the graph.
+// Annotation of the node is replaced as a whole, color accepts #rrggbb, rgb() and rgba().
+// The node must be a source of the graph or a node of the graph shown with depth, direction,
+// max_nodes and min_links of the request, as in /api/graph/uuid.
+// Annotations are returned with the graph in /api/graph/uuid. Request format:
+//
+//	{"graph_id": "5b3a8c2e-...", "node": 12, "note": "...", "labels": ["state-affiliated"], "color": "#d62728", "depth": 2}
+//
+// Possible response codes:
+// 200 - annotation is saved, annotations of the graph in response;
+// 400 - invalid request format, empty annotation or node not in the graph;
+// 401 - user not authenticated;
+// 403 - user has no edit access to the graph;
+// 404 - graph not found;
+// 500 - an internal server error.
+func SetGraphAnnotation(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var annotation models.GraphAnnotationEdit
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&annotation)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if paramsErr := validateGraphParams(annotation.GraphParams); paramsErr != nil {
+			messageResponse(w, "Bad Request. "+paramsErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		annotation.Note = strings.TrimSpace(annotation.Note)
+		if len([]rune(annotation.Note)) > maxNoteLength {
+			messageResponse(w, fmt.Sprintf("Bad Request. note must be at most %d characters", maxNoteLength), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		labels, labelsErr := cleanTags(annotation.Labels, "label")
+		if labelsErr != nil {
+			messageResponse(w, "Bad Request. "+labelsErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+		annotation.Labels = labels
+
+		annotation.Color = strings.TrimSpace(annotation.Color)
+		if len(annotation.Color) != 0 {
+			if _, ok := graphio.ParseColor(annotation.Color); !ok {
+				messageResponse(w, "Bad Request. color must be #rrggbb, rgb() or rgba()", "application/json", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if len(annotation.Note) == 0 && len(annotation.Labels) == 0 && len(annotation.Color) == 0 {
+			messageResponse(w, "Bad Request. note, labels or color must be set", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		annotations, annotationsErr := database.Repo.SetGraphAnnotation(r.Context(), userID, annotation)
+		if annotationsErr != nil {
+			switch {
+			case errors.Is(annotationsErr, storagepg.ErrForbidden):
+				messageResponse(w, annotationsErr.Error(), "application/json", http.StatusForbidden)
+			case errors.Is(annotationsErr, sql.ErrNoRows):
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+			case errors.Is(annotationsErr, storagepg.ErrUnknownSources):
+				messageResponse(w, "Bad Request. "+annotationsErr.Error(), "application/json", http.StatusBadRequest)
+			default:
+				log.Println(annotationsErr)
+				messageResponse(w, "Internal Server Error: "+annotationsErr.Error(), "application/json", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		annotationsRes, annotationsResErr := json.Marshal(annotations)
+		if annotationsResErr != nil {
+			panic(annotationsResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(annotationsRes)
+	}
+}
+
+// DeleteGraphAnnotation - removal of annotation of a node of saved graph
+//
+// Handler DELETE /api/graph/annotations
+//
+// The handler is available to the owner and editors of the graph.
+// Request format:
+//
+//	{"graph_id": "5b3a8c2e-...", "node": 12}
+//
+// Possible response codes:
+// 200 - annotation is removed, annotations of the graph in response;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user has no edit access to the graph;
+// 404 - graph or annotation not found;
+// 500 - an internal server error.
+func DeleteGraphAnnotation(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var ref models.GraphNodeRef
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&ref)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		annotations, annotationsErr := database.Repo.DeleteGraphAnnotation(r.Context(), userID, ref)
+		if annotationsErr != nil {
+			switch {
+			case errors.Is(annotationsErr, storagepg.ErrForbidden):
+				messageResponse(w, annotationsErr.Error(), "application/json", http.StatusForbidden)
+			case errors.Is(annotationsErr, sql.ErrNoRows):
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+			case errors.Is(annotationsErr, storagepg.ErrNoData):
+				messageResponse(w, "annotation not found", "application/json", http.StatusNotFound)
+			default:
+				log.Println(annotationsErr)
+				messageResponse(w, "Internal Server Error: "+annotationsErr.Error(), "application/json", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		annotationsRes, annotationsResErr := json.Marshal(annotations)
+		if annotationsResErr != nil {
+			panic(annotationsResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(annotationsRes)
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/storage/storagepg"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// annotationRepo repository of annotation tests, records the last edit and fails with err
+type annotationRepo struct {
+	stubRepo
+	edit models.GraphAnnotationEdit
+	ref  models.GraphNodeRef
+	err  error
+}
+
+func (a *annotationRepo) SetGraphAnnotation(ctx context.Context, userID uuid.UUID, annotation models.GraphAnnotationEdit) ([]models.GraphAnnotation, error) {
+	a.edit = annotation
+	if a.err != nil {
+		return nil, a.err
+	}
+	return []models.GraphAnnotation{{Node: annotation.Node, Note: annotation.Note, Labels: annotation.Labels, Color: annotation.Color}}, nil
+}
+
+func (a *annotationRepo) DeleteGraphAnnotation(ctx context.Context, userID uuid.UUID, ref models.GraphNodeRef) ([]models.GraphAnnotation, error) {
+	a.ref = ref
+	return []models.GraphAnnotation{}, a.err
+}
+
+func TestSetGraphAnnotation(t *testing.T) {
+	graphID := `"graph_id": "5b3a8c2e-0000-4000-8000-000000000001"`
+	tests := []struct {
+		name    string
+		body    string
+		err     error
+		want    int
+		message string
+	}{
+		{name: "note", body: `{` + graphID + `, "node": 12, "note": "  state media  "}`, want: http.StatusOK, message: `"note":"state media"`},
+		{name: "labels and color", body: `{` + graphID + `, "node": 12, "labels": ["State", "state"], "color": "#d62728"}`, want: http.StatusOK, message: `"color":"#d62728"`},
+		{name: "empty", body: `{` + graphID + `, "node": 12, "note": "  "}`, want: http.StatusBadRequest, message: "note, labels or color must be set"},
+		{name: "long note", body: `{` + graphID + `, "node": 12, "note": "` + strings.Repeat("a", maxNoteLength+1) + `"}`, want: http.StatusBadRequest, message: "note must be at most"},
+		{name: "bad color", body: `{` + graphID + `, "node": 12, "color": "red"}`, want: http.StatusBadRequest, message: "color must be"},
+		{name: "bad depth", body: `{` + graphID + `, "node": 12, "note": "a", "depth": 9}`, want: http.StatusBadRequest, message: "depth must be"},
+		{name: "unknown field", body: `{` + graphID + `, "node": 12, "note": "a", "author": "x"}`, want: http.StatusBadRequest, message: "unknown field"},
+		{name: "node not in graph", body: `{` + graphID + `, "node": 12, "note": "a"}`, err: fmt.Errorf("%w: 12", storagepg.ErrUnknownSources), want: http.StatusBadRequest, message: "sources not found"},
+		{name: "viewer", body: `{` + graphID + `, "node": 12, "note": "a"}`, err: storagepg.ErrForbidden, want: http.StatusForbidden},
+		{name: "no graph", body: `{` + graphID + `, "node": 12, "note": "a"}`, err: sql.ErrNoRows, want: http.StatusNotFound, message: "graph not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &annotationRepo{err: tt.err}
+			request := httptest.NewRequest(http.MethodPut, "/api/graph/annotations", strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+
+			SetGraphAnnotation(&app.Database{Repo: repo}).ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.want, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), tt.message)
+		})
+	}
+}
+
+func TestSetGraphAnnotationParams(t *testing.T) {
+	repo := &annotationRepo{}
+	body := `{"graph_id": "5b3a8c2e-0000-4000-8000-000000000001", "node": 12, "note": "a", "depth": 2, "direction": "both", "min_links": 3}`
+	request := httptest.NewRequest(http.MethodPut, "/api/graph/annotations", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+
+	SetGraphAnnotation(&app.Database{Repo: repo}).ServeHTTP(httptest.NewRecorder(), request)
+
+	assert.Equal(t, int64(12), repo.edit.Node)
+	assert.Equal(t, models.GraphParams{Depth: 2, Direction: models.DirectionBoth, MinLinks: 3}, repo.edit.GraphParams)
+}
+
+func TestDeleteGraphAnnotation(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "deleted", want: http.StatusOK},
+		{name: "no annotation", err: storagepg.ErrNoData, want: http.StatusNotFound},
+		{name: "no graph", err: sql.ErrNoRows, want: http.StatusNotFound},
+		{name: "viewer", err: storagepg.ErrForbidden, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &annotationRepo{err: tt.err}
+			body := `{"graph_id": "5b3a8c2e-0000-4000-8000-000000000001", "node": 12}`
+			request := httptest.NewRequest(http.MethodDelete, "/api/graph/annotations", strings.NewReader(body))
+			request.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+
+			DeleteGraphAnnotation(&app.Database{Repo: repo}).ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.want, recorder.Code)
+			assert.Equal(t, int64(12), repo.ref.Node)
+		})
+	}
+}
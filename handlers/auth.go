@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"AlexSarva/media/admin"
+	"AlexSarva/media/crypto"
 	"AlexSarva/media/models"
 	"database/sql"
 	"encoding/json"
@@ -24,17 +25,20 @@ const timeLayout = "2006-01-02 15:04:05"
 // Registration is performed by a pair of login/password.
 // Each login must be set.
 // After successful registration, automatic user authentication is required.
+// New users get viewer role, admins grant them analyst or admin role.
 // post message should contain such body:
 //
 //	"login": "<login>",
 //	"password": "<password>"
 //
+// Response contains short-lived access token and refresh token to get the new pair at /api/user/refresh.
+//
 // Possible response codes:
 // 200 - user successfully registered and authenticated;
 // 400 - invalid request format;
 // 409 - login is already taken;
 // 500 - an internal server error.
-func UserRegistration(database *admin.PostgresDB) http.HandlerFunc {
+func UserRegistration(database *admin.PostgresDB, keys *crypto.Keyring, accessTTL time.Duration, refreshTTL time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		headerContentType := r.Header.Get("Content-Type")
 		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
@@ -65,13 +69,12 @@ func UserRegistration(database *admin.PostgresDB) http.HandlerFunc {
 		}
 
 		userID := uuid.New()
-		userToken, userTokenExp := GenerateToken(userID)
 		hashedPassword, bcrypteErr := bcrypt.GenerateFromPassword([]byte(user.Password), 4)
 		if bcrypteErr != nil {
 			log.Println(bcrypteErr)
 		}
 
-		user.ID, user.Password, user.Token, user.TokenExp = userID, string(hashedPassword), userToken, userTokenExp
+		user.ID, user.Password = userID, string(hashedPassword)
 
 		newUserErr := database.RegisterUser(&user)
 		if newUserErr != nil {
@@ -83,16 +86,16 @@ func UserRegistration(database *admin.PostgresDB) http.HandlerFunc {
 			return
 		}
 
-		tokenDetails := models.Token{
-			Username: user.Username,
-			Email:    user.Email,
-			Type:     "Bearer",
-			Token:    userToken,
-			TokenExp: userTokenExp,
+		tokenDetails, tokenErr := generateTokens(database, keys, userID, accessTTL, refreshTTL)
+		if tokenErr != nil {
+			messageResponse(w, "Internal Server Error "+tokenErr.Error(), "application/json", http.StatusInternalServerError)
+			return
 		}
+		tokenDetails.Username, tokenDetails.Email = user.Username, user.Email
+
 		jsonResp, _ := json.Marshal(tokenDetails)
 		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Authorization", tokenDetails.Type+" "+userToken)
+		w.Header().Set("Authorization", tokenDetails.Type+" "+tokenDetails.Token)
 		w.WriteHeader(http.StatusOK)
 		w.Write(jsonResp)
 	}
@@ -108,12 +111,15 @@ func UserRegistration(database *admin.PostgresDB) http.HandlerFunc {
 //	{"login": "<login>",
 //	"password": "<password>"}
 //
+// Response contains short-lived access token and refresh token to get the new pair at /api/user/refresh.
+//
 // Possible response codes:
 // 200 - user successfully authenticated;
 // 400 - invalid request format;
 // 401 - invalid login/password pair;
+// 403 - account is disabled by admin;
 // 500 - an internal server error.
-func UserAuthentication(database *admin.PostgresDB) http.HandlerFunc {
+func UserAuthentication(database *admin.PostgresDB, keys *crypto.Keyring, accessTTL time.Duration, refreshTTL time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		headerContentType := r.Header.Get("Content-Type")
 		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
@@ -159,20 +165,117 @@ func UserAuthentication(database *admin.PostgresDB) http.HandlerFunc {
 			messageResponse(w, "password doesnt match", "application/json", http.StatusUnauthorized)
 			return
 		}
-		// TODO Предусмотреть обновление куки
-		if userDB.TokenExp.Before(time.Now()) {
-			log.Println("cookie expired")
+
+		if userDB.Disabled != nil {
+			messageResponse(w, "account is disabled", "application/json", http.StatusForbidden)
+			return
+		}
+		tokenDetails, tokenErr := generateTokens(database, keys, userDB.ID, accessTTL, refreshTTL)
+		if tokenErr != nil {
+			messageResponse(w, "Internal Server Error: "+tokenErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+		tokenDetails.Username, tokenDetails.Email = userDB.Username, userDB.Email
+
+		jsonResp, _ := json.Marshal(tokenDetails)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Authorization", tokenDetails.Type+" "+tokenDetails.Token)
+		w.WriteHeader(http.StatusOK)
+		w.Write(jsonResp)
+	}
+}
+
+// RefreshToken - issue of the new token pair by refresh token
+//
+// Handler POST /api/user/refresh
+//
+// Each refresh token can be used once, it is replaced by the refresh token of the response
+// and the session of the token is prolonged.
+// Presenting already used refresh token logs out its session.
+// Request format:
+//
+//	{"refresh_token": "<refresh token>"}
+//
+// Possible response codes:
+// 200 - new access and refresh tokens;
+// 400 - invalid request format;
+// 401 - refresh token is unknown, expired or already used;
+// 500 - an internal server error.
+func RefreshToken(database *admin.PostgresDB, keys *crypto.Keyring, accessTTL time.Duration, refreshTTL time.Duration, cache *SessionCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		var query models.RefreshQuery
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
 		}
 
-		// Авторизация по токену
-		token, tokenExp := userDB.Token, userDB.TokenExp
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
 
+		if len(query.Refresh) == 0 {
+			messageResponse(w, "Bad Request. refresh_token must be set", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		refresh, refreshHash, refreshErr := crypto.NewRefreshToken()
+		if refreshErr != nil {
+			messageResponse(w, "Internal Server Error: "+refreshErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+		refreshExp := time.Now().Add(refreshTTL)
+
+		session, rotateErr := database.RotateRefreshToken(crypto.HashToken(query.Refresh), refreshHash, refreshExp)
+		if rotateErr != nil {
+			if errors.Is(rotateErr, admin.ErrRefreshReused) {
+				// access tokens of the revoked session must stop working at once, as after logout
+				cache.Forget(session.ID)
+			}
+			if errors.Is(rotateErr, admin.ErrNotValidRefresh) || errors.Is(rotateErr, admin.ErrRefreshReused) {
+				messageResponse(w, "User unauthorized: "+rotateErr.Error(), "application/json", http.StatusUnauthorized)
+				return
+			}
+			messageResponse(w, "Internal Server Error: "+rotateErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		userInfo, userInfoErr := database.GetUserInfo(session.UserID)
+		if userInfoErr != nil {
+			if errors.Is(userInfoErr, sql.ErrNoRows) {
+				messageResponse(w, "user doesnt exist", "application/json", http.StatusUnauthorized)
+				return
+			}
+			messageResponse(w, "Internal Server Error: "+userInfoErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		token, tokenExp := GenerateToken(keys, session.UserID, session.ID, accessTTL)
 		tokenDetails := models.Token{
-			Username: userDB.Username,
-			Email:    userDB.Email,
-			Type:     "Bearer",
-			Token:    token,
-			TokenExp: tokenExp,
+			Username:   userInfo.Username,
+			Email:      userInfo.Email,
+			Type:       "Bearer",
+			Token:      token,
+			TokenExp:   tokenExp,
+			Refresh:    refresh,
+			RefreshExp: refreshExp,
 		}
 		jsonResp, _ := json.Marshal(tokenDetails)
 		w.Header().Set("Content-Type", "application/json")
@@ -182,6 +285,52 @@ func UserAuthentication(database *admin.PostgresDB) http.HandlerFunc {
 	}
 }
 
+// UserLogout - logout of the session of the token
+//
+// Handler POST /api/user/logout
+//
+// Access and refresh tokens of the session stop working at once.
+//
+// Possible response codes:
+// 204 - session is logged out;
+// 401 - user not authenticated;
+// 500 - an internal server error.
+func UserLogout(database *admin.PostgresDB, cache *SessionCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, sessionID := requestUser(r).ID, requestSession(r)
+
+		if revokeErr := database.RevokeSession(userID, sessionID); revokeErr != nil {
+			messageResponse(w, "Internal Server Error: "+revokeErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+		cache.Forget(sessionID)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// UserLogoutAll - logout of all sessions of the user on every device
+//
+// Handler POST /api/user/logout/all
+//
+// Possible response codes:
+// 204 - all sessions are logged out;
+// 401 - user not authenticated;
+// 500 - an internal server error.
+func UserLogoutAll(database *admin.PostgresDB, cache *SessionCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := requestUser(r).ID
+
+		if revokeErr := database.RevokeSessions(userID); revokeErr != nil {
+			messageResponse(w, "Internal Server Error: "+revokeErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+		cache.ForgetUser(userID)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
 func GetUserInfo(database *admin.PostgresDB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		headerContentType := r.Header.Get("Content-Length")
@@ -190,14 +339,7 @@ func GetUserInfo(database *admin.PostgresDB) http.HandlerFunc {
 			return
 		}
 
-		// Проверка авторизации по токену
-		userID, tokenErr := GetToken(r)
-		if tokenErr != nil {
-			messageResponse(w, "User unauthorized: "+tokenErr.Error(), "application/json", http.StatusUnauthorized)
-			return
-		}
-
-		userInfo, userInfoErr := database.GetUserInfo(userID)
+		userInfo, userInfoErr := database.GetUserInfo(requestUser(r).ID)
 		if userInfoErr != nil {
 			if errors.Is(userInfoErr, sql.ErrNoRows) {
 				messageResponse(w, "user doesnt exist", "application/json", http.StatusUnauthorized)
@@ -207,8 +349,6 @@ func GetUserInfo(database *admin.PostgresDB) http.HandlerFunc {
 			return
 		}
 
-		userInfo.Type = "Bearer"
-
 		jsonResp, _ := json.Marshal(userInfo)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/storage/storagepg"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// CombineGraphs - new saved graph from sources of two saved graphs
+//
+// Handler POST /api/graph/combine
+//
+// The handler is available to analysts and admins.
+// Both graphs must be available to the user, the new graph belongs to the user.
+// Operation is union, intersect or subtract (sources of left missing in right),
+// sources of left keep their order and come first. Request format:
+//
+//	{"left": "5b3a8c2e-...", "right": "0f4c61d2-...", "operation": "union", "name": "Both portfolios", "description": "..."}
+//
+// Possible response codes:
+// 201 - graph is created;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is a viewer or one of the graphs is not shared with the user;
+// 404 - one of the graphs not found;
+// 409 - the operation leaves no sources;
+// 500 - an internal server error.
+func CombineGraphs(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var query models.GraphCombineQuery
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		switch query.Operation {
+		case models.CombineUnion, models.CombineIntersect, models.CombineSubtract:
+		default:
+			messageResponse(w, "Bad Request. operation must be union, intersect or subtract", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		if query.MinLinks < 0 {
+			messageResponse(w, "Bad Request. min_links must not be negative", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		newGraph := models.NewGraph{
+			Name:        query.Name,
+			Description: query.Description,
+			MinLinks:    query.MinLinks,
+			GraphID:     uuid.New(),
+			UserID:      userID,
+		}
+
+		resp, respErr := database.Repo.CombineGraphs(r.Context(), newGraph, query)
+		if respErr != nil {
+			switch {
+			case errors.Is(respErr, storagepg.ErrForbidden):
+				messageResponse(w, respErr.Error(), "application/json", http.StatusForbidden)
+			case errors.Is(respErr, sql.ErrNoRows):
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+			case errors.Is(respErr, storagepg.ErrEmptyGraph):
+				messageResponse(w, respErr.Error(), "application/json", http.StatusConflict)
+			default:
+				log.Println(respErr)
+				messageResponse(w, "Internal Server Error: "+respErr.Error(), "application/json", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		graphRes, graphResErr := json.Marshal(resp)
+		if graphResErr != nil {
+			panic(graphResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(graphRes)
+	}
+}
+
+// CompareGraphs - comparison of sources of two saved graphs
+//
+// Handler POST /api/graph/compare
+//
+// Both graphs must be available to the user. Sources are split into shared ones and ones
+// of each graph only, shared neighbours are nodes linked to sources of both graphs
+// with at least min_links links, the strongest first. Request format:
+//
+//	{"left": "5b3a8c2e-...", "right": "0f4c61d2-...", "min_links": 5}
+//
+// Possible response codes:
+// 200 - comparison of the graphs;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - one of the graphs is not shared with the user;
+// 404 - one of the graphs not found;
+// 500 - an internal server error.
+func CompareGraphs(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var query models.GraphCompareQuery
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if query.MinLinks < 0 {
+			messageResponse(w, "Bad Request. min_links must not be negative", "application/json", http.StatusBadRequest)
+			return
+		}
+		if query.MinLinks == 0 {
+			query.MinLinks = models.DefaultMinLinks
+		}
+
+		comparison, comparisonErr := database.Repo.CompareGraphs(r.Context(), userID, query)
+		if comparisonErr != nil {
+			if errors.Is(comparisonErr, storagepg.ErrForbidden) {
+				messageResponse(w, comparisonErr.Error(), "application/json", http.StatusForbidden)
+				return
+			}
+			if errors.Is(comparisonErr, sql.ErrNoRows) {
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(comparisonErr)
+			messageResponse(w, "Internal Server Error: "+comparisonErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		comparisonRes, comparisonResErr := json.Marshal(comparison)
+		if comparisonResErr != nil {
+			panic(comparisonResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(comparisonRes)
+	}
+}
+
+// CloneGraph - copy of saved graph with its sources in their order
+//
+// Handler POST /api/graph/clone
+//
+// The handler is available to analysts and admins.
+// The copy belongs to the caller when email is not set, any analyst the graph is shared with
+// can copy it. The owner can copy the graph into the workspace of a user the graph is shared with
+// by the email of that user. Request format:
+//
+//	{"graph_id": "5b3a8c2e-...", "name": "Template", "email": "colleague@example.com"}
+//
+// Possible response codes:
+// 201 - copy is created;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is a viewer, graph is not shared with the user or copy to another user by not the owner;
+// 404 - graph or user with the email not found;
+// 409 - graph is not shared with the user with the email;
+// 500 - an internal server error.
+func CloneGraph(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var query models.GraphCloneQuery
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+		query.Email = strings.TrimSpace(query.Email)
+
+		resp, respErr := database.Repo.CloneGraph(r.Context(), userID, query, uuid.New())
+		if respErr != nil {
+			switch {
+			case errors.Is(respErr, storagepg.ErrForbidden):
+				messageResponse(w, respErr.Error(), "application/json", http.StatusForbidden)
+			case errors.Is(respErr, sql.ErrNoRows):
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+			case errors.Is(respErr, storagepg.ErrUnknownUser):
+				messageResponse(w, respErr.Error(), "application/json", http.StatusNotFound)
+			case errors.Is(respErr, storagepg.ErrNotShared):
+				messageResponse(w, respErr.Error(), "application/json", http.StatusConflict)
+			default:
+				log.Println(respErr)
+				messageResponse(w, "Internal Server Error: "+respErr.Error(), "application/json", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		graphRes, graphResErr := json.Marshal(resp)
+		if graphResErr != nil {
+			panic(graphResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(graphRes)
+	}
+}
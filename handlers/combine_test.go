@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/storage/storagepg"
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// cloneRepo repository of clone tests, records the last query and fails with err
+type cloneRepo struct {
+	stubRepo
+	query models.GraphCloneQuery
+	err   error
+}
+
+func (c *cloneRepo) CloneGraph(ctx context.Context, userID uuid.UUID, query models.GraphCloneQuery, graphID uuid.UUID) (models.NewGraphResp, error) {
+	c.query = query
+	if c.err != nil {
+		return models.NewGraphResp{}, c.err
+	}
+	return models.NewGraphResp{UUID: graphID, Name: query.Name}, nil
+}
+
+func TestCloneGraph(t *testing.T) {
+	graphID := `"graph_id": "5b3a8c2e-0000-4000-8000-000000000001"`
+	tests := []struct {
+		name    string
+		body    string
+		err     error
+		want    int
+		message string
+	}{
+		{name: "copy", body: `{` + graphID + `, "name": "Template"}`, want: http.StatusCreated, message: `"Template"`},
+		{name: "copy to colleague", body: `{` + graphID + `, "email": "colleague@example.com"}`, want: http.StatusCreated},
+		{name: "unknown field", body: `{` + graphID + `, "owner": "x"}`, want: http.StatusBadRequest, message: "unknown field"},
+		{name: "not shared with caller", body: `{` + graphID + `}`, err: storagepg.ErrForbidden, want: http.StatusForbidden},
+		{name: "no graph", body: `{` + graphID + `}`, err: sql.ErrNoRows, want: http.StatusNotFound, message: "graph not found"},
+		{name: "no user with email", body: `{` + graphID + `, "email": "nobody@example.com"}`, err: storagepg.ErrUnknownUser, want: http.StatusNotFound},
+		{name: "not shared with colleague", body: `{` + graphID + `, "email": "colleague@example.com"}`, err: storagepg.ErrNotShared, want: http.StatusConflict, message: storagepg.ErrNotShared.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &cloneRepo{err: tt.err}
+			request := httptest.NewRequest(http.MethodPost, "/api/graph/clone", strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+
+			CloneGraph(&app.Database{Repo: repo}).ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.want, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), tt.message)
+		})
+	}
+}
+
+func TestCloneGraphTrimsEmail(t *testing.T) {
+	repo := &cloneRepo{}
+	body := `{"graph_id": "5b3a8c2e-0000-4000-8000-000000000001", "email": "  colleague@example.com "}`
+	request := httptest.NewRequest(http.MethodPost, "/api/graph/clone", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+
+	CloneGraph(&app.Database{Repo: repo}).ServeHTTP(httptest.NewRecorder(), request)
+
+	assert.Equal(t, "colleague@example.com", repo.query.Email)
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"AlexSarva/media/admin"
 	"AlexSarva/media/crypto"
+	"AlexSarva/media/models"
+	"context"
 	"errors"
 	"net/http"
 	"strings"
@@ -19,47 +22,100 @@ var ErrNoAuth = errors.New("no Bearer token")
 // ErrNoCookie error that occurs when no cookie presents in Header
 var ErrNoCookie = errors.New("no cookie")
 
-// GenerateToken function of generating token for user when he successfully registered and authenticated
-// based at UserID (uuid format)
+// claimsKey key of request context holding the verified Bearer token
+type claimsKey struct{}
+
+// tokenClaims user and session of the verified Bearer token
+type tokenClaims struct {
+	userID    uuid.UUID
+	sessionID uuid.UUID
+}
+
+// GenerateToken function of generating access token for user session
+// based at UserID and SessionID (uuid format)
 // returns Token format for respond and time of expiration
-func GenerateToken(userID uuid.UUID) (string, time.Time) {
-	session := crypto.Encrypt(userID, crypto.SecretKey)
-	expiration := time.Now().Add(365 * 24 * time.Hour)
-	return session, expiration
+func GenerateToken(keys *crypto.Keyring, userID uuid.UUID, sessionID uuid.UUID, ttl time.Duration) (string, time.Time) {
+	expiration := time.Now().Add(ttl)
+	return keys.SignToken(userID, sessionID, expiration), expiration
+}
+
+// generateTokens starts new session of the user and issues its access and refresh tokens
+func generateTokens(database *admin.PostgresDB, keys *crypto.Keyring, userID uuid.UUID, accessTTL time.Duration, refreshTTL time.Duration) (models.Token, error) {
+	refresh, refreshHash, refreshErr := crypto.NewRefreshToken()
+	if refreshErr != nil {
+		return models.Token{}, refreshErr
+	}
+	refreshExp := time.Now().Add(refreshTTL)
+	sessionID, sessionErr := database.CreateSession(userID, refreshHash, refreshExp)
+	if sessionErr != nil {
+		return models.Token{}, sessionErr
+	}
+	token, tokenExp := GenerateToken(keys, userID, sessionID, accessTTL)
+	return models.Token{
+		Type:       "Bearer",
+		Token:      token,
+		TokenExp:   tokenExp,
+		Refresh:    refresh,
+		RefreshExp: refreshExp,
+	}, nil
 }
 
 // GetCookie cookie selection function from Header
-// returns UserID in uuid format
-func GetCookie(r *http.Request) (uuid.UUID, error) {
+// returns UserID in uuid format, tokens of logged out sessions are refused
+func GetCookie(r *http.Request, keys *crypto.Keyring, database *admin.PostgresDB) (uuid.UUID, error) {
 	cookie, cookieErr := r.Cookie("session")
 	if cookieErr != nil {
 		return uuid.UUID{}, ErrNotValidCookie
 	}
-	userID, cookieDecryptErr := crypto.Decrypt(cookie.Value, crypto.SecretKey)
+	userID, sessionID, cookieDecryptErr := keys.ParseToken(cookie.Value)
 	if cookieDecryptErr != nil {
 		return uuid.UUID{}, cookieDecryptErr
 	}
+	if sessionErr := database.CheckSession(userID, sessionID); sessionErr != nil {
+		return uuid.UUID{}, sessionErr
+	}
 	return userID, nil
 
 }
 
-// GetToken cookie selection function from Header
-// returns UserID in uuid format
-func GetToken(r *http.Request) (uuid.UUID, error) {
+// bearerToken Bearer token from Header
+func bearerToken(r *http.Request) (string, error) {
 	auth := r.Header.Get("Authorization")
 	if len(auth) == 0 {
-		return uuid.UUID{}, ErrNoAuth
+		return "", ErrNoAuth
 	}
 	tokenValue := strings.Split(auth, "Bearer ")
 	if len(tokenValue) < 2 {
-		return uuid.UUID{}, ErrNoAuth
+		return "", ErrNoAuth
 	}
-	authToken := tokenValue[1]
-	userID, tokenDecryptErr := crypto.Decrypt(authToken, crypto.SecretKey)
-	if tokenDecryptErr != nil {
-		return uuid.UUID{}, tokenDecryptErr
+	return tokenValue[1], nil
+}
+
+// TokenAuth middleware that verifies signature and expiration of Bearer token with the keys
+// and passes its user and session to handlers in request context.
+// Requests with invalid or expired token are refused, requests without token are passed
+// to the routes which decide whether it is required, UserAuth checks that the session is not logged out
+func TokenAuth(keys *crypto.Keyring) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(r.Header.Get("Authorization")) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			token, tokenErr := bearerToken(r)
+			if tokenErr != nil {
+				messageResponse(w, "User unauthorized: "+tokenErr.Error(), "application/json", http.StatusUnauthorized)
+				return
+			}
+			userID, sessionID, parseErr := keys.ParseToken(token)
+			if parseErr != nil {
+				messageResponse(w, "User unauthorized: "+parseErr.Error(), "application/json", http.StatusUnauthorized)
+				return
+			}
+			ctx := context.WithValue(r.Context(), claimsKey{}, tokenClaims{userID: userID, sessionID: sessionID})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
 	}
-	return userID, nil
 }
 
 // ParseCookie util that parse cookie string format into session id
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/storage/storagepg"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// UpdateGraphCard - change of name, description, folder and tags of saved graph
+//
+// Handler PATCH /api/graph
+//
+// The handler is available to the owner and editors of the graph.
+// Request format, omitted fields are kept, empty folder and tags are cleared:
+//
+//	{"graph_id": "5b3a8c2e-...", "name": "Regional media", "description": "...", "folder": "Regions", "tags": ["south"]}
+//
+// Possible response codes:
+// 200 - graph card is updated, card in response;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user has no edit access to the graph;
+// 404 - graph not found;
+// 500 - an internal server error.
+func UpdateGraphCard(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var update models.GraphUpdate
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&update)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if update.Name == nil && update.Description == nil && update.Folder == nil && update.Tags == nil {
+			messageResponse(w, "Bad Request. name, description, folder or tags must be set", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		if update.Folder != nil {
+			folder := strings.TrimSpace(*update.Folder)
+			if len([]rune(folder)) > maxFolderLength {
+				messageResponse(w, fmt.Sprintf("Bad Request. folder must be at most %d characters", maxFolderLength), "application/json", http.StatusBadRequest)
+				return
+			}
+			update.Folder = &folder
+		}
+
+		if update.Tags != nil {
+			tags, tagsErr := cleanTags(update.Tags, "tag")
+			if tagsErr != nil {
+				messageResponse(w, "Bad Request. "+tagsErr.Error(), "application/json", http.StatusBadRequest)
+				return
+			}
+			update.Tags = tags
+		}
+
+		card, cardErr := database.Repo.UpdateGraphCard(r.Context(), userID, update)
+		if cardErr != nil {
+			if errors.Is(cardErr, storagepg.ErrForbidden) {
+				messageResponse(w, cardErr.Error(), "application/json", http.StatusForbidden)
+				return
+			}
+			if errors.Is(cardErr, storagepg.ErrNoData) {
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(cardErr)
+			messageResponse(w, "Internal Server Error: "+cardErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		cardRes, cardResErr := json.Marshal(card)
+		if cardResErr != nil {
+			panic(cardResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(cardRes)
+	}
+}
+
+// AddGraphSources - adding of sources to the end of saved graph
+//
+// Handler PATCH /api/graph/sources/add
+//
+// The handler is available to analysts and admins who own or edit the graph.
+// Request format:
+//
+//	{"graph_id": "5b3a8c2e-...", "sources": [12, 45]}
+//
+// Possible response codes:
+// 200 - sources are added, graph card in response;
+// 400 - invalid request format or unknown sources;
+// 401 - user not authenticated;
+// 403 - user is a viewer or has no edit access to the graph;
+// 404 - graph not found;
+// 500 - an internal server error.
+func AddGraphSources(database *app.Database) http.HandlerFunc {
+	return editGraphSources(database.Repo.AddGraphSources)
+}
+
+// RemoveGraphSources - removal of sources from saved graph
+//
+// Handler PATCH /api/graph/sources/remove
+//
+// Request format:
+//
+//	{"graph_id": "5b3a8c2e-...", "sources": [12, 45]}
+//
+// Possible response codes:
+// 200 - sources are removed, graph card in response;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user has no edit access to the graph;
+// 404 - graph not found;
+// 409 - all sources of the graph would be removed;
+// 500 - an internal server error.
+func RemoveGraphSources(database *app.Database) http.HandlerFunc {
+	return editGraphSources(database.Repo.RemoveGraphSources)
+}
+
+// ReorderGraphSources - change of the order of saved graph sources
+//
+// Handler PATCH /api/graph/sources/order
+//
+// Request format, sources lists every source of the graph in the new order:
+//
+//	{"graph_id": "5b3a8c2e-...", "sources": [45, 12, 7]}
+//
+// Possible response codes:
+// 200 - order is saved, graph card in response;
+// 400 - invalid request format or sources do not match the graph;
+// 401 - user not authenticated;
+// 403 - user has no edit access to the graph;
+// 404 - graph not found;
+// 500 - an internal server error.
+func ReorderGraphSources(database *app.Database) http.HandlerFunc {
+	return editGraphSources(database.Repo.ReorderGraphSources)
+}
+
+// editGraphSources handler of requests that change sources of saved graph with edit
+func editGraphSources(edit func(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, sources []int64) (models.GraphCard, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var query models.GraphSources
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if len(query.Sources) == 0 {
+			messageResponse(w, "Bad Request. sources must not be empty", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		card, cardErr := edit(r.Context(), userID, query.GraphID, query.Sources)
+		if cardErr != nil {
+			switch {
+			case errors.Is(cardErr, storagepg.ErrNoData):
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+			case errors.Is(cardErr, storagepg.ErrForbidden):
+				messageResponse(w, cardErr.Error(), "application/json", http.StatusForbidden)
+			case errors.Is(cardErr, storagepg.ErrUnknownSources), errors.Is(cardErr, storagepg.ErrNotValidOrder):
+				messageResponse(w, "Bad Request. "+cardErr.Error(), "application/json", http.StatusBadRequest)
+			case errors.Is(cardErr, storagepg.ErrEmptyGraph):
+				messageResponse(w, cardErr.Error(), "application/json", http.StatusConflict)
+			default:
+				log.Println(cardErr)
+				messageResponse(w, "Internal Server Error: "+cardErr.Error(), "application/json", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		cardRes, cardResErr := json.Marshal(card)
+		if cardResErr != nil {
+			panic(cardResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(cardRes)
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"AlexSarva/media/graphio"
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/storage/storagepg"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ExportGraph - export of saved graph as node and edge tables
+//
+// Handler POST /api/graph/export
+//
+// The handler is available to every user the graph is shared with.
+// Request format:
+//
+//	{"graph_id": "5b3a8c2e-...", "format": "xlsx"}
+//
+// Format csv returns zip archive with nodes.csv and edges.csv,
+// format xlsx returns workbook with sheets nodes and edges.
+//
+// Possible response codes:
+// 200 - file with the graph tables;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - graph is not shared with the user;
+// 404 - graph not found;
+// 500 - an internal server error.
+func ExportGraph(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var query models.GraphExportQuery
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if query.Format != models.ExportCSV && query.Format != models.ExportXLSX {
+			messageResponse(w, "Bad Request. format must be csv or xlsx", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		graphTables, graphTablesErr := database.Repo.GetGraphTables(r.Context(), userID, query.GraphID)
+		if graphTablesErr != nil {
+			if errors.Is(graphTablesErr, storagepg.ErrForbidden) {
+				messageResponse(w, graphTablesErr.Error(), "application/json", http.StatusForbidden)
+				return
+			}
+			if errors.Is(graphTablesErr, sql.ErrNoRows) {
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(graphTablesErr)
+			messageResponse(w, "Internal Server Error: "+graphTablesErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		var buf bytes.Buffer
+		var writeErr error
+		var contentType, extension string
+		tables := graphio.GraphTables(graphTables)
+		switch query.Format {
+		case models.ExportCSV:
+			contentType, extension = graphio.ContentTypeZIP, "zip"
+			writeErr = graphio.WriteCSV(&buf, tables)
+		case models.ExportXLSX:
+			contentType, extension = graphio.ContentTypeXLSX, "xlsx"
+			writeErr = graphio.WriteXLSX(&buf, tables)
+		}
+		if writeErr != nil {
+			messageResponse(w, "Internal Server Error: "+writeErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="graph-`+query.GraphID.String()+`.`+extension+`"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	}
+}
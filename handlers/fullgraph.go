@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/styling"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotValidCursor error that occurs when the page cursor of the full graph can not be decoded
+var ErrNotValidCursor = errors.New("cursor is not valid")
+
+// streamFlushRows number of NDJSON rows written between flushes of the response
+const streamFlushRows = 500
+
+// streamRow line of NDJSON stream of the full graph
+type streamRow struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// fullGraphQuery reads threshold and node size options of the full graph from the URL query
+func fullGraphQuery(r *http.Request) (int, string, error) {
+	minLinks, minLinksErr := queryInt(r, "min_links")
+	if minLinksErr != nil {
+		return 0, "", minLinksErr
+	}
+	if minLinks < 0 {
+		return 0, "", errors.New("min_links must be 0 (default) or positive")
+	}
+	sizeBy := r.URL.Query().Get("size_by")
+	if sizeByErr := validateSizeBy(sizeBy); sizeByErr != nil {
+		return 0, "", sizeByErr
+	}
+	return minLinks, sizeBy, nil
+}
+
+// fullGraphStyle style of pages and stream of the full graph coloured by communities by default,
+// metric rules need all nodes at once and are available in /api/graph only
+func fullGraphStyle(r *http.Request) (styling.Style, error) {
+	style, styleErr := graphStyle(r, styling.RuleCommunity)
+	if styleErr != nil {
+		return styling.Style{}, styleErr
+	}
+	if styling.IsMetric(style.Rule) {
+		return styling.Style{}, fmt.Errorf("color_by %s needs the whole graph, use /api/graph", style.Rule)
+	}
+	return style, nil
+}
+
+// encodeCursor converts position in the full graph into an opaque string
+func encodeCursor(cursor models.GraphCursor) string {
+	raw := fmt.Sprintf("%s:%d", cursor.Phase, cursor.From)
+	if cursor.Phase == models.CursorEdges {
+		raw = fmt.Sprintf("%s:%d:%d", cursor.Phase, cursor.From, cursor.To)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor restores position in the full graph, empty string is the start of the graph
+func decodeCursor(value string) (models.GraphCursor, error) {
+	if len(value) == 0 {
+		return models.GraphCursor{Phase: models.CursorNodes}, nil
+	}
+	raw, decodeErr := base64.RawURLEncoding.DecodeString(value)
+	if decodeErr != nil {
+		return models.GraphCursor{}, ErrNotValidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	var cursor models.GraphCursor
+	var convErr error
+	switch {
+	case len(parts) == 2 && parts[0] == models.CursorNodes:
+		cursor.Phase = models.CursorNodes
+		cursor.From, convErr = strconv.ParseInt(parts[1], 10, 64)
+	case len(parts) == 3 && parts[0] == models.CursorEdges:
+		cursor.Phase = models.CursorEdges
+		cursor.From, convErr = strconv.ParseInt(parts[1], 10, 64)
+		if convErr == nil {
+			cursor.To, convErr = strconv.ParseInt(parts[2], 10, 64)
+		}
+	default:
+		return models.GraphCursor{}, ErrNotValidCursor
+	}
+	if convErr != nil {
+		return models.GraphCursor{}, ErrNotValidCursor
+	}
+	return cursor, nil
+}
+
+// GetFullGraphPage - cursor-paginated full graph
+//
+// Handler GET /api/graph/page?cursor=<cursor>&limit=5000&min_links=5&size_by=links&theme=light&color_by=community
+//
+// Nodes are returned first, then edges. The first request goes without cursor,
+// every next one passes next_cursor of the previous response until it is empty.
+//
+// Possible response codes:
+// 200 - page of the full graph;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is neither analyst nor admin;
+// 500 - an internal server error.
+func GetFullGraphPage(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Length")
+		if len(headerContentType) != 0 {
+			messageResponse(w, "Content-Length is not equal 0", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		minLinks, sizeBy, queryErr := fullGraphQuery(r)
+		if queryErr != nil {
+			messageResponse(w, "Bad Request. "+queryErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		style, styleErr := fullGraphStyle(r)
+		if styleErr != nil {
+			messageResponse(w, "Bad Request. "+styleErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		limit, limitErr := queryInt(r, "limit")
+		if limitErr == nil && (limit < 0 || limit > models.MaxGraphPage) {
+			limitErr = fmt.Errorf("limit must be between 1 and %d", models.MaxGraphPage)
+		}
+		if limitErr != nil {
+			messageResponse(w, "Bad Request. "+limitErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		cursor, cursorErr := decodeCursor(r.URL.Query().Get("cursor"))
+		if cursorErr != nil {
+			messageResponse(w, "Bad Request. "+cursorErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		page, pageErr := database.Repo.GetFullGraphPage(r.Context(), minLinks, sizeBy, cursor, limit)
+		if pageErr != nil {
+			messageResponse(w, "Internal Server Error: "+pageErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+		if page.Next != nil {
+			page.NextCursor = encodeCursor(*page.Next)
+		}
+		style.Apply(page.Nodes)
+
+		pageRes, pageResErr := json.Marshal(page)
+		if pageResErr != nil {
+			panic(pageResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(pageRes)
+	}
+}
+
+// StreamFullGraph - full graph as NDJSON stream
+//
+// Handler GET /api/graph/stream?min_links=5&size_by=links&theme=light&color_by=community
+//
+// Every line is a JSON object {"type": "node"|"edge", "data": {...}},
+// all nodes go before the first edge. Rows are written as they are read from the database,
+// and every flushed chunk moves the write deadline of the connection forward,
+// so the stream is cut off only when a single chunk takes longer than the write timeout.
+//
+// Possible response codes:
+// 200 - stream of the full graph;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is neither analyst nor admin.
+func StreamFullGraph(database *app.Database, writeTimeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Length")
+		if len(headerContentType) != 0 {
+			messageResponse(w, "Content-Length is not equal 0", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		minLinks, sizeBy, queryErr := fullGraphQuery(r)
+		if queryErr != nil {
+			messageResponse(w, "Bad Request. "+queryErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		style, styleErr := fullGraphStyle(r)
+		if styleErr != nil {
+			messageResponse(w, "Bad Request. "+styleErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		// every flushed chunk gives the stream another writeTimeout of the server
+		extendWriteDeadline(r, writeTimeout)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+
+		flusher, _ := w.(http.Flusher)
+		encoder := json.NewEncoder(w)
+		rows := 0
+		send := func(rowType string, data interface{}) error {
+			if ctxErr := r.Context().Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if encodeErr := encoder.Encode(streamRow{Type: rowType, Data: data}); encodeErr != nil {
+				return encodeErr
+			}
+			rows++
+			if flusher != nil && rows%streamFlushRows == 0 {
+				flusher.Flush()
+				extendWriteDeadline(r, writeTimeout)
+			}
+			return nil
+		}
+
+		streamErr := database.Repo.StreamFullGraph(r.Context(), minLinks, sizeBy,
+			func(node models.GraphNode) error {
+				style.ApplyNode(&node)
+				return send("node", node)
+			},
+			func(edge models.GraphEdge) error { return send("edge", edge) },
+		)
+		if streamErr != nil {
+			// headers are already sent, the client sees the stream cut off
+			log.Println("stream of full graph interrupted: ", streamErr)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"AlexSarva/media/graphio"
+	"AlexSarva/media/models"
+	"AlexSarva/media/styling"
+	"bytes"
+	"net/http"
+)
+
+// graphFileExtensions extensions of downloaded graph files
+var graphFileExtensions = map[string]string{
+	graphio.FormatGraphML: "graphml",
+	graphio.FormatGEXF:    "gexf",
+	graphio.FormatDOT:     "dot",
+}
+
+// graphFormat format of the graph response: format query param wins over Accept header
+func graphFormat(r *http.Request) (string, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		return graphio.FormatByName(name)
+	}
+	return graphio.FormatByAccept(r.Header.Get("Accept")), nil
+}
+
+// graphStyle style of nodes of the graph response from theme and color_by query params,
+// rule is used when color_by is not set
+func graphStyle(r *http.Request, rule string) (styling.Style, error) {
+	colorBy := r.URL.Query().Get("color_by")
+	if len(colorBy) == 0 {
+		colorBy = rule
+	}
+	return styling.New(r.URL.Query().Get("theme"), colorBy)
+}
+
+// writeGraphFile writes graph in GraphML, GEXF or DOT format as downloadable file
+func writeGraphFile(w http.ResponseWriter, format string, nodes []models.GraphNode, edges []models.GraphEdge) {
+	var buf bytes.Buffer
+	if err := graphio.Write(&buf, format, nodes, edges); err != nil {
+		messageResponse(w, "Internal Server Error: "+err.Error(), "application/json", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", graphio.ContentType(format)+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="graph.`+graphFileExtensions[format]+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
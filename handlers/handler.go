@@ -2,18 +2,25 @@ package handlers
 
 import (
 	"AlexSarva/media/admin"
+	"AlexSarva/media/crypto"
+	"AlexSarva/media/graphio"
 	"AlexSarva/media/internal/app"
 	"AlexSarva/media/models"
 	"AlexSarva/media/storage/storagepg"
+	"AlexSarva/media/styling"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -59,7 +66,100 @@ func readBodyBytes(r *http.Request) (io.ReadCloser, error) {
 }
 
 // gzipContentTypes request types that support data compression
-var gzipContentTypes = "application/x-gzip, application/javascript, application/json, text/css, text/html, text/plain, text/xml"
+var gzipContentTypes = "application/x-gzip, application/javascript, application/json, application/x-ndjson, application/graphml+xml, application/gexf+xml, text/vnd.graphviz, text/css, text/html, text/plain, text/xml"
+
+// validateGraphParams checks ego graph expansion options of the request
+func validateGraphParams(params models.GraphParams) error {
+	if params.Depth < 0 || params.Depth > models.MaxGraphDepth {
+		return fmt.Errorf("depth must be 0 (default) or 1..%d", models.MaxGraphDepth)
+	}
+	if params.MaxNodes < 0 || params.MaxNodes > models.MaxGraphNodes {
+		return fmt.Errorf("max_nodes must be 0 (default) or 1..%d", models.MaxGraphNodes)
+	}
+	switch params.Direction {
+	case "", models.DirectionOut, models.DirectionIn, models.DirectionBoth:
+	default:
+		return fmt.Errorf("direction must be one of %s, %s, %s", models.DirectionOut, models.DirectionIn, models.DirectionBoth)
+	}
+	if params.MinLinks < 0 {
+		return errors.New("min_links must be 0 (default) or positive")
+	}
+	return validateSizeBy(params.SizeBy)
+}
+
+// validateSizeBy checks the name of the metric that drives node size
+func validateSizeBy(sizeBy string) error {
+	switch sizeBy {
+	case "", models.SizeByLinks, models.SizeByPageRank, models.SizeByBetweenness, models.SizeByInDegree, models.SizeByOutDegree:
+		return nil
+	}
+	return fmt.Errorf("size_by must be one of %s, %s, %s, %s, %s",
+		models.SizeByLinks, models.SizeByPageRank, models.SizeByBetweenness, models.SizeByInDegree, models.SizeByOutDegree)
+}
+
+// queryInt reads optional integer parameter from the URL query
+// returns 0 when parameter is absent
+func queryInt(r *http.Request, name string) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if len(raw) == 0 {
+		return 0, nil
+	}
+	value, convErr := strconv.Atoi(raw)
+	if convErr != nil {
+		return 0, fmt.Errorf("%s must be integer", name)
+	}
+	return value, nil
+}
+
+// graphCardsQuery reads filter, sorting and page of saved graph cards from the URL query
+func graphCardsQuery(r *http.Request) (models.GraphCardsQuery, error) {
+	values := r.URL.Query()
+	query := models.GraphCardsQuery{
+		Folder: strings.TrimSpace(values.Get("folder")),
+		Search: strings.TrimSpace(values.Get("q")),
+		Sort:   values.Get("sort"),
+	}
+	for _, tag := range values["tag"] {
+		if tag = strings.TrimSpace(tag); len(tag) != 0 {
+			query.Tags = append(query.Tags, tag)
+		}
+	}
+
+	switch query.Sort {
+	case "":
+		query.Sort = models.GraphSortCreated
+	case models.GraphSortCreated, models.GraphSortUpdated, models.GraphSortName, models.GraphSortCnt:
+	default:
+		return models.GraphCardsQuery{}, fmt.Errorf("sort must be one of %s, %s, %s, %s",
+			models.GraphSortCreated, models.GraphSortUpdated, models.GraphSortName, models.GraphSortCnt)
+	}
+	switch values.Get("order") {
+	case "":
+		query.Desc = query.Sort != models.GraphSortName
+	case "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return models.GraphCardsQuery{}, errors.New("order must be asc or desc")
+	}
+
+	var limitErr, offsetErr error
+	query.Limit, limitErr = queryInt(r, "limit")
+	if limitErr != nil {
+		return models.GraphCardsQuery{}, limitErr
+	}
+	if query.Limit < 0 || query.Limit > models.MaxGraphCardsPage {
+		return models.GraphCardsQuery{}, fmt.Errorf("limit must be between 0 and %d", models.MaxGraphCardsPage)
+	}
+	query.Offset, offsetErr = queryInt(r, "offset")
+	if offsetErr != nil {
+		return models.GraphCardsQuery{}, offsetErr
+	}
+	if query.Offset < 0 {
+		return models.GraphCardsQuery{}, errors.New("offset must not be negative")
+	}
+	return query, nil
+}
 
 func GetSearch(database *app.Database) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -92,7 +192,7 @@ func GetSearch(database *app.Database) http.HandlerFunc {
 			return
 		}
 
-		searchRes, searchResErr := database.Repo.GetSearch(query.Text)
+		searchRes, searchResErr := database.Repo.GetSearch(r.Context(), query.Text)
 		if searchResErr != nil {
 			if searchResErr == admin.ErrNoValues {
 				w.Header().Set("Content-Type", "application/json")
@@ -122,6 +222,18 @@ func GetGraph(database *app.Database) http.HandlerFunc {
 			return
 		}
 
+		format, formatErr := graphFormat(r)
+		if formatErr != nil {
+			messageResponse(w, "Not Acceptable. "+formatErr.Error(), "application/json", http.StatusNotAcceptable)
+			return
+		}
+
+		style, styleErr := graphStyle(r, styling.RuleKind)
+		if styleErr != nil {
+			messageResponse(w, "Bad Request. "+styleErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
 		var query models.GraphQuery
 		var unmarshalErr *json.UnmarshalTypeError
 
@@ -144,7 +256,12 @@ func GetGraph(database *app.Database) http.HandlerFunc {
 			return
 		}
 
-		graphInfo, graphInfoErr := database.Repo.GetGraphByURL(query.Query)
+		if paramsErr := validateGraphParams(query.GraphParams); paramsErr != nil {
+			messageResponse(w, "Bad Request. "+paramsErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		graphInfo, graphInfoErr := database.Repo.GetGraphByURL(r.Context(), query.Query, query.GraphParams)
 		if graphInfoErr != nil {
 			if graphInfoErr == admin.ErrNoValues {
 				w.Header().Set("Content-Type", "application/json")
@@ -155,6 +272,12 @@ func GetGraph(database *app.Database) http.HandlerFunc {
 			return
 		}
 
+		style.Apply(graphInfo.Nodes)
+		if format != graphio.FormatJSON {
+			writeGraphFile(w, format, graphInfo.Nodes, graphInfo.Edges)
+			return
+		}
+
 		graphRes, graphResErr := json.Marshal(graphInfo)
 		if graphResErr != nil {
 			panic(graphResErr)
@@ -174,6 +297,18 @@ func GetGraphByID(database *app.Database) http.HandlerFunc {
 			return
 		}
 
+		format, formatErr := graphFormat(r)
+		if formatErr != nil {
+			messageResponse(w, "Not Acceptable. "+formatErr.Error(), "application/json", http.StatusNotAcceptable)
+			return
+		}
+
+		style, styleErr := graphStyle(r, styling.RuleKind)
+		if styleErr != nil {
+			messageResponse(w, "Bad Request. "+styleErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
 		var query models.GraphQueryID
 		var unmarshalErr *json.UnmarshalTypeError
 
@@ -196,7 +331,12 @@ func GetGraphByID(database *app.Database) http.HandlerFunc {
 			return
 		}
 
-		graphInfo, graphInfoErr := database.Repo.GetGraphByID(query.ID)
+		if paramsErr := validateGraphParams(query.GraphParams); paramsErr != nil {
+			messageResponse(w, "Bad Request. "+paramsErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		graphInfo, graphInfoErr := database.Repo.GetGraphByID(r.Context(), query.ID, query.GraphParams)
 		if graphInfoErr != nil {
 			if graphInfoErr == admin.ErrNoValues {
 				w.Header().Set("Content-Type", "application/json")
@@ -207,6 +347,108 @@ func GetGraphByID(database *app.Database) http.HandlerFunc {
 			return
 		}
 
+		style.Apply(graphInfo.Nodes)
+		if format != graphio.FormatJSON {
+			writeGraphFile(w, format, graphInfo.Nodes, graphInfo.Edges)
+			return
+		}
+
+		graphRes, graphResErr := json.Marshal(graphInfo)
+		if graphResErr != nil {
+			panic(graphResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(graphRes)
+	}
+}
+
+// FindPaths - search of the shortest paths between two sources
+//
+// Handler POST /api/graph/path
+//
+// Request format:
+//
+//	{"from": <source id>, "to": <source id>, "max_hops": 4, "min_links": 5}
+//
+// Up to 5 paths with the strongest links are returned in paths, ordered by weight.
+// paths_truncated is set when the search was stopped by its limit on dense networks,
+// the paths are then the best found but shorter ones may be missing.
+//
+// Possible response codes:
+// 200 - graph of the found paths;
+// 204 - sources are not connected within max_hops;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is neither analyst nor admin;
+// 422 - search limit or node cap is exceeded before any path is found;
+// 500 - an internal server error.
+func FindPaths(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		var query models.PathQuery
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		style, styleErr := graphStyle(r, styling.RuleKind)
+		if styleErr != nil {
+			messageResponse(w, "Bad Request. "+styleErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		if query.From == query.To {
+			messageResponse(w, "Bad Request. from and to must be different sources", "application/json", http.StatusBadRequest)
+			return
+		}
+		if query.MaxHops < 0 || query.MaxHops > models.MaxPathHops {
+			messageResponse(w, fmt.Sprintf("Bad Request. max_hops must be between 1 and %d", models.MaxPathHops), "application/json", http.StatusBadRequest)
+			return
+		}
+		if query.MinLinks < 0 {
+			messageResponse(w, "Bad Request. min_links must be 0 (default) or positive", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		graphInfo, graphInfoErr := database.Repo.FindPaths(r.Context(), query.From, query.To, query.MaxHops, query.MinLinks)
+		if graphInfoErr != nil {
+			if errors.Is(graphInfoErr, storagepg.ErrNoPath) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			if errors.Is(graphInfoErr, storagepg.ErrPathsTruncated) {
+				messageResponse(w, graphInfoErr.Error(), "application/json", http.StatusUnprocessableEntity)
+				return
+			}
+			messageResponse(w, "Internal Server Error: "+graphInfoErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		style.Apply(graphInfo.Nodes)
+
 		graphRes, graphResErr := json.Marshal(graphInfo)
 		if graphResErr != nil {
 			panic(graphResErr)
@@ -248,7 +490,7 @@ func GetSourceByURL(database *app.Database) http.HandlerFunc {
 			return
 		}
 
-		sourceInfo, sourceInfoErr := database.Repo.GetSourceInfoByURL(query.Query)
+		sourceInfo, sourceInfoErr := database.Repo.GetSourceInfoByURL(r.Context(), query.Query)
 		if sourceInfoErr != nil {
 			if sourceInfoErr == admin.ErrNoValues {
 				w.Header().Set("Content-Type", "application/json")
@@ -300,7 +542,7 @@ func GetSourceByID(database *app.Database) http.HandlerFunc {
 			return
 		}
 
-		sourceInfo, sourceInfoErr := database.Repo.GetSourceInfoByID(query.ID)
+		sourceInfo, sourceInfoErr := database.Repo.GetSourceInfoByID(r.Context(), query.ID)
 		if sourceInfoErr != nil {
 			if sourceInfoErr == admin.ErrNoValues {
 				w.Header().Set("Content-Type", "application/json")
@@ -329,7 +571,25 @@ func GetFullGraph(database *app.Database) http.HandlerFunc {
 			return
 		}
 
-		graph, graphErr := database.Repo.GetFullGraph()
+		minLinks, sizeBy, queryErr := fullGraphQuery(r)
+		if queryErr != nil {
+			messageResponse(w, "Bad Request. "+queryErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		format, formatErr := graphFormat(r)
+		if formatErr != nil {
+			messageResponse(w, "Not Acceptable. "+formatErr.Error(), "application/json", http.StatusNotAcceptable)
+			return
+		}
+
+		style, styleErr := graphStyle(r, styling.RuleCommunity)
+		if styleErr != nil {
+			messageResponse(w, "Bad Request. "+styleErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		graph, graphErr := database.Repo.GetFullGraph(r.Context(), minLinks, sizeBy)
 		if graphErr != nil {
 			if errors.Is(graphErr, sql.ErrNoRows) {
 				messageResponse(w, "user doesnt exist", "application/json", http.StatusUnauthorized)
@@ -339,6 +599,12 @@ func GetFullGraph(database *app.Database) http.HandlerFunc {
 			return
 		}
 
+		style.Apply(graph.Nodes)
+		if format != graphio.FormatJSON {
+			writeGraphFile(w, format, graph.Nodes, graph.Edges)
+			return
+		}
+
 		jsonResp, _ := json.Marshal(graph)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -346,7 +612,7 @@ func GetFullGraph(database *app.Database) http.HandlerFunc {
 	}
 }
 
-func AddNewGraph(database *app.Database, adminDB *admin.PostgresDB) http.HandlerFunc {
+func AddNewGraph(database *app.Database) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("%+v\n", r.Header)
 		headerContentType := r.Header.Get("Content-Type")
@@ -355,25 +621,7 @@ func AddNewGraph(database *app.Database, adminDB *admin.PostgresDB) http.Handler
 			return
 		}
 
-		// Проверка авторизации по токену
-		userID, tokenErr := GetToken(r)
-		if tokenErr != nil {
-			messageResponse(w, "User unauthorized: "+tokenErr.Error(), "application/json", http.StatusUnauthorized)
-			return
-		}
-
-		userInfo, userInfoErr := adminDB.GetUserInfo(userID)
-		if userInfoErr != nil {
-			if errors.Is(userInfoErr, sql.ErrNoRows) {
-				messageResponse(w, "user doesnt exist", "application/json", http.StatusUnauthorized)
-				return
-			}
-			messageResponse(w, "Internal Server Error: "+userInfoErr.Error(), "application/json", http.StatusInternalServerError)
-			return
-		}
-
-		userInfo.Type = "Bearer"
-		log.Printf("USER: %+v\n", userInfo)
+		userID := requestUser(r).ID
 
 		//defer r.Body.Close()
 		//bodyBytes, err := io.ReadAll(r.Body)
@@ -407,19 +655,26 @@ func AddNewGraph(database *app.Database, adminDB *admin.PostgresDB) http.Handler
 			return
 		}
 
+		if newGraph.MinLinks < 0 {
+			messageResponse(w, "Bad Request. min_links must be 0 (default) or positive", "application/json", http.StatusBadRequest)
+			return
+		}
+		if newGraph.MinLinks == 0 {
+			newGraph.MinLinks = models.DefaultMinLinks
+		}
+
 		newGraph.UserID = userID
 		newGraph.Cnt = len(newGraph.Sources)
 
-		resp, respErr := database.Repo.AddNewGraph(newGraph)
+		resp, respErr := database.Repo.AddNewGraph(r.Context(), newGraph)
 		if respErr != nil {
-			if errors.As(storagepg.ErrDuplicatePK, &respErr) {
+			if errors.Is(respErr, storagepg.ErrDuplicatePK) {
 				messageResponse(w, "GraphID already exists", "application/json", http.StatusConflict)
 				return
-			} else {
-				messageResponse(w, "Internal Server Error: "+respErr.Error(), "application/json", http.StatusInternalServerError)
-				return
-				log.Println(respErr)
 			}
+			log.Println(respErr)
+			messageResponse(w, "Internal Server Error: "+respErr.Error(), "application/json", http.StatusInternalServerError)
+			return
 
 		}
 
@@ -435,7 +690,25 @@ func AddNewGraph(database *app.Database, adminDB *admin.PostgresDB) http.Handler
 	}
 }
 
-func GetGraphCards(database *app.Database, adminDB *admin.PostgresDB) http.HandlerFunc {
+// GetGraphCards - list of saved graphs of the user and graphs shared with the user
+//
+// Handler GET /api/graph/all
+//
+// Optional query parameters:
+// folder - cards of the folder only;
+// tag - cards with the tag, repeated parameter requires every tag;
+// q - text search over name and description;
+// sort - created (default), updated, name or cnt;
+// order - asc or desc, name is sorted ascending and others descending by default;
+// limit, offset - page of cards, all cards are returned when limit is not set.
+// Header X-Total-Count contains the number of cards matching the filter.
+//
+// Possible response codes:
+// 200 - list of graph cards;
+// 400 - invalid query parameters;
+// 401 - user not authenticated;
+// 500 - an internal server error.
+func GetGraphCards(database *app.Database) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		headerContentType := r.Header.Get("Content-Length")
 		if len(headerContentType) != 0 {
@@ -443,26 +716,15 @@ func GetGraphCards(database *app.Database, adminDB *admin.PostgresDB) http.Handl
 			return
 		}
 
-		// Проверка авторизации по токену
-		userID, tokenErr := GetToken(r)
-		if tokenErr != nil {
-			messageResponse(w, "User unauthorized: "+tokenErr.Error(), "application/json", http.StatusUnauthorized)
-			return
-		}
+		userID := requestUser(r).ID
 
-		userInfo, userInfoErr := adminDB.GetUserInfo(userID)
-		if userInfoErr != nil {
-			if errors.Is(userInfoErr, sql.ErrNoRows) {
-				messageResponse(w, "user doesnt exist", "application/json", http.StatusUnauthorized)
-				return
-			}
-			messageResponse(w, "Internal Server Error: "+userInfoErr.Error(), "application/json", http.StatusInternalServerError)
+		query, queryErr := graphCardsQuery(r)
+		if queryErr != nil {
+			messageResponse(w, "Bad Request. "+queryErr.Error(), "application/json", http.StatusBadRequest)
 			return
 		}
 
-		userInfo.Type = "Bearer"
-
-		graphCards, graphCardsErr := database.Repo.GetGraphCards(userID)
+		graphCards, total, graphCardsErr := database.Repo.GetGraphCards(r.Context(), userID, query)
 		if graphCardsErr != nil {
 			if errors.Is(graphCardsErr, sql.ErrNoRows) {
 				messageResponse(w, "no graphs exist", "application/json", http.StatusUnauthorized)
@@ -474,12 +736,13 @@ func GetGraphCards(database *app.Database, adminDB *admin.PostgresDB) http.Handl
 
 		jsonResp, _ := json.Marshal(graphCards)
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
 		w.WriteHeader(http.StatusOK)
 		w.Write(jsonResp)
 	}
 }
 
-func DeleteGraphCard(database *app.Database, adminDB *admin.PostgresDB) http.HandlerFunc {
+func DeleteGraphCard(database *app.Database) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("%+v\n", r.Header)
 		headerContentType := r.Header.Get("Content-Type")
@@ -488,25 +751,7 @@ func DeleteGraphCard(database *app.Database, adminDB *admin.PostgresDB) http.Han
 			return
 		}
 
-		// Проверка авторизации по токену
-		userID, tokenErr := GetToken(r)
-		if tokenErr != nil {
-			messageResponse(w, "User unauthorized: "+tokenErr.Error(), "application/json", http.StatusUnauthorized)
-			return
-		}
-
-		userInfo, userInfoErr := adminDB.GetUserInfo(userID)
-		if userInfoErr != nil {
-			if errors.Is(userInfoErr, sql.ErrNoRows) {
-				messageResponse(w, "user doesnt exist", "application/json", http.StatusUnauthorized)
-				return
-			}
-			messageResponse(w, "Internal Server Error: "+userInfoErr.Error(), "application/json", http.StatusInternalServerError)
-			return
-		}
-
-		userInfo.Type = "Bearer"
-		log.Printf("USER: %+v\n", userInfo)
+		userID := requestUser(r).ID
 
 		var graphDel models.GraphDel
 		var unmarshalErr *json.UnmarshalTypeError
@@ -532,16 +777,15 @@ func DeleteGraphCard(database *app.Database, adminDB *admin.PostgresDB) http.Han
 			return
 		}
 
-		resp, respErr := database.Repo.DeleteGraphCard(userID, graphDel.GraphID)
+		resp, respErr := database.Repo.DeleteGraphCard(r.Context(), userID, graphDel.GraphID)
 		if respErr != nil {
-			if errors.As(storagepg.ErrNoData, &respErr) {
+			if errors.Is(respErr, storagepg.ErrNoData) {
 				messageResponse(w, storagepg.ErrNoData.Error(), "application/json", http.StatusConflict)
 				return
-			} else {
-				messageResponse(w, "Internal Server Error: "+respErr.Error(), "application/json", http.StatusInternalServerError)
-				return
-				log.Println(respErr)
 			}
+			log.Println(respErr)
+			messageResponse(w, "Internal Server Error: "+respErr.Error(), "application/json", http.StatusInternalServerError)
+			return
 
 		}
 
@@ -555,7 +799,7 @@ func DeleteGraphCard(database *app.Database, adminDB *admin.PostgresDB) http.Han
 	}
 }
 
-func GetGraphByUUID(database *app.Database, adminDB *admin.PostgresDB) http.HandlerFunc {
+func GetGraphByUUID(database *app.Database) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("%+v\n", r.Header)
 		headerContentType := r.Header.Get("Content-Type")
@@ -564,25 +808,19 @@ func GetGraphByUUID(database *app.Database, adminDB *admin.PostgresDB) http.Hand
 			return
 		}
 
-		// Проверка авторизации по токену
-		userID, tokenErr := GetToken(r)
-		if tokenErr != nil {
-			messageResponse(w, "User unauthorized: "+tokenErr.Error(), "application/json", http.StatusUnauthorized)
+		format, formatErr := graphFormat(r)
+		if formatErr != nil {
+			messageResponse(w, "Not Acceptable. "+formatErr.Error(), "application/json", http.StatusNotAcceptable)
 			return
 		}
 
-		userInfo, userInfoErr := adminDB.GetUserInfo(userID)
-		if userInfoErr != nil {
-			if errors.Is(userInfoErr, sql.ErrNoRows) {
-				messageResponse(w, "user doesnt exist", "application/json", http.StatusUnauthorized)
-				return
-			}
-			messageResponse(w, "Internal Server Error: "+userInfoErr.Error(), "application/json", http.StatusInternalServerError)
+		style, styleErr := graphStyle(r, styling.RuleKind)
+		if styleErr != nil {
+			messageResponse(w, "Bad Request. "+styleErr.Error(), "application/json", http.StatusBadRequest)
 			return
 		}
 
-		userInfo.Type = "Bearer"
-		log.Printf("USER: %+v\n", userInfo)
+		userID := requestUser(r).ID
 
 		var query models.GraphUUID
 		var unmarshalErr *json.UnmarshalTypeError
@@ -606,9 +844,18 @@ func GetGraphByUUID(database *app.Database, adminDB *admin.PostgresDB) http.Hand
 			return
 		}
 
-		graphInfo, graphInfoErr := database.Repo.GetGraphByUUID(query.GraphID)
+		if paramsErr := validateGraphParams(query.GraphParams); paramsErr != nil {
+			messageResponse(w, "Bad Request. "+paramsErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		graphInfo, graphInfoErr := database.Repo.GetGraphByUUID(r.Context(), userID, query.GraphID, query.GraphParams)
 		if graphInfoErr != nil {
-			if graphInfoErr == admin.ErrNoValues {
+			if errors.Is(graphInfoErr, storagepg.ErrForbidden) {
+				messageResponse(w, graphInfoErr.Error(), "application/json", http.StatusForbidden)
+				return
+			}
+			if graphInfoErr == admin.ErrNoValues || errors.Is(graphInfoErr, sql.ErrNoRows) {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusNoContent)
 				return
@@ -617,6 +864,12 @@ func GetGraphByUUID(database *app.Database, adminDB *admin.PostgresDB) http.Hand
 			return
 		}
 
+		style.ApplyExtended(&graphInfo)
+		if format != graphio.FormatJSON {
+			writeGraphFile(w, format, graphInfo.Nodes, graphInfo.Edges)
+			return
+		}
+
 		graphRes, graphResErr := json.Marshal(graphInfo)
 		if graphResErr != nil {
 			panic(graphResErr)
@@ -634,16 +887,58 @@ func MyAllowOriginFunc(r *http.Request, origin string) bool {
 	return false
 }
 
+// requestTimeout cancels the context of regular routes after timeout,
+// so database queries stop together with the connection closed by the server WriteTimeout
+func requestTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// writeDeadlineKey key of the write deadline setter in the request context
+type writeDeadlineKey struct{}
+
+// writeDeadlines keeps the write deadline setter of the connection in the request context,
+// response writers of the later middlewares hide it from streaming handlers
+func writeDeadlines(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn, ok := w.(interface{ SetWriteDeadline(time.Time) error }); ok {
+			r = r.WithContext(context.WithValue(r.Context(), writeDeadlineKey{}, conn.SetWriteDeadline))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// extendWriteDeadline moves the write deadline of the connection set by the server WriteTimeout,
+// it does nothing when the server does not support it
+func extendWriteDeadline(r *http.Request, timeout time.Duration) {
+	if setDeadline, ok := r.Context().Value(writeDeadlineKey{}).(func(time.Time) error); ok {
+		if err := setDeadline(time.Now().Add(timeout)); err != nil {
+			log.Println("write deadline: ", err)
+		}
+	}
+}
+
 // MyHandler - the main handler of the server
 // contains middlewares and all routes
-func MyHandler(database *app.Database, adminDatabase *admin.PostgresDB) *chi.Mux {
+func MyHandler(cfg *models.Config, database *app.Database, adminDatabase *admin.PostgresDB, keys *crypto.Keyring) *chi.Mux {
+	return newRouter(cfg, database, adminDatabase, adminDatabase, keys)
+}
+
+// newRouter router of MyHandler, users of sessions of authenticated routes come from sessions
+func newRouter(cfg *models.Config, database *app.Database, adminDatabase *admin.PostgresDB, sessions SessionUsers, keys *crypto.Keyring) *chi.Mux {
 	r := chi.NewRouter()
+	r.Use(writeDeadlines)
 	r.Use(cors.Handler(cors.Options{
 		AllowOriginFunc: MyAllowOriginFunc,
 		//AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "X-Total-Count"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -651,25 +946,102 @@ func MyHandler(database *app.Database, adminDatabase *admin.PostgresDB) *chi.Mux
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	r.Use(TokenAuth(keys))
 	r.Use(middleware.AllowContentEncoding("gzip"))
-	r.Use(middleware.AllowContentType("application/json", "text/plain", "application/x-gzip"))
+	r.Use(middleware.AllowContentType("application/json", "text/plain", "application/x-gzip", "multipart/form-data"))
 	r.Use(middleware.Compress(5, gzipContentTypes))
-	r.Mount("/debug", middleware.Profiler())
-	r.Get("/api/graph", GetFullGraph(database))
-	r.Post("/api/graph/url", GetGraph(database))
-	r.Post("/api/graph/id", GetGraphByID(database))
-	r.Post("/api/graph/new", AddNewGraph(database, adminDatabase))
-	r.Get("/api/graph/all", GetGraphCards(database, adminDatabase))
-	r.Post("/api/graph/uuid", GetGraphByUUID(database, adminDatabase))
-	r.Delete("/api/graph/del", DeleteGraphCard(database, adminDatabase))
-	r.Post("/api/source/url", GetSourceByURL(database))
-	r.Post("/api/source/id", GetSourceByID(database))
-	//
-	r.Post("/api/user/register", UserRegistration(adminDatabase))
-	r.Post("/api/user/login", UserAuthentication(adminDatabase))
-	r.Get("/api/users/me", GetUserInfo(adminDatabase))
-	r.Post("/api/search", GetSearch(database))
-	//r.Get("/api/user/orders", GetOrders(database))
+	cache := NewSessionCache(cfg.AuthCacheTTL)
+
+	// public routes
+	r.Group(func(r chi.Router) {
+		r.Use(requestTimeout(cfg.WriteTimeout))
+		r.Get("/api/public/graph/{token}", GetSharedGraph(database, keys))
+		r.Post("/api/user/register", UserRegistration(adminDatabase, keys, cfg.AccessTokenTTL, cfg.RefreshTokenTTL))
+		r.Post("/api/user/login", UserAuthentication(adminDatabase, keys, cfg.AccessTokenTTL, cfg.RefreshTokenTTL))
+		r.Post("/api/user/refresh", RefreshToken(adminDatabase, keys, cfg.AccessTokenTTL, cfg.RefreshTokenTTL, cache))
+		//r.Get("/api/user/orders", GetOrders(database))
+	})
+
+	// routes of the full graph and of building saved graphs from sources
+	r.Group(func(r chi.Router) {
+		r.Use(UserAuth(sessions, cache))
+		r.Use(RequireRole(models.RoleAdmin, models.RoleAnalyst))
+		// streaming routes write for as long as rows come from the database
+		r.Get("/api/graph/stream", StreamFullGraph(database, cfg.WriteTimeout))
+
+		r.Group(func(r chi.Router) {
+			r.Use(requestTimeout(cfg.WriteTimeout))
+			r.Get("/api/graph", GetFullGraph(database))
+			r.Get("/api/graph/page", GetFullGraphPage(database))
+			r.Post("/api/graph/url", GetGraph(database))
+			r.Post("/api/graph/id", GetGraphByID(database))
+			r.Post("/api/graph/path", FindPaths(database))
+			r.Post("/api/graph/new", AddNewGraph(database))
+			r.Post("/api/graph/import", ImportGraph(database))
+			// these routes put arbitrary sources into saved graphs the user can then read
+			r.Patch("/api/graph/sources/add", AddGraphSources(database))
+			r.Post("/api/graph/combine", CombineGraphs(database))
+			r.Post("/api/graph/clone", CloneGraph(database))
+			r.Post("/api/source/url", GetSourceByURL(database))
+			r.Post("/api/source/id", GetSourceByID(database))
+			r.Post("/api/search", GetSearch(database))
+		})
+	})
+
+	// routes of saved graphs available to every role
+	r.Group(func(r chi.Router) {
+		r.Use(UserAuth(sessions, cache))
+		r.Use(requestTimeout(cfg.WriteTimeout))
+		r.Get("/api/graph/all", GetGraphCards(database))
+		r.Get("/api/graph/labels", GetGraphLabels(database))
+		r.Post("/api/graph/uuid", GetGraphByUUID(database))
+		r.Post("/api/graph/export", ExportGraph(database))
+		r.Patch("/api/graph", UpdateGraphCard(database))
+		r.Patch("/api/graph/sources/remove", RemoveGraphSources(database))
+		r.Patch("/api/graph/sources/order", ReorderGraphSources(database))
+		r.Post("/api/graph/revisions", GetGraphRevisions(database))
+		r.Post("/api/graph/revisions/diff", DiffGraphRevisions(database))
+		r.Post("/api/graph/revisions/restore", RestoreGraphRevision(database))
+		r.Post("/api/graph/compare", CompareGraphs(database))
+		r.Put("/api/graph/layout", SaveGraphLayout(database))
+		r.Put("/api/graph/annotations", SetGraphAnnotation(database))
+		r.Delete("/api/graph/annotations", DeleteGraphAnnotation(database))
+		r.Post("/api/graph/share/list", GetGraphAccess(database))
+		r.Post("/api/graph/share", ShareGraph(database))
+		r.Delete("/api/graph/share", UnshareGraph(database))
+		r.Post("/api/graph/links", CreateGraphLink(database, keys))
+		r.Post("/api/graph/links/list", GetGraphLinks(database, keys))
+		r.Delete("/api/graph/links", RevokeGraphLink(database))
+		r.Delete("/api/graph/del", DeleteGraphCard(database))
+		r.Get("/api/graph/trash", GetDeletedGraphCards(database, cfg.GraphRetention))
+		r.Post("/api/graph/trash/restore", RestoreGraphCard(database))
+		r.Delete("/api/graph/trash/purge", PurgeGraphCard(database))
+		r.Post("/api/user/logout", UserLogout(adminDatabase, cache))
+		r.Post("/api/user/logout/all", UserLogoutAll(adminDatabase, cache))
+		r.Get("/api/users/me", GetUserInfo(adminDatabase))
+	})
+
+	// admin routes
+	r.Group(func(r chi.Router) {
+		r.Use(UserAuth(sessions, cache))
+		r.Use(RequireRole(models.RoleAdmin))
+		r.Mount("/debug", middleware.Profiler())
+
+		r.Group(func(r chi.Router) {
+			r.Use(requestTimeout(cfg.WriteTimeout))
+			r.Post("/api/analytics/communities", DetectCommunities(database))
+			r.Get("/api/analytics/communities", GetCommunitiesStatus(database))
+			r.Post("/api/analytics/metrics", CalculateMetrics(database))
+			r.Get("/api/analytics/metrics", GetMetricsStatus(database))
+			r.Post("/api/analytics/layout", CalculateLayout(database))
+			r.Get("/api/analytics/layout", GetLayoutStatus(database))
+			r.Get("/api/admin/users", ListUsers(adminDatabase))
+			r.Patch("/api/admin/users/role", SetUserRole(adminDatabase, cache))
+			r.Patch("/api/admin/users/disable", DisableUser(adminDatabase, cache))
+			r.Get("/api/admin/users/{user_id}/graphs", GetUserGraphCards(database))
+			r.Post("/api/admin/users/{user_id}/graph", GetUserGraph(database))
+		})
+	})
 
 	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"AlexSarva/media/admin"
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/storage"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubRepo repository of handler tests, methods which are not overridden panic
+type stubRepo struct {
+	storage.Repo
+	// metrics results of CalculateMetrics, which waits for them
+	metrics chan models.MetricsInfo
+	// communities results of DetectCommunities, which waits for them
+	communities chan models.CommunitiesInfo
+	// layout results of CalculateLayout, which waits for them
+	layout chan models.LayoutInfo
+}
+
+func (s *stubRepo) GetGraphByURL(ctx context.Context, text string, params models.GraphParams) (models.Graph, error) {
+	return models.Graph{}, admin.ErrNoValues
+}
+
+func (s *stubRepo) CalculateMetrics(ctx context.Context, minLinks int) (models.MetricsInfo, error) {
+	return <-s.metrics, nil
+}
+
+func (s *stubRepo) DetectCommunities(ctx context.Context, minLinks int) (models.CommunitiesInfo, error) {
+	return <-s.communities, nil
+}
+
+func (s *stubRepo) CalculateLayout(ctx context.Context, minLinks int) (models.LayoutInfo, error) {
+	return <-s.layout, nil
+}
+
+func TestCalculateMetricsInBackground(t *testing.T) {
+	repo := &stubRepo{metrics: make(chan models.MetricsInfo)}
+	database := &app.Database{Repo: repo}
+	start := func() *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/api/analytics/metrics", strings.NewReader(`{"min_links": 5}`))
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		CalculateMetrics(database).ServeHTTP(recorder, request)
+		return recorder
+	}
+	status := func() models.MetricsJob {
+		recorder := httptest.NewRecorder()
+		GetMetricsStatus(database).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/analytics/metrics", nil))
+		var job models.MetricsJob
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &job))
+		return job
+	}
+
+	first := start()
+	assert.Equal(t, http.StatusAccepted, first.Code)
+	assert.Contains(t, first.Body.String(), `"running":true`)
+	assert.Equal(t, http.StatusConflict, start().Code)
+	assert.True(t, status().Running)
+
+	repo.metrics <- models.MetricsInfo{Nodes: 3, MinLinks: 5}
+	assert.Eventually(t, func() bool { return !status().Running }, time.Second, 10*time.Millisecond)
+	job := status()
+	assert.Empty(t, job.Error)
+	if assert.NotNil(t, job.Last) {
+		assert.Equal(t, 3, job.Last.Nodes)
+	}
+
+	assert.Equal(t, http.StatusAccepted, start().Code)
+	repo.metrics <- models.MetricsInfo{}
+}
+
+func TestDetectCommunitiesInBackground(t *testing.T) {
+	repo := &stubRepo{communities: make(chan models.CommunitiesInfo)}
+	database := &app.Database{Repo: repo}
+	start := func() *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/api/analytics/communities", strings.NewReader(`{"min_links": 5}`))
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		DetectCommunities(database).ServeHTTP(recorder, request)
+		return recorder
+	}
+	status := func() models.CommunitiesJob {
+		recorder := httptest.NewRecorder()
+		GetCommunitiesStatus(database).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/analytics/communities", nil))
+		var job models.CommunitiesJob
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &job))
+		return job
+	}
+
+	assert.Equal(t, http.StatusAccepted, start().Code)
+	assert.Equal(t, http.StatusConflict, start().Code)
+	assert.True(t, status().Running)
+
+	repo.communities <- models.CommunitiesInfo{Nodes: 3, Communities: 2, MinLinks: 5}
+	assert.Eventually(t, func() bool { return !status().Running }, time.Second, 10*time.Millisecond)
+	if job := status(); assert.NotNil(t, job.Last) {
+		assert.Equal(t, 2, job.Last.Communities)
+	}
+}
+
+func TestCalculateLayoutInBackground(t *testing.T) {
+	repo := &stubRepo{layout: make(chan models.LayoutInfo)}
+	database := &app.Database{Repo: repo}
+	start := func() *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/api/analytics/layout", strings.NewReader(`{"min_links": 5}`))
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		CalculateLayout(database).ServeHTTP(recorder, request)
+		return recorder
+	}
+	status := func() models.LayoutJob {
+		recorder := httptest.NewRecorder()
+		GetLayoutStatus(database).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/analytics/layout", nil))
+		var job models.LayoutJob
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &job))
+		return job
+	}
+
+	assert.Equal(t, http.StatusAccepted, start().Code)
+	assert.Equal(t, http.StatusConflict, start().Code)
+	assert.True(t, status().Running)
+
+	repo.layout <- models.LayoutInfo{Nodes: 4, MinLinks: 5}
+	assert.Eventually(t, func() bool { return !status().Running }, time.Second, 10*time.Millisecond)
+	if job := status(); assert.NotNil(t, job.Last) {
+		assert.Equal(t, 4, job.Last.Nodes)
+	}
+}
+
+func TestGetGraphLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    int
+		message string
+	}{
+		{name: "defaults", body: `{"query": "t.me/news"}`, want: http.StatusNoContent},
+		{name: "zero depth is default", body: `{"query": "t.me/news", "depth": 0, "max_nodes": 0}`, want: http.StatusNoContent},
+		{name: "max depth", body: `{"query": "t.me/news", "depth": 3}`, want: http.StatusNoContent},
+		{name: "max nodes", body: `{"query": "t.me/news", "max_nodes": 5000}`, want: http.StatusNoContent},
+		{name: "too deep", body: `{"query": "t.me/news", "depth": 4}`, want: http.StatusBadRequest, message: "depth must be 0 (default) or 1..3"},
+		{name: "negative depth", body: `{"query": "t.me/news", "depth": -1}`, want: http.StatusBadRequest, message: "depth must be 0 (default) or 1..3"},
+		{name: "too many nodes", body: `{"query": "t.me/news", "max_nodes": 5001}`, want: http.StatusBadRequest, message: "max_nodes must be 0 (default) or 1..5000"},
+		{name: "unknown direction", body: `{"query": "t.me/news", "direction": "up"}`, want: http.StatusBadRequest, message: "direction must be one of"},
+		{name: "negative min_links", body: `{"query": "t.me/news", "min_links": -5}`, want: http.StatusBadRequest, message: "min_links must be 0 (default) or positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubRepo{}
+			request := httptest.NewRequest(http.MethodPost, "/api/graph/url", strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+
+			GetGraph(&app.Database{Repo: repo}).ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.want, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), tt.message)
+		})
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	var deadline time.Time
+	var ok bool
+	handler := requestTimeout(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok = r.Context().Deadline()
+	}))
+
+	started := time.Now()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/graph", nil))
+
+	assert.True(t, ok)
+	assert.WithinDuration(t, started.Add(time.Minute), deadline, time.Second)
+}
+
+// deadlineRecorder response writer of a connection with write deadline
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadline time.Time
+}
+
+func (d *deadlineRecorder) SetWriteDeadline(deadline time.Time) error {
+	d.deadline = deadline
+	return nil
+}
+
+// hidingWriter response writer of a middleware that does not expose the connection
+type hidingWriter struct {
+	http.ResponseWriter
+}
+
+func TestExtendWriteDeadline(t *testing.T) {
+	recorder := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler := writeDeadlines(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, exposed := w.(interface{ SetWriteDeadline(time.Time) error })
+		assert.False(t, exposed)
+		extendWriteDeadline(r, time.Minute)
+	}))
+	hiding := func(w http.ResponseWriter, r *http.Request) { handler.ServeHTTP(hidingWriter{w}, r) }
+
+	started := time.Now()
+	writeDeadlines(http.HandlerFunc(hiding)).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/graph/stream", nil))
+
+	assert.WithinDuration(t, started.Add(time.Minute), recorder.deadline, time.Second)
+
+	// writers without deadlines are left alone
+	plain := httptest.NewRequest(http.MethodGet, "/api/graph/stream", nil)
+	writeDeadlines(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		extendWriteDeadline(r, time.Minute)
+	})).ServeHTTP(httptest.NewRecorder(), plain)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"AlexSarva/media/graphio"
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/storage/storagepg"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxImportSize upper limit of imported file size
+const maxImportSize = 10 << 20
+
+// ImportGraph - creation of a graph from user-supplied list of sources
+//
+// Handler POST /api/graph/import
+//
+// The handler is available only to authenticated users.
+// Request is multipart/form-data with fields:
+//
+//	file - CSV, GraphML or JSON with source URLs;
+//	format - csv, graphml or json, by default taken from the file extension;
+//	description, min_links, graph_id - optional graph card fields.
+//
+// Response contains created graph card, number of matched sources
+// and rows of the file that were not found in the link graph.
+//
+// Possible response codes:
+// 201 - graph is created;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is neither analyst nor admin;
+// 409 - graph_id already exists;
+// 422 - none of the sources is found, unmatched rows in response;
+// 500 - an internal server error.
+func ImportGraph(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(headerContentType, "multipart/form-data") {
+			messageResponse(w, "Content Type is not multipart/form-data", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+		if parseErr := r.ParseMultipartForm(maxImportSize); parseErr != nil {
+			messageResponse(w, "Bad Request. "+parseErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		file, fileHeader, fileErr := r.FormFile("file")
+		if fileErr != nil {
+			messageResponse(w, "Bad Request. "+fileErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		format := strings.ToLower(r.FormValue("format"))
+		if format == "" {
+			format = graphio.ImportFormat(fileHeader.Filename)
+		}
+
+		newGraph := models.NewGraph{
+			Description: r.FormValue("description"),
+			MinLinks:    models.DefaultMinLinks,
+			GraphID:     uuid.New(),
+			UserID:      userID,
+		}
+		if value := r.FormValue("min_links"); value != "" {
+			minLinks, minLinksErr := strconv.Atoi(value)
+			if minLinksErr != nil || minLinks <= 0 {
+				messageResponse(w, "Bad Request. min_links must be 0 (default) or positive", "application/json", http.StatusBadRequest)
+				return
+			}
+			newGraph.MinLinks = minLinks
+		}
+		if value := r.FormValue("graph_id"); value != "" {
+			graphID, graphIDErr := uuid.Parse(value)
+			if graphIDErr != nil {
+				messageResponse(w, "Bad Request. "+graphIDErr.Error(), "application/json", http.StatusBadRequest)
+				return
+			}
+			newGraph.GraphID = graphID
+		}
+
+		rows, rowsErr := graphio.ReadSources(file, format)
+		if rowsErr != nil {
+			messageResponse(w, "Bad Request. "+rowsErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+		if len(rows) > models.MaxImportSources {
+			messageResponse(w, "Bad Request. file has more than "+strconv.Itoa(models.MaxImportSources)+" sources", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		resp, respErr := database.Repo.ImportGraph(r.Context(), newGraph, rows)
+		status := http.StatusCreated
+		if respErr != nil {
+			switch {
+			case errors.Is(respErr, storagepg.ErrNoMatches):
+				status = http.StatusUnprocessableEntity
+			case errors.Is(respErr, storagepg.ErrDuplicatePK):
+				messageResponse(w, "GraphID already exists", "application/json", http.StatusConflict)
+				return
+			default:
+				log.Println(respErr)
+				messageResponse(w, "Internal Server Error: "+respErr.Error(), "application/json", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		importRes, importResErr := json.Marshal(resp)
+		if importResErr != nil {
+			panic(importResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(importRes)
+	}
+}
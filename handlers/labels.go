@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"AlexSarva/media/internal/app"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const (
+	// maxFolderLength upper bound of the length of folder name
+	maxFolderLength = 200
+	// maxTagLength upper bound of the length of one tag or label
+	maxTagLength = 50
+	// maxTags upper bound of the number of tags of one graph or labels of one node
+	maxTags = 20
+	// maxNoteLength upper bound of the length of note of a node
+	maxNoteLength = 2000
+)
+
+// cleanTags trims tags or labels named by field and drops empty and repeated ones,
+// the order of the first occurrence is kept
+func cleanTags(tags []string, field string) ([]string, error) {
+	cleaned := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if len(tag) == 0 || seen[tag] {
+			continue
+		}
+		if len([]rune(tag)) > maxTagLength {
+			return nil, fmt.Errorf("%s must be at most %d characters", field, maxTagLength)
+		}
+		seen[tag] = true
+		cleaned = append(cleaned, tag)
+	}
+	if len(cleaned) > maxTags {
+		return nil, fmt.Errorf("at most %d %ss are allowed", maxTags, field)
+	}
+	return cleaned, nil
+}
+
+// GetGraphLabels - folders and tags of saved graphs of the user
+//
+// Handler GET /api/graph/labels
+//
+// Graphs shared with the user are counted too.
+//
+// Possible response codes:
+// 200 - folders by name and tags by the number of graphs;
+// 401 - user not authenticated;
+// 500 - an internal server error.
+func GetGraphLabels(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Length")
+		if len(headerContentType) != 0 {
+			messageResponse(w, "Content-Length is not equal 0", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		labels, labelsErr := database.Repo.GetGraphLabels(r.Context(), userID)
+		if labelsErr != nil {
+			log.Println(labelsErr)
+			messageResponse(w, "Internal Server Error: "+labelsErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		labelsRes, labelsResErr := json.Marshal(labels)
+		if labelsResErr != nil {
+			panic(labelsResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(labelsRes)
+	}
+}
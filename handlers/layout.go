@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/storage/storagepg"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// SaveGraphLayout - storing of node positions of saved graph
+//
+// Handler PUT /api/graph/layout
+//
+// The handler is available to the owner and editors of the graph.
+// Nodes shown with the given parameters are laid out around the positions stored before, which are kept,
+// so the graph opens with the same layout every time. Reading the graph returns only stored positions.
+// Request format:
+//
+//	{"graph_id": "5b3a8c2e-...", "depth": 2, "min_links": 5}
+//
+// Possible response codes:
+// 200 - positions are stored, summary in response;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user has no edit access to the graph;
+// 404 - graph not found;
+// 500 - an internal server error.
+func SaveGraphLayout(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var query models.GraphUUID
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if paramsErr := validateGraphParams(query.GraphParams); paramsErr != nil {
+			messageResponse(w, "Bad Request. "+paramsErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		info, infoErr := database.Repo.SaveGraphLayout(r.Context(), userID, query.GraphID, query.GraphParams)
+		if infoErr != nil {
+			switch {
+			case errors.Is(infoErr, storagepg.ErrForbidden):
+				messageResponse(w, infoErr.Error(), "application/json", http.StatusForbidden)
+			case errors.Is(infoErr, sql.ErrNoRows):
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+			default:
+				log.Println(infoErr)
+				messageResponse(w, "Internal Server Error: "+infoErr.Error(), "application/json", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		infoRes, infoResErr := json.Marshal(info)
+		if infoResErr != nil {
+			panic(infoResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(infoRes)
+	}
+}
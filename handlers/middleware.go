@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"AlexSarva/media/admin"
+	"AlexSarva/media/models"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrRoleForbidden error that occurs when the role of the user is not allowed to the route
+var ErrRoleForbidden = errors.New("user role has no access to the route")
+
+// userKey key of request context holding the authenticated user
+type userKey struct{}
+
+// cachedUser user of the session and the time until it is trusted without database
+type cachedUser struct {
+	user    models.UserInfo
+	expires time.Time
+}
+
+// SessionCache users of active sessions, so authenticated requests do not go to database each time.
+// Logout and reuse of refresh token forget sessions of this instance at once, other instances see it after ttl
+type SessionCache struct {
+	ttl   time.Duration
+	mu    sync.Mutex
+	users map[uuid.UUID]cachedUser
+}
+
+// NewSessionCache cache of users of sessions for ttl, 0 disables the cache
+func NewSessionCache(ttl time.Duration) *SessionCache {
+	return &SessionCache{
+		ttl:   ttl,
+		users: make(map[uuid.UUID]cachedUser),
+	}
+}
+
+// Get user of the session if it is cached and not expired
+func (c *SessionCache) Get(sessionID uuid.UUID) (models.UserInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.users[sessionID]
+	if !ok || !time.Now().Before(cached.expires) {
+		return models.UserInfo{}, false
+	}
+	return cached.user, true
+}
+
+// Set keeps user of the session, expired sessions are dropped when the cache grows
+func (c *SessionCache) Set(sessionID uuid.UUID, user models.UserInfo) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.users) >= 1024 {
+		for id, cached := range c.users {
+			if !now.Before(cached.expires) {
+				delete(c.users, id)
+			}
+		}
+	}
+	c.users[sessionID] = cachedUser{user: user, expires: now.Add(c.ttl)}
+}
+
+// Forget drops the session
+func (c *SessionCache) Forget(sessionID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.users, sessionID)
+}
+
+// ForgetUser drops all sessions of the user
+func (c *SessionCache) ForgetUser(userID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, cached := range c.users {
+		if cached.user.ID == userID {
+			delete(c.users, id)
+		}
+	}
+}
+
+// SessionUsers database of users of sessions which are neither logged out nor expired
+type SessionUsers interface {
+	GetSessionUser(userID uuid.UUID, sessionID uuid.UUID) (*models.UserInfo, error)
+}
+
+// UserAuth middleware of authenticated routes, it requires Bearer token verified by TokenAuth,
+// checks that its session is not logged out and passes the user to handlers in request context
+func UserAuth(database SessionUsers, cache *SessionCache) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(claimsKey{}).(tokenClaims)
+			if !ok {
+				messageResponse(w, "User unauthorized: "+ErrNoAuth.Error(), "application/json", http.StatusUnauthorized)
+				return
+			}
+
+			user, cached := cache.Get(claims.sessionID)
+			if !cached {
+				userInfo, userInfoErr := database.GetSessionUser(claims.userID, claims.sessionID)
+				if userInfoErr != nil {
+					if errors.Is(userInfoErr, admin.ErrSessionRevoked) {
+						messageResponse(w, "User unauthorized: "+userInfoErr.Error(), "application/json", http.StatusUnauthorized)
+						return
+					}
+					messageResponse(w, "Internal Server Error: "+userInfoErr.Error(), "application/json", http.StatusInternalServerError)
+					return
+				}
+				user = *userInfo
+				cache.Set(claims.sessionID, user)
+			}
+
+			ctx := context.WithValue(r.Context(), userKey{}, user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireRole middleware of routes available to some roles only, it follows UserAuth
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := requestUser(r).Role
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			messageResponse(w, ErrRoleForbidden.Error(), "application/json", http.StatusForbidden)
+		})
+	}
+}
+
+// UserFromContext user authenticated by UserAuth
+func UserFromContext(ctx context.Context) (models.UserInfo, bool) {
+	user, ok := ctx.Value(userKey{}).(models.UserInfo)
+	return user, ok
+}
+
+// requestUser user of the request of authenticated route
+func requestUser(r *http.Request) models.UserInfo {
+	user, _ := UserFromContext(r.Context())
+	return user
+}
+
+// requestSession session of the request of authenticated route
+func requestSession(r *http.Request) uuid.UUID {
+	claims, _ := r.Context().Value(claimsKey{}).(tokenClaims)
+	return claims.sessionID
+}
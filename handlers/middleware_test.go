@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"AlexSarva/media/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionCache(t *testing.T) {
+	user := models.UserInfo{ID: uuid.New(), Email: "analyst@media.test", Role: models.RoleAnalyst}
+	other := models.UserInfo{ID: uuid.New(), Email: "viewer@media.test", Role: models.RoleViewer}
+	session, otherSession, secondSession := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		// change is applied to the cache holding session and secondSession of user and otherSession of other
+		change func(cache *SessionCache)
+		want   map[uuid.UUID]bool
+	}{
+		{
+			name:   "cached",
+			ttl:    time.Minute,
+			change: func(cache *SessionCache) {},
+			want:   map[uuid.UUID]bool{session: true, secondSession: true, otherSession: true},
+		},
+		{
+			name:   "disabled",
+			ttl:    0,
+			change: func(cache *SessionCache) {},
+			want:   map[uuid.UUID]bool{session: false, secondSession: false, otherSession: false},
+		},
+		{
+			name:   "expired",
+			ttl:    10 * time.Millisecond,
+			change: func(cache *SessionCache) { time.Sleep(20 * time.Millisecond) },
+			want:   map[uuid.UUID]bool{session: false, secondSession: false, otherSession: false},
+		},
+		{
+			name:   "forget session",
+			ttl:    time.Minute,
+			change: func(cache *SessionCache) { cache.Forget(session) },
+			want:   map[uuid.UUID]bool{session: false, secondSession: true, otherSession: true},
+		},
+		{
+			name:   "forget user",
+			ttl:    time.Minute,
+			change: func(cache *SessionCache) { cache.ForgetUser(user.ID) },
+			want:   map[uuid.UUID]bool{session: false, secondSession: false, otherSession: true},
+		},
+		{
+			name:   "forget unknown session",
+			ttl:    time.Minute,
+			change: func(cache *SessionCache) { cache.Forget(uuid.New()) },
+			want:   map[uuid.UUID]bool{session: true, secondSession: true, otherSession: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewSessionCache(tt.ttl)
+			cache.Set(session, user)
+			cache.Set(secondSession, user)
+			cache.Set(otherSession, other)
+
+			tt.change(cache)
+
+			for id, want := range tt.want {
+				got, ok := cache.Get(id)
+				assert.Equal(t, want, ok, "session %s", id)
+				if want {
+					assert.NotEqual(t, uuid.Nil, got.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionCacheDropsExpired(t *testing.T) {
+	cache := NewSessionCache(10 * time.Millisecond)
+	user := models.UserInfo{ID: uuid.New()}
+	for i := 0; i < 1024; i++ {
+		cache.Set(uuid.New(), user)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	fresh := uuid.New()
+	cache.Set(fresh, user)
+
+	assert.Len(t, cache.users, 1)
+	_, ok := cache.Get(fresh)
+	assert.True(t, ok)
+}
+
+func TestSessionCacheRefreshesTTL(t *testing.T) {
+	cache := NewSessionCache(40 * time.Millisecond)
+	session := uuid.New()
+	cache.Set(session, models.UserInfo{ID: uuid.New(), Role: models.RoleAnalyst})
+	time.Sleep(25 * time.Millisecond)
+	cache.Set(session, models.UserInfo{ID: uuid.New(), Role: models.RoleAdmin})
+	time.Sleep(25 * time.Millisecond)
+
+	user, ok := cache.Get(session)
+	assert.True(t, ok)
+	assert.Equal(t, models.RoleAdmin, user.Role)
+}
@@ -70,7 +70,7 @@ package handlers
 //		}
 //
 //		orderNumStr := fmt.Sprintf("%d", orderNum)
-//		orderDB, orderDBErr := database.Repo.CheckOrder(orderNumStr)
+//		orderDB, orderDBErr := database.Repo.CheckOrder(r.Context(), orderNumStr)
 //		if orderDBErr != nil {
 //			messageResponse(w, "Internal Server Error: "+orderDBErr.Error(), "application/json", http.StatusInternalServerError)
 //			return
@@ -89,7 +89,7 @@ package handlers
 //		var order models.Order
 //		order.UserID, order.OrderNum = userID, orderNumStr
 //
-//		insertErr := database.Repo.NewOrder(&order)
+//		insertErr := database.Repo.NewOrder(r.Context(), &order)
 //
 //		if insertErr != nil {
 //			messageResponse(w, "Internal Server Error: "+insertErr.Error(), "application/json", http.StatusInternalServerError)
@@ -152,7 +152,7 @@ package handlers
 //			return
 //		}
 //
-//		orders, ordersErr := database.Repo.GetOrders(userID)
+//		orders, ordersErr := database.Repo.GetOrders(r.Context(), userID)
 //		if ordersErr != nil {
 //			if ordersErr == storagepg.ErrNoValues {
 //				w.Header().Set("Content-Type", "application/json")
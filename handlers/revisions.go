@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/storage/storagepg"
+	"AlexSarva/media/styling"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// GetGraphRevisions - list of revisions of saved graph
+//
+// Handler POST /api/graph/revisions
+//
+// The handler is available to every user the graph is shared with.
+// Request format:
+//
+//	{"graph_id": "5b3a8c2e-..."}
+//
+// Possible response codes:
+// 200 - revisions, the latest first;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user has no access to the graph;
+// 404 - graph not found;
+// 500 - an internal server error.
+func GetGraphRevisions(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var query models.GraphRef
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		revisions, revisionsErr := database.Repo.GetGraphRevisions(r.Context(), userID, query.GraphID)
+		if revisionsErr != nil {
+			if errors.Is(revisionsErr, storagepg.ErrForbidden) {
+				messageResponse(w, revisionsErr.Error(), "application/json", http.StatusForbidden)
+				return
+			}
+			if errors.Is(revisionsErr, sql.ErrNoRows) {
+				messageResponse(w, "graph not found", "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(revisionsErr)
+			messageResponse(w, "Internal Server Error: "+revisionsErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		revisionsRes, revisionsResErr := json.Marshal(revisions)
+		if revisionsResErr != nil {
+			panic(revisionsResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(revisionsRes)
+	}
+}
+
+// DiffGraphRevisions - difference between graphs of two revisions of saved graph
+//
+// Handler POST /api/graph/revisions/diff
+//
+// The handler is available to every user the graph is shared with.
+// Both graphs are built as in /api/graph/uuid with the threshold saved in each revision
+// unless min_links is set. Request format:
+//
+//	{"graph_id": "5b3a8c2e-...", "from": 1, "to": 3}
+//
+// Possible response codes:
+// 200 - sources, nodes and edges added and removed between revisions;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user has no access to the graph;
+// 404 - graph or revision not found;
+// 500 - an internal server error.
+func DiffGraphRevisions(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var query models.GraphDiffQuery
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		style, styleErr := graphStyle(r, styling.RuleKind)
+		if styleErr != nil {
+			messageResponse(w, "Bad Request. "+styleErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		if paramsErr := validateGraphParams(query.GraphParams); paramsErr != nil {
+			messageResponse(w, "Bad Request. "+paramsErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		diff, diffErr := database.Repo.DiffGraphRevisions(r.Context(), userID, query)
+		if diffErr != nil {
+			if errors.Is(diffErr, storagepg.ErrForbidden) {
+				messageResponse(w, diffErr.Error(), "application/json", http.StatusForbidden)
+				return
+			}
+			if errors.Is(diffErr, sql.ErrNoRows) || errors.Is(diffErr, storagepg.ErrNoRevision) {
+				messageResponse(w, diffErr.Error(), "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(diffErr)
+			messageResponse(w, "Internal Server Error: "+diffErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		style.Apply(diff.AddedNodes)
+		style.Apply(diff.RemovedNodes)
+
+		diffRes, diffResErr := json.Marshal(diff)
+		if diffResErr != nil {
+			panic(diffResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(diffRes)
+	}
+}
+
+// RestoreGraphRevision - return of saved graph to one of its revisions
+//
+// Handler POST /api/graph/revisions/restore
+//
+// The handler is available to the owner and editors of the graph.
+// Name, description, threshold and sources of the revision become current,
+// the restore itself is kept as a new revision. Request format:
+//
+//	{"graph_id": "5b3a8c2e-...", "revision": 2}
+//
+// Possible response codes:
+// 200 - graph is restored, graph card in response;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user has no access to the graph;
+// 404 - graph or revision not found;
+// 500 - an internal server error.
+func RestoreGraphRevision(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var query models.GraphRevisionQuery
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		card, cardErr := database.Repo.RestoreGraphRevision(r.Context(), userID, query.GraphID, query.Revision)
+		if cardErr != nil {
+			if errors.Is(cardErr, storagepg.ErrForbidden) {
+				messageResponse(w, cardErr.Error(), "application/json", http.StatusForbidden)
+				return
+			}
+			if errors.Is(cardErr, storagepg.ErrNoData) || errors.Is(cardErr, storagepg.ErrNoRevision) {
+				messageResponse(w, cardErr.Error(), "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(cardErr)
+			messageResponse(w, "Internal Server Error: "+cardErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		cardRes, cardResErr := json.Marshal(card)
+		if cardResErr != nil {
+			panic(cardResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(cardRes)
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"AlexSarva/media/admin"
+	"AlexSarva/media/crypto"
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubSessions users of active sessions by session id
+type stubSessions map[uuid.UUID]models.UserInfo
+
+func (s stubSessions) GetSessionUser(userID uuid.UUID, sessionID uuid.UUID) (*models.UserInfo, error) {
+	user, ok := s[sessionID]
+	if !ok || user.ID != userID {
+		return &models.UserInfo{}, admin.ErrSessionRevoked
+	}
+	return &user, nil
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name  string
+		role  string
+		roles []string
+		want  int
+	}{
+		{name: "admin of admin route", role: models.RoleAdmin, roles: []string{models.RoleAdmin}, want: http.StatusOK},
+		{name: "analyst of admin route", role: models.RoleAnalyst, roles: []string{models.RoleAdmin}, want: http.StatusForbidden},
+		{name: "analyst of analyst route", role: models.RoleAnalyst, roles: []string{models.RoleAdmin, models.RoleAnalyst}, want: http.StatusOK},
+		{name: "viewer of analyst route", role: models.RoleViewer, roles: []string{models.RoleAdmin, models.RoleAnalyst}, want: http.StatusForbidden},
+		{name: "user without role", role: "", roles: []string{models.RoleViewer}, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireRole(tt.roles...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request = request.WithContext(context.WithValue(request.Context(), userKey{}, models.UserInfo{ID: uuid.New(), Role: tt.role}))
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.want, recorder.Code)
+		})
+	}
+}
+
+func TestRouteAccess(t *testing.T) {
+	keys, keysErr := crypto.NewKeyring("test:0123456789abcdef")
+	if !assert.NoError(t, keysErr) {
+		return
+	}
+	sessions := stubSessions{}
+	tokens := make(map[string]string)
+	for _, role := range []string{models.RoleAdmin, models.RoleAnalyst, models.RoleViewer} {
+		user := models.UserInfo{ID: uuid.New(), Role: role}
+		sessionID := uuid.New()
+		sessions[sessionID] = user
+		tokens[role], _ = GenerateToken(keys, user.ID, sessionID, time.Minute)
+	}
+	cfg := &models.Config{WriteTimeout: time.Minute}
+	router := newRouter(cfg, &app.Database{Repo: &stubRepo{}}, nil, sessions, keys)
+
+	analysts := []string{models.RoleAdmin, models.RoleAnalyst}
+	everyone := []string{models.RoleAdmin, models.RoleAnalyst, models.RoleViewer}
+	admins := []string{models.RoleAdmin}
+	tests := []struct {
+		method string
+		path   string
+		roles  []string
+	}{
+		{http.MethodGet, "/api/graph", analysts},
+		{http.MethodGet, "/api/graph/stream", analysts},
+		{http.MethodPost, "/api/graph/url", analysts},
+		{http.MethodPost, "/api/graph/path", analysts},
+		{http.MethodPost, "/api/graph/new", analysts},
+		{http.MethodPost, "/api/graph/import", analysts},
+		{http.MethodPatch, "/api/graph/sources/add", analysts},
+		{http.MethodPost, "/api/graph/combine", analysts},
+		{http.MethodPost, "/api/graph/clone", analysts},
+		{http.MethodPost, "/api/search", analysts},
+		{http.MethodGet, "/api/graph/all", everyone},
+		{http.MethodPost, "/api/graph/uuid", everyone},
+		{http.MethodPost, "/api/graph/export", everyone},
+		{http.MethodPatch, "/api/graph/sources/remove", everyone},
+		{http.MethodPost, "/api/graph/compare", everyone},
+		{http.MethodPut, "/api/graph/annotations", everyone},
+		{http.MethodPost, "/api/analytics/metrics", admins},
+		{http.MethodGet, "/api/admin/users?role=nobody", admins},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			for _, role := range everyone {
+				// requests are malformed so allowed ones stop at validation before storage
+				request := httptest.NewRequest(tt.method, tt.path, nil)
+				request.Header.Set("Content-Type", "text/plain")
+				request.Header.Set("Content-Length", "1")
+				request.Header.Set("Authorization", "Bearer "+tokens[role])
+				recorder := httptest.NewRecorder()
+
+				router.ServeHTTP(recorder, request)
+
+				if contains(tt.roles, role) {
+					assert.Equal(t, http.StatusBadRequest, recorder.Code, "%s: %s", role, recorder.Body.String())
+				} else {
+					assert.Equal(t, http.StatusForbidden, recorder.Code, "%s: %s", role, recorder.Body.String())
+				}
+			}
+
+			anonymous := httptest.NewRequest(tt.method, tt.path, nil)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, anonymous)
+			assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		})
+	}
+}
+
+// contains the role is one of roles
+func contains(roles []string, role string) bool {
+	for _, allowed := range roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
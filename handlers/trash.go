@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/storage/storagepg"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GetDeletedGraphCards - list of graphs in trash of the user
+//
+// Handler GET /api/graph/trash
+//
+// Cards contain the time of deletion and, when retention is on,
+// the time when the graph is purged permanently.
+//
+// Possible response codes:
+// 200 - list of deleted graphs;
+// 401 - user not authenticated;
+// 500 - an internal server error.
+func GetDeletedGraphCards(database *app.Database, retention time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Length")
+		if len(headerContentType) != 0 {
+			messageResponse(w, "Content-Length is not equal 0", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		graphCards, graphCardsErr := database.Repo.GetDeletedGraphCards(r.Context(), userID)
+		if graphCardsErr != nil {
+			messageResponse(w, "Internal Server Error: "+graphCardsErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+		if retention > 0 {
+			for i := range graphCards {
+				purgeAt := graphCards[i].Deleted.Add(retention)
+				graphCards[i].PurgeAt = &purgeAt
+			}
+		}
+
+		jsonResp, _ := json.Marshal(graphCards)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(jsonResp)
+	}
+}
+
+// RestoreGraphCard - return of deleted graph from trash
+//
+// Handler POST /api/graph/trash/restore
+//
+// Request format:
+//
+//	{"graph_id": "5b3a8c2e-..."}
+//
+// Possible response codes:
+// 200 - graph is restored, graph card in response;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 404 - graph is not in trash;
+// 500 - an internal server error.
+func RestoreGraphCard(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var ref models.GraphRef
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&ref)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		card, cardErr := database.Repo.RestoreGraphCard(r.Context(), userID, ref.GraphID)
+		if cardErr != nil {
+			if errors.Is(cardErr, storagepg.ErrNoData) {
+				messageResponse(w, "graph is not in trash", "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(cardErr)
+			messageResponse(w, "Internal Server Error: "+cardErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		cardRes, cardResErr := json.Marshal(card)
+		if cardResErr != nil {
+			panic(cardResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(cardRes)
+	}
+}
+
+// PurgeGraphCard - permanent deletion of graph from trash
+//
+// Handler DELETE /api/graph/trash/purge
+//
+// Request format:
+//
+//	{"graph_id": "5b3a8c2e-..."}
+//
+// Possible response codes:
+// 204 - graph is deleted permanently;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 404 - graph is not in trash;
+// 500 - an internal server error.
+func PurgeGraphCard(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID := requestUser(r).ID
+
+		var ref models.GraphRef
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&ref)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		purgeErr := database.Repo.PurgeGraphCard(r.Context(), userID, ref.GraphID)
+		if purgeErr != nil {
+			if errors.Is(purgeErr, storagepg.ErrNoData) {
+				messageResponse(w, "graph is not in trash", "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(purgeErr)
+			messageResponse(w, "Internal Server Error: "+purgeErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"AlexSarva/media/admin"
+	"AlexSarva/media/graphio"
+	"AlexSarva/media/internal/app"
+	"AlexSarva/media/models"
+	"AlexSarva/media/storage/storagepg"
+	"AlexSarva/media/styling"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ErrSelfChange error that occurs when admin changes role or disables own account
+var ErrSelfChange = errors.New("admins cannot change their own role or disable themselves")
+
+// ListUsers - list of registered users
+//
+// Handler GET /api/admin/users?role=viewer&q=smith&limit=100&offset=0
+//
+// The handler is available only to admins.
+// Users are ordered by registration, role filters by role, q searches in username and email.
+// Total number of users matching the filter is returned in X-Total-Count header.
+//
+// Possible response codes:
+// 200 - users;
+// 400 - invalid query;
+// 401 - user not authenticated;
+// 403 - user is not admin;
+// 500 - an internal server error.
+func ListUsers(database *admin.PostgresDB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := models.UsersQuery{
+			Role:   r.URL.Query().Get("role"),
+			Search: strings.TrimSpace(r.URL.Query().Get("q")),
+		}
+		if len(query.Role) != 0 && !validRole(query.Role) {
+			messageResponse(w, "Bad Request. role must be one of "+strings.Join(roles, ", "), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		var limitErr, offsetErr error
+		query.Limit, limitErr = queryInt(r, "limit")
+		if limitErr != nil {
+			messageResponse(w, "Bad Request. "+limitErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+		if query.Limit < 0 || query.Limit > models.MaxUsersPage {
+			messageResponse(w, fmt.Sprintf("Bad Request. limit must be between 0 and %d", models.MaxUsersPage), "application/json", http.StatusBadRequest)
+			return
+		}
+		query.Offset, offsetErr = queryInt(r, "offset")
+		if offsetErr != nil {
+			messageResponse(w, "Bad Request. "+offsetErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+		if query.Offset < 0 {
+			messageResponse(w, "Bad Request. offset must not be negative", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		users, total, usersErr := database.ListUsers(query)
+		if usersErr != nil {
+			log.Println(usersErr)
+			messageResponse(w, "Internal Server Error: "+usersErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		usersRes, usersResErr := json.Marshal(users)
+		if usersResErr != nil {
+			panic(usersResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		w.WriteHeader(http.StatusOK)
+		w.Write(usersRes)
+	}
+}
+
+// SetUserRole - change of the role of the user
+//
+// Handler PATCH /api/admin/users/role
+//
+// The handler is available only to admins, the new role applies to the next request of the user.
+// Request format, role is admin, analyst or viewer:
+//
+//	{"user_id": "5b3a8c2e-...", "role": "analyst"}
+//
+// Possible response codes:
+// 200 - role is changed, user in response;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is not admin;
+// 404 - user not found;
+// 409 - admin changes own role;
+// 500 - an internal server error.
+func SetUserRole(database *admin.PostgresDB, cache *SessionCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		var update models.UserRoleUpdate
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&update)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if !validRole(update.Role) {
+			messageResponse(w, "Bad Request. role must be one of "+strings.Join(roles, ", "), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		if update.UserID == requestUser(r).ID {
+			messageResponse(w, ErrSelfChange.Error(), "application/json", http.StatusConflict)
+			return
+		}
+
+		user, userErr := database.SetUserRole(update.UserID, update.Role)
+		if userErr != nil {
+			if errors.Is(userErr, sql.ErrNoRows) {
+				messageResponse(w, "user not found", "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(userErr)
+			messageResponse(w, "Internal Server Error: "+userErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+		cache.ForgetUser(update.UserID)
+
+		userRes, userResErr := json.Marshal(user)
+		if userResErr != nil {
+			panic(userResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(userRes)
+	}
+}
+
+// DisableUser - disabling or enabling of the account of the user
+//
+// Handler PATCH /api/admin/users/disable
+//
+// The handler is available only to admins.
+// Disabled user cannot log in and all the sessions of the user are logged out. Request format:
+//
+//	{"user_id": "5b3a8c2e-...", "disabled": true}
+//
+// Possible response codes:
+// 200 - account is disabled or enabled, user in response;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is not admin;
+// 404 - user not found;
+// 409 - admin disables own account;
+// 500 - an internal server error.
+func DisableUser(database *admin.PostgresDB, cache *SessionCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		var update models.UserDisable
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&update)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if update.UserID == requestUser(r).ID {
+			messageResponse(w, ErrSelfChange.Error(), "application/json", http.StatusConflict)
+			return
+		}
+
+		user, userErr := database.SetUserDisabled(update.UserID, update.Disabled)
+		if userErr != nil {
+			if errors.Is(userErr, sql.ErrNoRows) {
+				messageResponse(w, "user not found", "application/json", http.StatusNotFound)
+				return
+			}
+			log.Println(userErr)
+			messageResponse(w, "Internal Server Error: "+userErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+		cache.ForgetUser(update.UserID)
+
+		userRes, userResErr := json.Marshal(user)
+		if userResErr != nil {
+			panic(userResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(userRes)
+	}
+}
+
+// GetUserGraphCards - saved graphs of any user
+//
+// Handler GET /api/admin/users/{user_id}/graphs
+//
+// The handler is available only to admins.
+// Graphs of the user and graphs shared with the user are filtered, sorted and paginated as in /api/graph/all.
+//
+// Possible response codes:
+// 200 - graph cards of the user;
+// 400 - invalid user id or query;
+// 401 - user not authenticated;
+// 403 - user is not admin;
+// 500 - an internal server error.
+func GetUserGraphCards(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, userIDErr := uuid.Parse(chi.URLParam(r, "user_id"))
+		if userIDErr != nil {
+			messageResponse(w, "Bad Request. user_id must be uuid", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		query, queryErr := graphCardsQuery(r)
+		if queryErr != nil {
+			messageResponse(w, "Bad Request. "+queryErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		graphCards, total, graphCardsErr := database.Repo.GetGraphCards(r.Context(), userID, query)
+		if graphCardsErr != nil {
+			messageResponse(w, "Internal Server Error: "+graphCardsErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		jsonResp, _ := json.Marshal(graphCards)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		w.WriteHeader(http.StatusOK)
+		w.Write(jsonResp)
+	}
+}
+
+// GetUserGraph - saved graph of any user
+//
+// Handler POST /api/admin/users/{user_id}/graph
+//
+// The handler is available only to admins.
+// The graph is built as in /api/graph/uuid for the user, so it must be owned by or shared with the user.
+// Request format:
+//
+//	{"graph_id": "5b3a8c2e-..."}
+//
+// Possible response codes:
+// 200 - graph;
+// 204 - graph not found;
+// 400 - invalid request format;
+// 401 - user not authenticated;
+// 403 - user is not admin or the graph is not available to the user;
+// 406 - requested format is not supported;
+// 500 - an internal server error.
+func GetUserGraph(database *app.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		headerContentType := r.Header.Get("Content-Type")
+		if !strings.Contains("application/json, application/x-gzip", headerContentType) {
+			messageResponse(w, "Content Type is not application/json or application/x-gzip", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		userID, userIDErr := uuid.Parse(chi.URLParam(r, "user_id"))
+		if userIDErr != nil {
+			messageResponse(w, "Bad Request. user_id must be uuid", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		format, formatErr := graphFormat(r)
+		if formatErr != nil {
+			messageResponse(w, "Not Acceptable. "+formatErr.Error(), "application/json", http.StatusNotAcceptable)
+			return
+		}
+
+		style, styleErr := graphStyle(r, styling.RuleKind)
+		if styleErr != nil {
+			messageResponse(w, "Bad Request. "+styleErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		var query models.GraphUUID
+		var unmarshalErr *json.UnmarshalTypeError
+
+		b, err := readBodyBytes(r)
+		if err != nil {
+			messageResponse(w, "Problem in body", "application/json", http.StatusBadRequest)
+			return
+		}
+
+		decoder := json.NewDecoder(b)
+		decoder.DisallowUnknownFields()
+		errDecode := decoder.Decode(&query)
+
+		if errDecode != nil {
+			if errors.As(errDecode, &unmarshalErr) {
+				messageResponse(w, "Bad Request. Wrong Type provided for field "+unmarshalErr.Field, "application/json", http.StatusBadRequest)
+			} else {
+				messageResponse(w, "Bad Request. "+errDecode.Error(), "application/json", http.StatusBadRequest)
+			}
+			return
+		}
+
+		if paramsErr := validateGraphParams(query.GraphParams); paramsErr != nil {
+			messageResponse(w, "Bad Request. "+paramsErr.Error(), "application/json", http.StatusBadRequest)
+			return
+		}
+
+		graphInfo, graphInfoErr := database.Repo.GetGraphByUUID(r.Context(), userID, query.GraphID, query.GraphParams)
+		if graphInfoErr != nil {
+			if errors.Is(graphInfoErr, storagepg.ErrForbidden) {
+				messageResponse(w, graphInfoErr.Error(), "application/json", http.StatusForbidden)
+				return
+			}
+			if errors.Is(graphInfoErr, sql.ErrNoRows) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			messageResponse(w, "Internal Server Error: "+graphInfoErr.Error(), "application/json", http.StatusInternalServerError)
+			return
+		}
+
+		style.ApplyExtended(&graphInfo)
+		if format != graphio.FormatJSON {
+			writeGraphFile(w, format, graphInfo.Nodes, graphInfo.Edges)
+			return
+		}
+
+		graphRes, graphResErr := json.Marshal(graphInfo)
+		if graphResErr != nil {
+			panic(graphResErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(graphRes)
+	}
+}
+
+// roles roles of users in the order of listing in errors
+var roles = []string{models.RoleAdmin, models.RoleAnalyst, models.RoleViewer}
+
+// validRole role is one of the known roles
+func validRole(role string) bool {
+	for _, known := range roles {
+		if role == known {
+			return true
+		}
+	}
+	return false
+}
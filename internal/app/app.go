@@ -10,7 +10,10 @@ import (
 
 // Database interface for different types of databases
 type Database struct {
-	Repo storage.Repo
+	Repo        storage.Repo
+	metrics     analyticsJob[models.MetricsInfo]
+	communities analyticsJob[models.CommunitiesInfo]
+	layout      analyticsJob[models.LayoutInfo]
 }
 
 // NewStorage generate new instance of database
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"AlexSarva/media/models"
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// analyticsJob state of a recalculation over the full graph,
+// only one recalculation of a kind runs at a time
+type analyticsJob[T any] struct {
+	mu     sync.Mutex
+	status models.AnalyticsJob[T]
+}
+
+// start starts run in background and returns the state of the job,
+// started is false when a recalculation is already running
+func (j *analyticsJob[T]) start(name string, minLinks int, run func(ctx context.Context, minLinks int) (T, error)) (job models.AnalyticsJob[T], started bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status.Running {
+		return j.status, false
+	}
+	j.status.Running = true
+	j.status.MinLinks = minLinks
+	j.status.Started = time.Now()
+	j.status.Error = ""
+	go j.run(name, minLinks, run)
+	return j.status, true
+}
+
+// state state of the last or running recalculation
+func (j *analyticsJob[T]) state() models.AnalyticsJob[T] {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// run runs the recalculation and records the result
+func (j *analyticsJob[T]) run(name string, minLinks int, run func(ctx context.Context, minLinks int) (T, error)) {
+	info, err := run(context.Background(), minLinks)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Running = false
+	if err != nil {
+		log.Printf("%s: %v", name, err)
+		j.status.Error = err.Error()
+		return
+	}
+	j.status.Last = &info
+}
+
+// StartMetrics starts recalculation of centrality metrics in background
+// and returns its state, started is false when a recalculation is already running
+func (d *Database) StartMetrics(minLinks int) (job models.MetricsJob, started bool) {
+	return d.metrics.start("metrics", minLinks, d.Repo.CalculateMetrics)
+}
+
+// MetricsStatus state of the last or running recalculation of centrality metrics
+func (d *Database) MetricsStatus() models.MetricsJob {
+	return d.metrics.state()
+}
+
+// StartCommunities starts community detection in background
+// and returns its state, started is false when a detection is already running
+func (d *Database) StartCommunities(minLinks int) (job models.CommunitiesJob, started bool) {
+	return d.communities.start("communities", minLinks, d.Repo.DetectCommunities)
+}
+
+// CommunitiesStatus state of the last or running community detection
+func (d *Database) CommunitiesStatus() models.CommunitiesJob {
+	return d.communities.state()
+}
+
+// StartLayout starts layout of the full graph in background
+// and returns its state, started is false when a layout is already running
+func (d *Database) StartLayout(minLinks int) (job models.LayoutJob, started bool) {
+	return d.layout.start("layout", minLinks, d.Repo.CalculateLayout)
+}
+
+// LayoutStatus state of the last or running layout of the full graph
+func (d *Database) LayoutStatus() models.LayoutJob {
+	return d.layout.state()
+}
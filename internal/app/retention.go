@@ -0,0 +1,35 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// retentionInterval how often deleted graphs are checked for purge
+const retentionInterval = time.Hour
+
+// RunRetention permanently deletes graphs that stay in trash longer than retention
+// until ctx is done, retention 0 turns purge off
+func (d *Database) RunRetention(ctx context.Context, retention time.Duration) {
+	if retention <= 0 {
+		log.Println("graph retention is off")
+		return
+	}
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+	for {
+		purged, err := d.Repo.PurgeDeletedGraphs(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("graph retention: %v", err)
+		} else if purged > 0 {
+			log.Printf("graph retention: %d graphs purged", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
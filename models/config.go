@@ -1,8 +1,34 @@
 package models
 
+import "time"
+
 // Config  start parameters for lunch the service
 type Config struct {
-	ServerAddress string `env:"RUN_ADDRESS" envDefault:"localhost:8080"`
-	DatabasePG    string `env:"DATABASE_PG_URI"`
-	DatabaseClick string `env:"DATABASE_Click_URI"`
+	ServerAddress string        `env:"RUN_ADDRESS" envDefault:"localhost:8080"`
+	DatabasePG    string        `env:"DATABASE_PG_URI"`
+	DatabaseClick string        `env:"DATABASE_Click_URI"`
+	WriteTimeout  time.Duration `env:"WRITE_TIMEOUT" envDefault:"60s"`
+	// GraphRetention how long deleted graphs stay in trash, 0 keeps them forever
+	GraphRetention time.Duration `env:"GRAPH_RETENTION" envDefault:"720h"`
+	// AccessTokenTTL lifetime of access tokens, RefreshTokenTTL lifetime of refresh tokens
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+	// AuthCacheTTL how long users of sessions are cached by the auth middleware,
+	// logout on other instances of the service is seen after this time
+	AuthCacheTTL time.Duration `env:"AUTH_CACHE_TTL" envDefault:"30s"`
+	// AdminEmails emails of registered users who get admin role when the service starts
+	AdminEmails []string `env:"ADMIN_EMAILS" envSeparator:","`
+	// SigningKeys keys of tokens in format "kid:secret,kid:secret", the first one signs new tokens
+	SigningKeys Secret `env:"SIGNING_KEYS"`
+}
+
+// Secret setting that is hidden when the config is printed
+type Secret string
+
+// String hides the value of the secret
+func (s Secret) String() string {
+	if len(s) == 0 {
+		return ""
+	}
+	return "***"
 }
@@ -4,14 +4,92 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const (
+	// DefaultGraphDepth number of hops taken from the requested sources when depth is not set
+	DefaultGraphDepth = 1
+	// MaxGraphDepth upper bound of hops for ego graph expansion
+	MaxGraphDepth = 3
+	// DefaultGraphNodes node cap applied when max_nodes is not set
+	DefaultGraphNodes = 500
+	// MaxGraphNodes upper bound of nodes in one ego graph
+	MaxGraphNodes = 5000
+	// DefaultMinLinks minimal number of forwarded links for an edge to be shown
+	DefaultMinLinks = 5
+	// DefaultPathHops hop limit of path search when max_hops is not set
+	DefaultPathHops = 4
+	// MaxPathHops upper bound of hops of path search
+	MaxPathHops = 6
+	// PathsLimit number of the shortest paths returned by path search
+	PathsLimit = 5
+)
+
+const (
+	// DefaultGraphPage number of nodes or edges in one page of the full graph
+	DefaultGraphPage = 5000
+	// MaxGraphPage upper bound of the page size of the full graph
+	MaxGraphPage = 50000
+)
+
+// Phases of full graph pagination, nodes are paginated first
+const (
+	// CursorNodes cursor points into the nodes of the full graph
+	CursorNodes = "n"
+	// CursorEdges cursor points into the edges of the full graph
+	CursorEdges = "e"
+)
+
+// Metrics that can drive the size of nodes
+const (
+	// SizeByLinks size by the summed number of forwarded links from the ETL
+	SizeByLinks = "links"
+	// SizeByPageRank size by weighted PageRank
+	SizeByPageRank = "pagerank"
+	// SizeByBetweenness size by weighted betweenness centrality
+	SizeByBetweenness = "betweenness"
+	// SizeByInDegree size by the number of incoming links
+	SizeByInDegree = "in_degree"
+	// SizeByOutDegree size by the number of outgoing links
+	SizeByOutDegree = "out_degree"
+)
+
+// Directions of edges followed by graph expansion
+const (
+	// DirectionOut follows edges from a source to the channels it forwards
+	DirectionOut = "out"
+	// DirectionIn follows edges from the channels forwarding a source
+	DirectionIn = "in"
+	// DirectionBoth follows edges in both directions
+	DirectionBoth = "both"
+)
+
+// GraphParams options of ego graph expansion shared by all graph queries
+type GraphParams struct {
+	Depth     int    `json:"depth"`
+	MaxNodes  int    `json:"max_nodes"`
+	Direction string `json:"direction"`
+	MinLinks  int    `json:"min_links"`
+	SizeBy    string `json:"size_by"`
+}
+
 type GraphQuery struct {
 	Query string `json:"query"`
+	GraphParams
 }
 
 type GraphQueryID struct {
 	ID int `json:"query"`
+	GraphParams
+}
+
+// PathQuery request of paths between two sources
+type PathQuery struct {
+	From     int `json:"from"`
+	To       int `json:"to"`
+	MaxHops  int `json:"max_hops"`
+	MinLinks int `json:"min_links"`
 }
 
 type DataForGraph struct {
@@ -34,12 +112,32 @@ type GraphNodeColor struct {
 	Hover      GraphNodeColorStyle `json:"hover,omitempty"`
 }
 
+// Kinds of nodes in returned graphs, styles pick colours by them
+const (
+	// NodeKindMain requested or saved source
+	NodeKindMain = "main"
+	// NodeKindSub neighbour found by expansion
+	NodeKindSub = "sub"
+	// NodeKindPath intermediate source of the shortest path
+	NodeKindPath = "path"
+	// NodeKindIsolated requested source without any links
+	NodeKindIsolated = "isolated"
+)
+
 type GraphNode struct {
-	ID    int64       `json:"id" db:"id"`
-	Label string      `json:"title" db:"url"`
-	Title string      `json:"label" db:"title"`
-	Color interface{} `json:"color,omitempty"`
-	Value int32       `json:"value" db:"links"`
+	ID          int64       `json:"id" db:"id"`
+	Label       string      `json:"title" db:"url"`
+	Title       string      `json:"label" db:"title"`
+	Color       interface{} `json:"color,omitempty"`
+	Kind        string      `json:"kind,omitempty" db:"-"`
+	Value       float64     `json:"value" db:"links"`
+	Community   *int        `json:"community,omitempty" db:"community"`
+	PageRank    *float64    `json:"pagerank,omitempty" db:"pagerank"`
+	Betweenness *float64    `json:"betweenness,omitempty" db:"betweenness"`
+	InDegree    *int64      `json:"in_degree,omitempty" db:"in_degree"`
+	OutDegree   *int64      `json:"out_degree,omitempty" db:"out_degree"`
+	X           *float64    `json:"x,omitempty" db:"x"`
+	Y           *float64    `json:"y,omitempty" db:"y"`
 }
 
 type NodeDescription struct {
@@ -48,21 +146,78 @@ type NodeDescription struct {
 }
 
 type GraphEdge struct {
-	From int64 `json:"from" db:"id_from"`
-	To   int64 `json:"to" db:"id_to"`
-	//Value  int32 `json:"value" db:"links"`
-	Dashes bool `json:"dashes"`
+	From      int64  `json:"from" db:"id_from"`
+	To        int64  `json:"to" db:"id_to"`
+	Value     int32  `json:"value" db:"links"`
+	Dashes    bool   `json:"dashes"`
+	Direction string `json:"direction,omitempty"`
+}
+
+// GraphPath chain of node ids found by path search
+// weight is the sum of 1/links over the edges of the path
+type GraphPath struct {
+	Nodes  []int64 `json:"nodes"`
+	Hops   int     `json:"hops"`
+	Weight float64 `json:"weight"`
 }
 
 type Graph struct {
 	Nodes []GraphNode `json:"nodes"`
 	Edges []GraphEdge `json:"edges"`
+	Paths []GraphPath `json:"paths,omitempty"`
+	// PathsTruncated path search hit its limit or the node cap, paths are the shortest found but may miss shorter ones
+	PathsTruncated bool `json:"paths_truncated,omitempty"`
+}
+
+// GraphCursor position in the full graph after the last returned row,
+// From is the node id for the nodes phase and (From, To) is the edge for the edges phase
+type GraphCursor struct {
+	Phase string
+	From  int64
+	To    int64
+}
+
+// GraphPage part of the full graph, NextCursor is empty on the last page
+type GraphPage struct {
+	Nodes      []GraphNode  `json:"nodes"`
+	Edges      []GraphEdge  `json:"edges"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	Next       *GraphCursor `json:"-"`
 }
 
 type GraphExtended struct {
-	Nodes     []GraphNode `json:"nodes"`
-	Edges     []GraphEdge `json:"edges"`
-	NodesList []GraphNode `json:"nodes_list"`
+	Nodes       []GraphNode       `json:"nodes"`
+	Edges       []GraphEdge       `json:"edges"`
+	NodesList   []GraphNode       `json:"nodes_list"`
+	Annotations []GraphAnnotation `json:"annotations"`
+}
+
+// GraphAnnotation analyst note, labels and colour override of a node of saved graph,
+// Color is applied by the frontend on top of the default GraphNodeColor
+type GraphAnnotation struct {
+	Node      int64          `json:"node" db:"node"`
+	Note      string         `json:"note" db:"note"`
+	Labels    pq.StringArray `json:"labels" db:"labels"`
+	Color     string         `json:"color,omitempty" db:"color"`
+	UpdatedBy *uuid.UUID     `json:"updated_by,omitempty" db:"user_id"`
+	Updated   time.Time      `json:"updated" db:"updated"`
+}
+
+// GraphAnnotationEdit request of setting annotation of a node of saved graph
+// shown with GraphParams
+type GraphAnnotationEdit struct {
+	GraphID uuid.UUID `json:"graph_id"`
+	Node    int64     `json:"node"`
+	Note    string    `json:"note"`
+	Labels  []string  `json:"labels"`
+	Color   string    `json:"color"`
+	GraphParams
+}
+
+// GraphNodeRef request that refers to a node of saved graph
+type GraphNodeRef struct {
+	GraphID uuid.UUID `json:"graph_id"`
+	Node    int64     `json:"node"`
 }
 
 type NewGraphElement struct {
@@ -71,8 +226,10 @@ type NewGraphElement struct {
 }
 
 type NewGraph struct {
+	Name        string            `json:"name" db:"name"`
 	Description string            `json:"description" db:"description"`
 	Sources     []NewGraphElement `json:"sources"`
+	MinLinks    int               `json:"min_links" db:"min_links"`
 	Cnt         int               `db:"cnt_elements"`
 	GraphID     uuid.UUID         `json:"graph_id" db:"graph_id"`
 	UserID      uuid.UUID         `json:"user_id" db:"user_id"`
@@ -80,15 +237,104 @@ type NewGraph struct {
 
 type NewGraphResp struct {
 	UUID        uuid.UUID `json:"graph_id" db:"graph_id"`
+	Name        string    `json:"name" db:"name"`
 	Description string    `json:"description" db:"description"`
+	MinLinks    int       `json:"min_links" db:"min_links"`
 	Created     time.Time `json:"created" db:"created"`
 }
 
 type GraphCard struct {
-	GraphID     uuid.UUID `json:"graph_id" db:"graph_id"`
-	Cnt         int       `json:"cnt" db:"cnt_elements"`
-	Description string    `json:"description" db:"description"`
-	Created     time.Time `json:"created" db:"created"`
+	GraphID     uuid.UUID      `json:"graph_id" db:"graph_id"`
+	Name        string         `json:"name" db:"name"`
+	Cnt         int            `json:"cnt" db:"cnt_elements"`
+	Description string         `json:"description" db:"description"`
+	MinLinks    int            `json:"min_links" db:"min_links"`
+	Created     time.Time      `json:"created" db:"created"`
+	Updated     time.Time      `json:"updated" db:"updated"`
+	Folder      string         `json:"folder" db:"folder"`
+	Tags        pq.StringArray `json:"tags" db:"tags"`
+	Role        string         `json:"role,omitempty" db:"role"`
+}
+
+// Sorting of saved graph cards
+const (
+	GraphSortCreated = "created"
+	GraphSortUpdated = "updated"
+	GraphSortName    = "name"
+	GraphSortCnt     = "cnt"
+)
+
+// MaxGraphCardsPage upper bound of the page size of saved graph cards
+const MaxGraphCardsPage = 500
+
+// GraphCardsQuery filter, sorting and page of saved graph cards, zero Limit returns all cards
+type GraphCardsQuery struct {
+	Folder string
+	Tags   []string
+	Search string
+	Sort   string
+	Desc   bool
+	Limit  int
+	Offset int
+}
+
+// GraphLabel folder or tag with the number of saved graphs in it
+type GraphLabel struct {
+	Name string `json:"name" db:"name"`
+	Cnt  int    `json:"cnt" db:"cnt"`
+}
+
+// GraphLabels folders and tags of saved graphs available to the user
+type GraphLabels struct {
+	Folders []GraphLabel `json:"folders"`
+	Tags    []GraphLabel `json:"tags"`
+}
+
+// AnalyticsQuery request of recalculation of analytics over the link graph
+type AnalyticsQuery struct {
+	MinLinks int `json:"min_links"`
+}
+
+// CommunitiesInfo summary of the last community detection
+type CommunitiesInfo struct {
+	Nodes       int       `json:"nodes"`
+	Communities int       `json:"communities"`
+	Modularity  float64   `json:"modularity"`
+	MinLinks    int       `json:"min_links"`
+	Updated     time.Time `json:"updated"`
+}
+
+// MetricsInfo summary of the last centrality metrics calculation
+type MetricsInfo struct {
+	Nodes    int       `json:"nodes"`
+	MinLinks int       `json:"min_links"`
+	Updated  time.Time `json:"updated"`
+}
+
+// AnalyticsJob state of a background recalculation over the full graph,
+// Last is the summary of the last successful one
+type AnalyticsJob[T any] struct {
+	Running  bool      `json:"running"`
+	MinLinks int       `json:"min_links"`
+	Started  time.Time `json:"started"`
+	Error    string    `json:"error,omitempty"`
+	Last     *T        `json:"last,omitempty"`
+}
+
+// MetricsJob state of the background recalculation of centrality metrics
+type MetricsJob = AnalyticsJob[MetricsInfo]
+
+// CommunitiesJob state of the background community detection
+type CommunitiesJob = AnalyticsJob[CommunitiesInfo]
+
+// LayoutJob state of the background layout of the full graph
+type LayoutJob = AnalyticsJob[LayoutInfo]
+
+// LayoutInfo summary of the last layout of the full graph
+type LayoutInfo struct {
+	Nodes    int       `json:"nodes"`
+	MinLinks int       `json:"min_links"`
+	Updated  time.Time `json:"updated"`
 }
 
 type GraphDel struct {
@@ -97,4 +343,240 @@ type GraphDel struct {
 
 type GraphUUID struct {
 	GraphID uuid.UUID `json:"graph_id" db:"graph_id"`
+	GraphParams
+}
+
+// Formats of saved graph export
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+)
+
+// GraphExportQuery request of saved graph export
+type GraphExportQuery struct {
+	GraphID uuid.UUID `json:"graph_id"`
+	Format  string    `json:"format"`
+}
+
+// SourceMetrics statistics of a source from public.srcs, nil when the source is unknown
+type SourceMetrics struct {
+	Country             *string  `db:"country"`
+	Category            *string  `db:"category"`
+	Subscribers         *int64   `db:"subscribers"`
+	Posts               *int64   `db:"posts"`
+	ForwardedPosts      *int64   `db:"forwarded_posts"`
+	ForwardedReactions  *int64   `db:"forwarded_reactions"`
+	AvgDailySubscribers *float64 `db:"avg_daily_subscribers"`
+	ERR                 *float64 `db:"err"`
+	CitationIndex       *float64 `db:"citation_index"`
+}
+
+// GraphTableNode row of the nodes table of exported graph
+type GraphTableNode struct {
+	ID         int64  `db:"id"`
+	URL        string `db:"url"`
+	Title      string `db:"title"`
+	Links      int64  `db:"links"`
+	Neighbours int
+	SourceMetrics
+}
+
+// GraphTableEdge row of the edges table of exported graph
+type GraphTableEdge struct {
+	From      int64
+	FromURL   string
+	FromTitle string
+	To        int64
+	ToURL     string
+	ToTitle   string
+	Links     int32
+}
+
+// GraphTables saved graph as node and edge tables
+type GraphTables struct {
+	Description string
+	Nodes       []GraphTableNode
+	Edges       []GraphTableEdge
+}
+
+// MaxImportSources upper limit of sources in imported list
+const MaxImportSources = 5000
+
+// ImportRow source URL from imported file with its row number
+type ImportRow struct {
+	Row int    `json:"row"`
+	URL string `json:"url"`
+}
+
+// ImportResult created graph and rows of imported file that were not found in the link graph
+type ImportResult struct {
+	Graph     NewGraphResp `json:"graph"`
+	Matched   int          `json:"matched"`
+	Unmatched []ImportRow  `json:"unmatched"`
+}
+
+// GraphUpdate new name, description, folder and tags of saved graph, nil fields are kept
+type GraphUpdate struct {
+	GraphID     uuid.UUID `json:"graph_id"`
+	Name        *string   `json:"name"`
+	Description *string   `json:"description"`
+	Folder      *string   `json:"folder"`
+	Tags        []string  `json:"tags"`
+}
+
+// GraphSources sources to add to, remove from or reorder in saved graph
+type GraphSources struct {
+	GraphID uuid.UUID `json:"graph_id"`
+	Sources []int64   `json:"sources"`
+}
+
+// Actions that create revisions of saved graph
+const (
+	RevisionInitial = "initial"
+	RevisionCreate  = "create"
+	RevisionImport  = "import"
+	RevisionUpdate  = "update"
+	RevisionAdd     = "add_sources"
+	RevisionRemove  = "remove_sources"
+	RevisionReorder = "reorder_sources"
+	RevisionRestore = "restore"
+	RevisionCombine = "combine"
+	RevisionClone   = "clone"
+)
+
+// GraphRevision immutable state of saved graph after one change
+type GraphRevision struct {
+	Revision    int       `json:"revision" db:"revision"`
+	Action      string    `json:"action" db:"action"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	MinLinks    int       `json:"min_links" db:"min_links"`
+	Sources     []int64   `json:"sources" db:"-"`
+	Created     time.Time `json:"created" db:"created"`
+}
+
+// GraphRevisionQuery request of one revision of saved graph
+type GraphRevisionQuery struct {
+	GraphID  uuid.UUID `json:"graph_id"`
+	Revision int       `json:"revision"`
+}
+
+// GraphDiffQuery request of difference between two revisions of saved graph
+type GraphDiffQuery struct {
+	GraphID uuid.UUID `json:"graph_id"`
+	From    int       `json:"from"`
+	To      int       `json:"to"`
+	GraphParams
+}
+
+// GraphDiff nodes and edges of revision To that are absent in revision From and vice versa
+type GraphDiff struct {
+	From           int         `json:"from"`
+	To             int         `json:"to"`
+	AddedSources   []int64     `json:"added_sources"`
+	RemovedSources []int64     `json:"removed_sources"`
+	AddedNodes     []GraphNode `json:"added_nodes"`
+	RemovedNodes   []GraphNode `json:"removed_nodes"`
+	AddedEdges     []GraphEdge `json:"added_edges"`
+	RemovedEdges   []GraphEdge `json:"removed_edges"`
+}
+
+// GraphRef request that refers to saved graph
+type GraphRef struct {
+	GraphID uuid.UUID `json:"graph_id"`
+}
+
+// DeletedGraphCard card of graph in trash
+type DeletedGraphCard struct {
+	GraphCard
+	Deleted time.Time  `json:"deleted" db:"deleted"`
+	PurgeAt *time.Time `json:"purge_at,omitempty" db:"-"`
+}
+
+// Roles of users in saved graph
+const (
+	GraphRoleOwner  = "owner"
+	GraphRoleEditor = "editor"
+	GraphRoleViewer = "viewer"
+)
+
+// GraphShare request of granting or revoking access to saved graph
+type GraphShare struct {
+	GraphID uuid.UUID `json:"graph_id"`
+	Email   string    `json:"email"`
+	Role    string    `json:"role,omitempty"`
+}
+
+// GraphAccess user with access to saved graph
+type GraphAccess struct {
+	UserID   uuid.UUID `json:"user_id" db:"user_id"`
+	Username string    `json:"username" db:"username"`
+	Email    string    `json:"email" db:"email"`
+	Role     string    `json:"role" db:"role"`
+	Granted  time.Time `json:"granted" db:"granted"`
+}
+
+// GraphLink read-only public link to saved graph
+type GraphLink struct {
+	LinkID  uuid.UUID  `json:"link_id" db:"link_id"`
+	GraphID uuid.UUID  `json:"graph_id" db:"graph_id"`
+	Token   string     `json:"token" db:"-"`
+	Created time.Time  `json:"created" db:"created"`
+	Revoked *time.Time `json:"revoked,omitempty" db:"revoked"`
+}
+
+// GraphLinkRef request that refers to public link of saved graph
+type GraphLinkRef struct {
+	GraphID uuid.UUID `json:"graph_id"`
+	LinkID  uuid.UUID `json:"link_id"`
+}
+
+// Set operations over sources of two saved graphs
+const (
+	CombineUnion     = "union"
+	CombineIntersect = "intersect"
+	CombineSubtract  = "subtract"
+)
+
+// GraphCombineQuery request of new saved graph from sources of two saved graphs,
+// subtract keeps sources of Left missing in Right, zero MinLinks takes the threshold of Left
+type GraphCombineQuery struct {
+	Left        uuid.UUID `json:"left"`
+	Right       uuid.UUID `json:"right"`
+	Operation   string    `json:"operation"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	MinLinks    int       `json:"min_links"`
+}
+
+// GraphCompareQuery request of comparison of two saved graphs
+type GraphCompareQuery struct {
+	Left     uuid.UUID `json:"left"`
+	Right    uuid.UUID `json:"right"`
+	MinLinks int       `json:"min_links"`
+}
+
+// SharedNeighbour node linked to sources of both compared graphs
+// with the summed links to the sources of each graph
+type SharedNeighbour struct {
+	GraphNode
+	LeftLinks  int64 `json:"left_links" db:"left_links"`
+	RightLinks int64 `json:"right_links" db:"right_links"`
+}
+
+// GraphComparison sources of two saved graphs split by membership and their shared neighbours
+type GraphComparison struct {
+	Shared           []int64           `json:"shared"`
+	LeftOnly         []int64           `json:"left_only"`
+	RightOnly        []int64           `json:"right_only"`
+	SharedNeighbours []SharedNeighbour `json:"shared_neighbours"`
+}
+
+// GraphCloneQuery request of copy of saved graph, copy belongs to the user with Email
+// when it is set and to the caller otherwise, empty Name keeps the name of the graph
+type GraphCloneQuery struct {
+	GraphID uuid.UUID `json:"graph_id"`
+	Name    string    `json:"name"`
+	Email   string    `json:"email"`
 }
@@ -6,17 +6,28 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	// RoleAdmin manages users and recalculates analytics
+	RoleAdmin = "admin"
+	// RoleAnalyst explores the full graph and builds saved graphs from sources
+	RoleAnalyst = "analyst"
+	// RoleViewer works only with saved graphs of its own and shared with it
+	RoleViewer = "viewer"
+)
+
+// MaxUsersPage maximal number of users on one page of the admin list
+const MaxUsersPage = 500
+
 type Status struct {
 	Result string `json:"result"`
 }
 
 type User struct {
-	ID       uuid.UUID `json:"id" db:"id"`
-	Username string    `json:"username" db:"username"`
-	Email    string    `json:"email" db:"email"`
-	Password string    `json:"password" db:"passwd"`
-	Token    string    `json:"token" db:"token"`
-	TokenExp time.Time `json:"token_expires" db:"token_expires"`
+	ID       uuid.UUID  `json:"id" db:"id"`
+	Username string     `json:"username" db:"username"`
+	Email    string     `json:"email" db:"email"`
+	Password string     `json:"password" db:"passwd"`
+	Disabled *time.Time `json:"-" db:"disabled"`
 }
 
 type UserLogin struct {
@@ -24,15 +35,61 @@ type UserLogin struct {
 	Password string `json:"password" db:"passwd"`
 }
 
+// Token pair of short-lived access token and refresh token issued on login
 type Token struct {
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	Type       string    `json:"type"`
+	Token      string    `json:"token"`
+	TokenExp   time.Time `json:"token_expires"`
+	Refresh    string    `json:"refresh_token"`
+	RefreshExp time.Time `json:"refresh_token_expires"`
+}
+
+// Session login of the user on one device, access and refresh tokens are bound to it
+type Session struct {
+	ID      uuid.UUID  `json:"session_id" db:"id"`
+	UserID  uuid.UUID  `json:"-" db:"user_id"`
+	Created time.Time  `json:"created" db:"created"`
+	Expires time.Time  `json:"expires" db:"expires"`
+	Revoked *time.Time `json:"revoked,omitempty" db:"revoked"`
+}
+
+// RefreshQuery request of the new token pair by refresh token
+type RefreshQuery struct {
+	Refresh string `json:"refresh_token"`
+}
+
+type UserInfo struct {
+	ID       uuid.UUID `json:"id" db:"id"`
 	Username string    `json:"username" db:"username"`
 	Email    string    `json:"email" db:"email"`
-	Type     string    `json:"type"`
-	Token    string    `json:"token" db:"token"`
-	TokenExp time.Time `json:"token_expires" db:"token_expires"`
+	Role     string    `json:"role" db:"role"`
 }
 
-type UserInfo struct {
-	Username string `json:"username" db:"username"`
-	Email    string `json:"email" db:"email"`
+// UserAccount user as seen by admins
+type UserAccount struct {
+	UserInfo
+	Created  time.Time  `json:"created" db:"created"`
+	Disabled *time.Time `json:"disabled,omitempty" db:"disabled"`
+}
+
+// UsersQuery filter and page of the admin list of users
+type UsersQuery struct {
+	Role   string
+	Search string
+	Limit  int
+	Offset int
+}
+
+// UserRoleUpdate change of the role of the user
+type UserRoleUpdate struct {
+	UserID uuid.UUID `json:"user_id"`
+	Role   string    `json:"role"`
+}
+
+// UserDisable disabling or enabling of the account of the user
+type UserDisable struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Disabled bool      `json:"disabled"`
 }
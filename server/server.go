@@ -2,6 +2,7 @@ package server
 
 import (
 	"AlexSarva/media/admin"
+	"AlexSarva/media/crypto"
 	"AlexSarva/media/handlers"
 	"AlexSarva/media/internal/app"
 	"AlexSarva/media/models"
@@ -19,14 +20,15 @@ type Server struct {
 }
 
 // NewServer Initializing new server instance
-func NewServer(cfg *models.Config, database *app.Database, adminDatabase *admin.PostgresDB) *Server {
+func NewServer(cfg *models.Config, database *app.Database, adminDatabase *admin.PostgresDB, keys *crypto.Keyring) *Server {
 
-	handler := handlers.MyHandler(database, adminDatabase)
+	handler := handlers.MyHandler(cfg, database, adminDatabase, keys)
+	// streaming routes move the write deadline forward while there is data to send
 	server := http.Server{
 		Addr:         cfg.ServerAddress,
 		Handler:      handler,
 		ReadTimeout:  time.Second * 60,
-		WriteTimeout: time.Second * 60,
+		WriteTimeout: cfg.WriteTimeout,
 	}
 	return &Server{
 		httpServer: &server,
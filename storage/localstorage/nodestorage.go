@@ -65,7 +65,7 @@ func (s *NodeStorage) GenerateNodes(query string) ([]models.GraphNode, error) {
 			ID:    key,
 			Label: element.Label,
 			Color: color,
-			Value: element.Value,
+			Value: float64(element.Value),
 		}
 		nodes = append(nodes, node)
 	}
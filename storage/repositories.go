@@ -2,6 +2,8 @@ package storage
 
 import (
 	"AlexSarva/media/models"
+	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -9,16 +11,49 @@ import (
 // Repo primary interface for all types of databases
 type Repo interface {
 	Ping() bool
-	GetSearch(text string) ([]models.SearchRes, error)
-	GetGraphByURL(text string) (models.Graph, error)
-	GetGraphByID(id int) (models.Graph, error)
-	GetFullGraph() (models.Graph, error)
-	GetSourceInfoByURL(text string) (models.GraphNode, error)
-	GetSourceInfoByID(id int) (models.GraphNode, error)
-	AddNewGraph(graphInfo models.NewGraph) (models.NewGraphResp, error)
-	GetGraphCards(userID uuid.UUID) ([]models.GraphCard, error)
-	DeleteGraphCard(userID, graphID uuid.UUID) ([]models.GraphCard, error)
-	GetGraphByUUID(GraphID uuid.UUID) (models.GraphExtended, error)
+	GetSearch(ctx context.Context, text string) ([]models.SearchRes, error)
+	GetGraphByURL(ctx context.Context, text string, params models.GraphParams) (models.Graph, error)
+	GetGraphByID(ctx context.Context, id int, params models.GraphParams) (models.Graph, error)
+	GetFullGraph(ctx context.Context, minLinks int, sizeBy string) (models.Graph, error)
+	GetFullGraphPage(ctx context.Context, minLinks int, sizeBy string, cursor models.GraphCursor, limit int) (models.GraphPage, error)
+	StreamFullGraph(ctx context.Context, minLinks int, sizeBy string, onNode func(models.GraphNode) error, onEdge func(models.GraphEdge) error) error
+	FindPaths(ctx context.Context, fromID, toID, maxHops, minLinks int) (models.Graph, error)
+	DetectCommunities(ctx context.Context, minLinks int) (models.CommunitiesInfo, error)
+	CalculateMetrics(ctx context.Context, minLinks int) (models.MetricsInfo, error)
+	CalculateLayout(ctx context.Context, minLinks int) (models.LayoutInfo, error)
+	GetSourceInfoByURL(ctx context.Context, text string) (models.GraphNode, error)
+	GetSourceInfoByID(ctx context.Context, id int) (models.GraphNode, error)
+	AddNewGraph(ctx context.Context, graphInfo models.NewGraph) (models.NewGraphResp, error)
+	GetGraphCards(ctx context.Context, userID uuid.UUID, query models.GraphCardsQuery) ([]models.GraphCard, int, error)
+	GetGraphLabels(ctx context.Context, userID uuid.UUID) (models.GraphLabels, error)
+	DeleteGraphCard(ctx context.Context, userID, graphID uuid.UUID) ([]models.GraphCard, error)
+	GetGraphByUUID(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, params models.GraphParams) (models.GraphExtended, error)
+	GetGraphTables(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) (models.GraphTables, error)
+	ImportGraph(ctx context.Context, graphInfo models.NewGraph, rows []models.ImportRow) (models.ImportResult, error)
+	UpdateGraphCard(ctx context.Context, userID uuid.UUID, update models.GraphUpdate) (models.GraphCard, error)
+	AddGraphSources(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, sources []int64) (models.GraphCard, error)
+	RemoveGraphSources(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, sources []int64) (models.GraphCard, error)
+	ReorderGraphSources(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, sources []int64) (models.GraphCard, error)
+	GetGraphRevisions(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) ([]models.GraphRevision, error)
+	DiffGraphRevisions(ctx context.Context, userID uuid.UUID, query models.GraphDiffQuery) (models.GraphDiff, error)
+	RestoreGraphRevision(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, revision int) (models.GraphCard, error)
+	GetDeletedGraphCards(ctx context.Context, userID uuid.UUID) ([]models.DeletedGraphCard, error)
+	RestoreGraphCard(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) (models.GraphCard, error)
+	PurgeGraphCard(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) error
+	PurgeDeletedGraphs(ctx context.Context, before time.Time) (int64, error)
+	GetGraphAccess(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) ([]models.GraphAccess, error)
+	ShareGraph(ctx context.Context, userID uuid.UUID, share models.GraphShare) ([]models.GraphAccess, error)
+	UnshareGraph(ctx context.Context, userID uuid.UUID, share models.GraphShare) ([]models.GraphAccess, error)
+	CreateGraphLink(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) (models.GraphLink, error)
+	GetGraphLinks(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) ([]models.GraphLink, error)
+	RevokeGraphLink(ctx context.Context, userID uuid.UUID, ref models.GraphLinkRef) error
+	GetGraphByLink(ctx context.Context, linkID uuid.UUID, params models.GraphParams) (models.GraphExtended, error)
+	CombineGraphs(ctx context.Context, graphInfo models.NewGraph, query models.GraphCombineQuery) (models.NewGraphResp, error)
+	CompareGraphs(ctx context.Context, userID uuid.UUID, query models.GraphCompareQuery) (models.GraphComparison, error)
+	CloneGraph(ctx context.Context, userID uuid.UUID, query models.GraphCloneQuery, graphID uuid.UUID) (models.NewGraphResp, error)
+	SaveGraphLayout(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, params models.GraphParams) (models.LayoutInfo, error)
+	SetGraphAnnotation(ctx context.Context, userID uuid.UUID, annotation models.GraphAnnotationEdit) ([]models.GraphAnnotation, error)
+	DeleteGraphAnnotation(ctx context.Context, userID uuid.UUID, ref models.GraphNodeRef) ([]models.GraphAnnotation, error)
 	//GetGraphData(url string) ([]models.DataForGraph, error)
 	//NewUser(user *models.User) error
 	//GetUser(username string) (*models.User, error)
@@ -0,0 +1,184 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrForbidden error that occurs when the user has no access to the graph
+var ErrForbidden = errors.New("access to graph denied")
+
+// ErrUnknownUser error that occurs when the graph is shared with a user that does not exist
+var ErrUnknownUser = errors.New("user not found")
+
+// ErrNotValidShare error that occurs when the graph is shared with its owner
+var ErrNotValidShare = errors.New("graph can not be shared with its owner")
+
+// ErrNotShared error that occurs when the graph is copied to a user it is not shared with
+var ErrNotShared = errors.New("graph is not shared with the user")
+
+// graphRole role of the user in not deleted graph: sql.ErrNoRows when there is no such graph,
+// ErrForbidden when the graph is not shared with the user
+func graphRole(ctx context.Context, q sqlx.QueryerContext, userID uuid.UUID, graphID uuid.UUID) (string, error) {
+	var role sql.NullString
+	err := sqlx.GetContext(ctx, q, &role, `select case when g.user_id = $2 then 'owner' else a.role end
+from media.graphs g
+left join media.graphs_access a on a.graph_id = g.graph_id and a.user_id = $2
+where g.graph_id = $1
+and g.is_del = 0`, graphID, userID)
+	if err != nil {
+		return "", err
+	}
+	if !role.Valid {
+		return "", ErrForbidden
+	}
+	return role.String, nil
+}
+
+// checkGraphRole checks that the user has one of the roles in the graph
+func checkGraphRole(ctx context.Context, q sqlx.QueryerContext, userID uuid.UUID, graphID uuid.UUID, roles ...string) error {
+	role, err := graphRole(ctx, q, userID, graphID)
+	if err != nil {
+		return err
+	}
+	for _, allowed := range roles {
+		if role == allowed {
+			return nil
+		}
+	}
+	return ErrForbidden
+}
+
+// GetGraphAccess owner and users the graph is shared with, available to the owner only
+func (d *PostgresDB) GetGraphAccess(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) ([]models.GraphAccess, error) {
+	if err := checkGraphRole(ctx, d.database, userID, graphID, models.GraphRoleOwner); err != nil {
+		return nil, err
+	}
+	access := []models.GraphAccess{}
+	err := d.database.SelectContext(ctx, &access, `select u.id user_id, coalesce(u.username, '') username, u.email, 'owner' role, g.created granted
+from media.graphs g
+join public.users u on u.id = g.user_id
+where g.graph_id = $1
+union all
+select u.id, coalesce(u.username, ''), u.email, a.role, a.granted
+from media.graphs_access a
+join public.users u on u.id = a.user_id
+where a.graph_id = $1
+order by granted`, graphID)
+	if err != nil {
+		log.Println("errAccess: ", err)
+		return nil, err
+	}
+	return access, nil
+}
+
+// ShareGraph grants the user with the email a role in the graph of the owner
+func (d *PostgresDB) ShareGraph(ctx context.Context, userID uuid.UUID, share models.GraphShare) ([]models.GraphAccess, error) {
+	if err := checkGraphRole(ctx, d.database, userID, share.GraphID, models.GraphRoleOwner); err != nil {
+		return nil, err
+	}
+	var target uuid.UUID
+	if err := d.database.GetContext(ctx, &target, "select id from public.users where lower(email) = lower($1)", share.Email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUnknownUser
+		}
+		return nil, err
+	}
+	if target == userID {
+		return nil, ErrNotValidShare
+	}
+	_, err := d.database.ExecContext(ctx, `insert into media.graphs_access (graph_id, user_id, role, granted_by)
+values ($1, $2, $3, $4)
+on conflict (graph_id, user_id) do update set role = excluded.role, granted_by = excluded.granted_by, granted = now()`,
+		share.GraphID, target, share.Role, userID)
+	if err != nil {
+		return nil, err
+	}
+	return d.GetGraphAccess(ctx, userID, share.GraphID)
+}
+
+// UnshareGraph revokes access of the user with the email to the graph of the owner
+func (d *PostgresDB) UnshareGraph(ctx context.Context, userID uuid.UUID, share models.GraphShare) ([]models.GraphAccess, error) {
+	if err := checkGraphRole(ctx, d.database, userID, share.GraphID, models.GraphRoleOwner); err != nil {
+		return nil, err
+	}
+	res, err := d.database.ExecContext(ctx, `delete from media.graphs_access a
+using public.users u
+where u.id = a.user_id
+and a.graph_id = $1
+and lower(u.email) = lower($2)`, share.GraphID, share.Email)
+	if err != nil {
+		return nil, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return nil, ErrNoData
+	}
+	return d.GetGraphAccess(ctx, userID, share.GraphID)
+}
+
+// CreateGraphLink creates public read-only link to the graph of the owner
+func (d *PostgresDB) CreateGraphLink(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) (models.GraphLink, error) {
+	if err := checkGraphRole(ctx, d.database, userID, graphID, models.GraphRoleOwner); err != nil {
+		return models.GraphLink{}, err
+	}
+	var link models.GraphLink
+	err := d.database.GetContext(ctx, &link, `insert into media.graphs_links (link_id, graph_id, created_by)
+values ($1, $2, $3)
+returning link_id, graph_id, created, revoked`, uuid.New(), graphID, userID)
+	return link, err
+}
+
+// GetGraphLinks public links of the graph of the owner including revoked ones
+func (d *PostgresDB) GetGraphLinks(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) ([]models.GraphLink, error) {
+	if err := checkGraphRole(ctx, d.database, userID, graphID, models.GraphRoleOwner); err != nil {
+		return nil, err
+	}
+	links := []models.GraphLink{}
+	err := d.database.SelectContext(ctx, &links, `select link_id, graph_id, created, revoked from media.graphs_links
+where graph_id = $1
+order by created desc`, graphID)
+	return links, err
+}
+
+// RevokeGraphLink makes public link of the graph of the owner invalid
+func (d *PostgresDB) RevokeGraphLink(ctx context.Context, userID uuid.UUID, ref models.GraphLinkRef) error {
+	if err := checkGraphRole(ctx, d.database, userID, ref.GraphID, models.GraphRoleOwner); err != nil {
+		return err
+	}
+	res, err := d.database.ExecContext(ctx, `update media.graphs_links set revoked = now()
+where link_id = $1
+and graph_id = $2
+and revoked is null`, ref.LinkID, ref.GraphID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrNoData
+	}
+	return nil
+}
+
+// GetGraphByLink graph of not revoked public link, annotations are shown without their authors
+func (d *PostgresDB) GetGraphByLink(ctx context.Context, linkID uuid.UUID, params models.GraphParams) (models.GraphExtended, error) {
+	var graphID uuid.UUID
+	err := d.database.GetContext(ctx, &graphID, `select l.graph_id from media.graphs_links l
+join media.graphs g on g.graph_id = l.graph_id
+where l.link_id = $1
+and l.revoked is null
+and g.is_del = 0`, linkID)
+	if err != nil {
+		return models.GraphExtended{}, err
+	}
+	graph, graphErr := d.savedGraph(ctx, graphID, params)
+	if graphErr != nil {
+		return models.GraphExtended{}, graphErr
+	}
+	hideAuthors(graph.Annotations)
+	return graph, nil
+}
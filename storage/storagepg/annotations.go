@@ -0,0 +1,89 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// graphAnnotations annotations of nodes of saved graph ordered by node
+func graphAnnotations(ctx context.Context, q sqlx.QueryerContext, graphID uuid.UUID) ([]models.GraphAnnotation, error) {
+	annotations := []models.GraphAnnotation{}
+	err := sqlx.SelectContext(ctx, q, &annotations, `select node, coalesce(note, '') note, coalesce(labels, '{}') labels,
+       coalesce(color, '') color, user_id, updated
+from media.graphs_annotations
+where graph_id = $1
+order by node`, graphID)
+	if err != nil {
+		log.Println("errAnnotations: ", err)
+		return nil, err
+	}
+	return annotations, nil
+}
+
+// containsNode the node is one of ids
+func containsNode(ids []int64, node int64) bool {
+	for _, id := range ids {
+		if id == node {
+			return true
+		}
+	}
+	return false
+}
+
+// hideAuthors removes ids of users who edited annotations, for readers of public links
+func hideAuthors(annotations []models.GraphAnnotation) {
+	for i := range annotations {
+		annotations[i].UpdatedBy = nil
+	}
+}
+
+// SetGraphAnnotation creates or replaces annotation of the node of the graph,
+// available to the owner and editors of the graph. The node must be one of the sources of the graph
+// or of the nodes the graph expands to with the params of the annotation
+func (d *PostgresDB) SetGraphAnnotation(ctx context.Context, userID uuid.UUID, annotation models.GraphAnnotationEdit) ([]models.GraphAnnotation, error) {
+	if err := checkGraphRole(ctx, d.database, userID, annotation.GraphID, models.GraphRoleOwner, models.GraphRoleEditor); err != nil {
+		return nil, err
+	}
+	seeds, params, seedsErr := d.savedSeeds(ctx, annotation.GraphID, annotation.GraphParams)
+	if seedsErr != nil {
+		return nil, seedsErr
+	}
+	ids, _, _, expandErr := d.expandGraph(ctx, seeds, params)
+	if expandErr != nil {
+		return nil, expandErr
+	}
+	if !containsNode(ids, annotation.Node) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownSources, annotation.Node)
+	}
+	_, err := d.database.ExecContext(ctx, `insert into media.graphs_annotations (graph_id, node, note, labels, color, user_id)
+values ($1, $2, nullif($3, ''), $4, nullif($5, ''), $6)
+on conflict (graph_id, node) do update set note = excluded.note, labels = excluded.labels, color = excluded.color,
+    user_id = excluded.user_id, updated = now()`,
+		annotation.GraphID, annotation.Node, annotation.Note, pq.Array(annotation.Labels), annotation.Color, userID)
+	if err != nil {
+		return nil, err
+	}
+	return graphAnnotations(ctx, d.database, annotation.GraphID)
+}
+
+// DeleteGraphAnnotation removes annotation of the node of the graph,
+// available to the owner and editors of the graph
+func (d *PostgresDB) DeleteGraphAnnotation(ctx context.Context, userID uuid.UUID, ref models.GraphNodeRef) ([]models.GraphAnnotation, error) {
+	if err := checkGraphRole(ctx, d.database, userID, ref.GraphID, models.GraphRoleOwner, models.GraphRoleEditor); err != nil {
+		return nil, err
+	}
+	res, err := d.database.ExecContext(ctx, "delete from media.graphs_annotations where graph_id = $1 and node = $2", ref.GraphID, ref.Node)
+	if err != nil {
+		return nil, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return nil, ErrNoData
+	}
+	return graphAnnotations(ctx, d.database, ref.GraphID)
+}
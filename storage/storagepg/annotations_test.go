@@ -0,0 +1,34 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHideAuthors(t *testing.T) {
+	author := uuid.New()
+	annotations := []models.GraphAnnotation{
+		{Node: 1, Note: "state media", UpdatedBy: &author},
+		{Node: 2, Labels: []string{"bot"}, UpdatedBy: &author},
+	}
+
+	hideAuthors(annotations)
+
+	res, err := json.Marshal(annotations)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(res), "updated_by")
+	assert.NotContains(t, string(res), author.String())
+	assert.Equal(t, "state media", annotations[0].Note)
+}
+
+func TestContainsNode(t *testing.T) {
+	ids := []int64{3, 1, 2}
+	assert.True(t, containsNode(ids, 3))
+	assert.True(t, containsNode(ids, 2))
+	assert.False(t, containsNode(ids, 4))
+	assert.False(t, containsNode(nil, 1))
+}
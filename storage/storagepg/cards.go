@@ -0,0 +1,98 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// graphCardsCTE cards of graphs owned by and shared with the user $1
+const graphCardsCTE = `with cards as (
+select ` + graphCardColumns + `, 'owner' role from media.graphs
+where 1=1
+and is_del = 0
+and user_id = $1
+union all
+select ` + graphCardColumns + `, a.role from media.graphs
+join media.graphs_access a using (graph_id)
+where 1=1
+and is_del = 0
+and a.user_id = $1
+)
+`
+
+// graphCardsSort columns of cards by sorting of models.GraphCardsQuery
+var graphCardsSort = map[string]string{
+	models.GraphSortCreated: "created",
+	models.GraphSortUpdated: "updated",
+	models.GraphSortName:    "lower(name)",
+	models.GraphSortCnt:     "cnt_elements",
+}
+
+// graphCardsFilter where clause over graphCardsCTE and its arguments for the query
+func graphCardsFilter(query models.GraphCardsQuery, userID uuid.UUID) (string, []interface{}) {
+	args := []interface{}{userID}
+	var conds []string
+	if len(query.Folder) != 0 {
+		args = append(args, query.Folder)
+		conds = append(conds, fmt.Sprintf("folder = $%d", len(args)))
+	}
+	if len(query.Tags) != 0 {
+		args = append(args, pq.Array(query.Tags))
+		conds = append(conds, fmt.Sprintf("tags @> $%d::text[]", len(args)))
+	}
+	if len(query.Search) != 0 {
+		args = append(args, strings.ToLower(query.Search))
+		conds = append(conds, fmt.Sprintf("(strpos(lower(description), $%[1]d) > 0 or strpos(lower(name), $%[1]d) > 0)", len(args)))
+	}
+	if len(conds) == 0 {
+		return "", args
+	}
+	return "\nwhere " + strings.Join(conds, "\nand "), args
+}
+
+// graphCardsOrder order and page of cards for the query, unknown sorting falls back to created
+func graphCardsOrder(query models.GraphCardsQuery) string {
+	column, ok := graphCardsSort[query.Sort]
+	if !ok {
+		column = graphCardsSort[models.GraphSortCreated]
+	}
+	direction := "asc"
+	if query.Desc {
+		direction = "desc"
+	}
+	order := fmt.Sprintf("\norder by %s %s, graph_id", column, direction)
+	if query.Limit > 0 {
+		order += fmt.Sprintf("\nlimit %d", query.Limit)
+	}
+	if query.Offset > 0 {
+		order += fmt.Sprintf("\noffset %d", query.Offset)
+	}
+	return order
+}
+
+// GetGraphLabels folders and tags of graphs owned by and shared with the user
+func (d *PostgresDB) GetGraphLabels(ctx context.Context, userID uuid.UUID) (models.GraphLabels, error) {
+	labels := models.GraphLabels{Folders: []models.GraphLabel{}, Tags: []models.GraphLabel{}}
+	foldersErr := d.database.SelectContext(ctx, &labels.Folders, graphCardsCTE+`select folder name, count(*) cnt from cards
+where folder <> ''
+group by folder
+order by folder`, userID)
+	if foldersErr != nil {
+		log.Println("foldersErr: ", foldersErr)
+		return models.GraphLabels{}, foldersErr
+	}
+	tagsErr := d.database.SelectContext(ctx, &labels.Tags, graphCardsCTE+`select t name, count(*) cnt from cards, unnest(tags) t
+group by t
+order by cnt desc, t`, userID)
+	if tagsErr != nil {
+		log.Println("tagsErr: ", tagsErr)
+		return models.GraphLabels{}, tagsErr
+	}
+	return labels, nil
+}
@@ -0,0 +1,51 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphCardsQuery(t *testing.T) {
+	userID := uuid.New()
+	tests := []struct {
+		name      string
+		query     models.GraphCardsQuery
+		wantWhere string
+		wantArgs  []interface{}
+		wantOrder string
+	}{
+		{
+			name:      "all cards",
+			query:     models.GraphCardsQuery{Sort: models.GraphSortCreated, Desc: true},
+			wantWhere: "",
+			wantArgs:  []interface{}{userID},
+			wantOrder: "\norder by created desc, graph_id",
+		},
+		{
+			name:      "folder, tags and search",
+			query:     models.GraphCardsQuery{Folder: "News", Tags: []string{"ru", "tv"}, Search: "Media", Sort: models.GraphSortName, Limit: 20, Offset: 40},
+			wantWhere: "\nwhere folder = $2\nand tags @> $3::text[]\nand (strpos(lower(description), $4) > 0 or strpos(lower(name), $4) > 0)",
+			wantArgs:  []interface{}{userID, "News", pq.Array([]string{"ru", "tv"}), "media"},
+			wantOrder: "\norder by lower(name) asc, graph_id\nlimit 20\noffset 40",
+		},
+		{
+			name:      "unknown sorting",
+			query:     models.GraphCardsQuery{Sort: "size"},
+			wantWhere: "",
+			wantArgs:  []interface{}{userID},
+			wantOrder: "\norder by created asc, graph_id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := graphCardsFilter(tt.query, userID)
+			assert.Equal(t, tt.wantWhere, where)
+			assert.Equal(t, tt.wantArgs, args)
+			assert.Equal(t, tt.wantOrder, graphCardsOrder(tt.query))
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// graphSources sources of saved graph in their order
+func graphSources(ctx context.Context, q sqlx.QueryerContext, graphID uuid.UUID) ([]int64, error) {
+	sources := []int64{}
+	err := sqlx.SelectContext(ctx, q, &sources, "select node from media.graphs_elements where graph_id = $1 order by num", graphID)
+	return sources, err
+}
+
+// combineSources applies the set operation to sources of two graphs,
+// the order of left comes first and repeated sources are kept once
+func combineSources(operation string, left, right []int64) []int64 {
+	inRight := make(map[int64]bool, len(right))
+	for _, id := range right {
+		inRight[id] = true
+	}
+	seen := make(map[int64]bool, len(left)+len(right))
+	combined := []int64{}
+	for _, id := range left {
+		keep := true
+		switch operation {
+		case models.CombineIntersect:
+			keep = inRight[id]
+		case models.CombineSubtract:
+			keep = !inRight[id]
+		}
+		if keep && !seen[id] {
+			seen[id] = true
+			combined = append(combined, id)
+		}
+	}
+	if operation == models.CombineUnion {
+		for _, id := range right {
+			if !seen[id] {
+				seen[id] = true
+				combined = append(combined, id)
+			}
+		}
+	}
+	return combined
+}
+
+// CombineGraphs saves sources of two graphs available to the user combined by the set operation
+// as a new graph of the user in one transaction
+func (d *PostgresDB) CombineGraphs(ctx context.Context, graphInfo models.NewGraph, query models.GraphCombineQuery) (models.NewGraphResp, error) {
+	tx, txErr := d.database.BeginTxx(ctx, nil)
+	if txErr != nil {
+		return models.NewGraphResp{}, txErr
+	}
+	defer tx.Rollback()
+
+	var operands [2][]int64
+	for i, graphID := range []uuid.UUID{query.Left, query.Right} {
+		if err := checkGraphRole(ctx, tx, graphInfo.UserID, graphID, models.GraphRoleOwner, models.GraphRoleEditor, models.GraphRoleViewer); err != nil {
+			return models.NewGraphResp{}, err
+		}
+		sources, err := graphSources(ctx, tx, graphID)
+		if err != nil {
+			return models.NewGraphResp{}, err
+		}
+		operands[i] = sources
+	}
+	sources := combineSources(query.Operation, operands[0], operands[1])
+	if len(sources) == 0 {
+		return models.NewGraphResp{}, ErrEmptyGraph
+	}
+
+	if graphInfo.MinLinks == 0 {
+		if err := tx.GetContext(ctx, &graphInfo.MinLinks, "select coalesce(min_links, $2) from media.graphs where graph_id = $1", query.Left, models.DefaultMinLinks); err != nil {
+			return models.NewGraphResp{}, err
+		}
+	}
+	graphInfo.Cnt = len(sources)
+	if err := insertGraph(ctx, tx, graphInfo, sources); err != nil {
+		return models.NewGraphResp{}, err
+	}
+	if err := recordRevision(ctx, tx, graphInfo.GraphID, graphInfo.UserID, models.RevisionCombine); err != nil {
+		return models.NewGraphResp{}, err
+	}
+
+	var created models.NewGraphResp
+	if err := tx.GetContext(ctx, &created, "SELECT graph_id, coalesce(name, '') name, description, min_links, created FROM media.graphs WHERE graph_id=$1", graphInfo.GraphID); err != nil {
+		return models.NewGraphResp{}, err
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		return models.NewGraphResp{}, commitErr
+	}
+	log.Printf("combined graphs %s %s %s into %s: %d sources", query.Left, query.Operation, query.Right, graphInfo.GraphID, len(sources))
+	return created, nil
+}
+
+// CompareGraphs splits sources of two graphs available to the user into shared and own ones
+// and finds nodes linked with at least minLinks to sources of both graphs
+func (d *PostgresDB) CompareGraphs(ctx context.Context, userID uuid.UUID, query models.GraphCompareQuery) (models.GraphComparison, error) {
+	var operands [2][]int64
+	for i, graphID := range []uuid.UUID{query.Left, query.Right} {
+		if err := checkGraphRole(ctx, d.database, userID, graphID, models.GraphRoleOwner, models.GraphRoleEditor, models.GraphRoleViewer); err != nil {
+			return models.GraphComparison{}, err
+		}
+		sources, err := graphSources(ctx, d.database, graphID)
+		if err != nil {
+			return models.GraphComparison{}, err
+		}
+		operands[i] = sources
+	}
+	left, right := operands[0], operands[1]
+
+	var comparison models.GraphComparison
+	comparison.RightOnly, comparison.LeftOnly = diffSources(left, right)
+	comparison.Shared = combineSources(models.CombineIntersect, left, right)
+
+	comparison.SharedNeighbours = []models.SharedNeighbour{}
+	err := d.database.SelectContext(ctx, &comparison.SharedNeighbours, `with adjacent as (
+select id_to node, links, id_from = any($1) in_left, id_from = any($2) in_right from analytics.graph_edges
+where (id_from = any($1) or id_from = any($2))
+and links >= $3
+union all
+select id_from, links, id_to = any($1), id_to = any($2) from analytics.graph_edges
+where (id_to = any($1) or id_to = any($2))
+and links >= $3
+), sums as (
+select node,
+       sum(case when in_left then links else 0 end) left_links,
+       sum(case when in_right then links else 0 end) right_links
+from adjacent
+group by node
+)
+select n.id, n.url, coalesce(n.title, n.url) title, n.links, s.left_links, s.right_links
+from sums s
+join analytics.graph_nodes n on n.id = s.node
+where s.left_links > 0
+and s.right_links > 0
+and not n.id = any($1)
+and not n.id = any($2)
+order by s.left_links + s.right_links desc, n.id
+limit $4`, pq.Array(left), pq.Array(right), query.MinLinks, models.DefaultGraphNodes)
+	if err != nil {
+		log.Println("errSharedNeighbours: ", err)
+		return models.GraphComparison{}, err
+	}
+	return comparison, nil
+}
+
+// CloneGraph copies the graph available to the user with its sources as a new graph.
+// Copy into the workspace of another user is allowed to the owner of the graph
+// when the graph is shared with that user
+func (d *PostgresDB) CloneGraph(ctx context.Context, userID uuid.UUID, query models.GraphCloneQuery, graphID uuid.UUID) (models.NewGraphResp, error) {
+	tx, txErr := d.database.BeginTxx(ctx, nil)
+	if txErr != nil {
+		return models.NewGraphResp{}, txErr
+	}
+	defer tx.Rollback()
+
+	target := userID
+	if len(query.Email) == 0 {
+		if err := checkGraphRole(ctx, tx, userID, query.GraphID, models.GraphRoleOwner, models.GraphRoleEditor, models.GraphRoleViewer); err != nil {
+			return models.NewGraphResp{}, err
+		}
+	} else {
+		if err := checkGraphRole(ctx, tx, userID, query.GraphID, models.GraphRoleOwner); err != nil {
+			return models.NewGraphResp{}, err
+		}
+		if err := tx.GetContext(ctx, &target, "select id from public.users where lower(email) = lower($1)", query.Email); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.NewGraphResp{}, ErrUnknownUser
+			}
+			return models.NewGraphResp{}, err
+		}
+		if err := checkGraphRole(ctx, tx, target, query.GraphID, models.GraphRoleOwner, models.GraphRoleEditor, models.GraphRoleViewer); err != nil {
+			if errors.Is(err, ErrForbidden) {
+				return models.NewGraphResp{}, ErrNotShared
+			}
+			return models.NewGraphResp{}, err
+		}
+	}
+
+	_, insErr := tx.ExecContext(ctx, `insert into media.graphs (user_id, graph_id, name, cnt_elements, description, min_links, tags)
+select $1, $2, coalesce(nullif($3, ''), name), cnt_elements, description, min_links, tags
+from media.graphs
+where graph_id = $4`, target, graphID, query.Name, query.GraphID)
+	if insErr != nil {
+		return models.NewGraphResp{}, insErr
+	}
+	_, elementsErr := tx.ExecContext(ctx, `insert into media.graphs_elements (graph_id, node, num)
+select $1, node, num
+from media.graphs_elements
+where graph_id = $2`, graphID, query.GraphID)
+	if elementsErr != nil {
+		return models.NewGraphResp{}, fmt.Errorf("copy graph elements: %w", elementsErr)
+	}
+	if err := recordRevision(ctx, tx, graphID, userID, models.RevisionClone); err != nil {
+		return models.NewGraphResp{}, err
+	}
+
+	var created models.NewGraphResp
+	if err := tx.GetContext(ctx, &created, "SELECT graph_id, coalesce(name, '') name, description, min_links, created FROM media.graphs WHERE graph_id=$1", graphID); err != nil {
+		return models.NewGraphResp{}, err
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		return models.NewGraphResp{}, commitErr
+	}
+	log.Printf("cloned graph %s into %s of user %s", query.GraphID, graphID, target)
+	return created, nil
+}
@@ -0,0 +1,73 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombineSources(t *testing.T) {
+	left := []int64{3, 1, 2, 1}
+	right := []int64{2, 4, 3, 5}
+	tests := []struct {
+		operation string
+		want      []int64
+	}{
+		{operation: models.CombineUnion, want: []int64{3, 1, 2, 4, 5}},
+		{operation: models.CombineIntersect, want: []int64{3, 2}},
+		{operation: models.CombineSubtract, want: []int64{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operation, func(t *testing.T) {
+			assert.Equal(t, tt.want, combineSources(tt.operation, left, right))
+		})
+	}
+}
+
+func TestCloneGraph(t *testing.T) {
+	database, owner := testDB(t)
+	shared := testUser(t, database)
+	stranger := testUser(t, database)
+	graphID := testGraph(t, database, owner)
+	database.database.MustExec("insert into media.graphs_access (graph_id, user_id, role, granted_by) values ($1, $2, $3, $4)", graphID, shared, models.GraphRoleViewer, owner)
+	email := func(userID uuid.UUID) string {
+		return userID.String() + "@storage.test"
+	}
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		query  models.GraphCloneQuery
+		owner  uuid.UUID
+		err    error
+	}{
+		{name: "owner copies for themselves", userID: owner, query: models.GraphCloneQuery{GraphID: graphID}, owner: owner},
+		{name: "viewer copies for themselves", userID: shared, query: models.GraphCloneQuery{GraphID: graphID, Name: "mine"}, owner: shared},
+		{name: "stranger copies for themselves", userID: stranger, query: models.GraphCloneQuery{GraphID: graphID}, err: ErrForbidden},
+		{name: "owner copies to user it is shared with", userID: owner, query: models.GraphCloneQuery{GraphID: graphID, Email: email(shared)}, owner: shared},
+		{name: "owner copies to user it is not shared with", userID: owner, query: models.GraphCloneQuery{GraphID: graphID, Email: email(stranger)}, err: ErrNotShared},
+		{name: "viewer copies to another user", userID: shared, query: models.GraphCloneQuery{GraphID: graphID, Email: email(owner)}, err: ErrForbidden},
+		{name: "unknown email", userID: owner, query: models.GraphCloneQuery{GraphID: graphID, Email: "nobody@storage.test"}, err: ErrUnknownUser},
+		{name: "unknown graph", userID: owner, query: models.GraphCloneQuery{GraphID: uuid.New()}, err: sql.ErrNoRows},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			copyID := uuid.New()
+			_, err := database.CloneGraph(context.Background(), tt.userID, tt.query, copyID)
+			assert.ErrorIs(t, err, tt.err)
+
+			var copyOwner uuid.UUID
+			ownerErr := database.database.Get(&copyOwner, "select user_id from media.graphs where graph_id = $1", copyID)
+			if tt.err != nil {
+				assert.ErrorIs(t, ownerErr, sql.ErrNoRows)
+				return
+			}
+			assert.NoError(t, ownerErr)
+			assert.Equal(t, tt.owner, copyOwner)
+		})
+	}
+}
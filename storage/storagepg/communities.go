@@ -0,0 +1,72 @@
+package storagepg
+
+import (
+	"AlexSarva/media/analytics"
+	"AlexSarva/media/models"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DetectCommunities splits the link graph into communities with Louvain method
+// and replaces the content of analytics.graph_communities with the result
+func (d *PostgresDB) DetectCommunities(ctx context.Context, minLinks int) (models.CommunitiesInfo, error) {
+	if minLinks <= 0 {
+		minLinks = models.DefaultMinLinks
+	}
+
+	var edges []models.GraphEdge
+	errEdges := d.database.SelectContext(ctx, &edges, `
+select id_from, id_to, links from analytics.graph_edges
+where links >= $1;`, minLinks)
+	if errEdges != nil {
+		log.Println("errEdges: ", errEdges)
+		return models.CommunitiesInfo{}, errEdges
+	}
+
+	started := time.Now()
+	network := analytics.NewNetwork(edges)
+	communities, modularity := network.Communities()
+
+	ids := make([]int64, 0, len(communities))
+	nums := make([]int64, 0, len(communities))
+	count := 0
+	for _, id := range network.Nodes {
+		ids = append(ids, id)
+		nums = append(nums, int64(communities[id]))
+		if communities[id]+1 > count {
+			count = communities[id] + 1
+		}
+	}
+	log.Printf("communities: %d nodes, %d communities, modularity %.4f in %s", len(ids), count, modularity, time.Since(started))
+
+	tx, txErr := d.database.BeginTxx(ctx, nil)
+	if txErr != nil {
+		return models.CommunitiesInfo{}, txErr
+	}
+	if _, delErr := tx.ExecContext(ctx, "delete from analytics.graph_communities"); delErr != nil {
+		tx.Rollback()
+		return models.CommunitiesInfo{}, fmt.Errorf("clear communities: %w", delErr)
+	}
+	_, insErr := tx.ExecContext(ctx, `
+insert into analytics.graph_communities (id, community, min_links)
+select unnest($1::int8[]), unnest($2::int8[]), $3;`, pq.Array(ids), pq.Array(nums), minLinks)
+	if insErr != nil {
+		tx.Rollback()
+		return models.CommunitiesInfo{}, fmt.Errorf("insert communities: %w", insErr)
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		return models.CommunitiesInfo{}, commitErr
+	}
+
+	return models.CommunitiesInfo{
+		Nodes:       len(ids),
+		Communities: count,
+		Modularity:  modularity,
+		MinLinks:    minLinks,
+		Updated:     time.Now(),
+	}, nil
+}
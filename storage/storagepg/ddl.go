@@ -0,0 +1,113 @@
+package storagepg
+
+import "AlexSarva/media/utils/urlutils"
+
+// ddl tables and queries of saved graphs for the first initializing of database
+const ddl = `
+CREATE SCHEMA if not exists media;
+
+CREATE TABLE if not exists media.graphs (
+    user_id uuid references public.users(id),
+    graph_id uuid unique,
+    cnt_elements int,
+    description text,
+    created timestamp default now(),
+    is_del int2 default 0
+);
+
+CREATE TABLE if not exists media.graphs_elements (
+    graph_id uuid references media.graphs(graph_id),
+    node int,
+    num int
+);
+
+ALTER TABLE media.graphs ADD COLUMN if not exists min_links int default 5;
+ALTER TABLE media.graphs ADD COLUMN if not exists name text;
+ALTER TABLE media.graphs ADD COLUMN if not exists updated timestamp default now();
+ALTER TABLE media.graphs ADD COLUMN if not exists deleted timestamp;
+-- graphs trashed before deleted was recorded stay in trash for the full retention period
+UPDATE media.graphs SET deleted = now() WHERE is_del = 1 AND deleted is null;
+ALTER TABLE media.graphs ADD COLUMN if not exists folder text;
+ALTER TABLE media.graphs ADD COLUMN if not exists tags text[];
+
+CREATE INDEX if not exists graphs_tags_idx ON media.graphs USING gin (tags);
+
+CREATE SCHEMA if not exists analytics;
+
+CREATE TABLE if not exists analytics.graph_communities (
+    id int8 primary key,
+    community int,
+    min_links int,
+    updated timestamp default now()
+);
+
+CREATE TABLE if not exists analytics.graph_metrics (
+    id int8 primary key,
+    pagerank float8,
+    betweenness float8,
+    in_degree int8,
+    out_degree int8,
+    min_links int,
+    updated timestamp default now()
+);
+
+CREATE TABLE if not exists analytics.graph_layout (
+    id int8 primary key,
+    x float8,
+    y float8,
+    min_links int,
+    updated timestamp default now()
+);
+
+CREATE TABLE if not exists media.graphs_layout (
+    graph_id uuid references media.graphs(graph_id),
+    node int8,
+    x float8,
+    y float8,
+    primary key (graph_id, node)
+);
+
+CREATE TABLE if not exists media.graphs_annotations (
+    graph_id uuid references media.graphs(graph_id),
+    node int8,
+    note text,
+    labels text[],
+    color text,
+    user_id uuid references public.users(id),
+    updated timestamp default now(),
+    primary key (graph_id, node)
+);
+
+CREATE TABLE if not exists media.graphs_revisions (
+    graph_id uuid references media.graphs(graph_id),
+    revision int,
+    user_id uuid,
+    action text,
+    name text,
+    description text,
+    min_links int,
+    sources int8[],
+    created timestamp default now(),
+    primary key (graph_id, revision)
+);
+
+CREATE TABLE if not exists media.graphs_access (
+    graph_id uuid references media.graphs(graph_id),
+    user_id uuid references public.users(id),
+    role text not null,
+    granted_by uuid,
+    granted timestamp default now(),
+    primary key (graph_id, user_id)
+);
+
+CREATE TABLE if not exists media.graphs_links (
+    link_id uuid primary key,
+    graph_id uuid references media.graphs(graph_id),
+    created_by uuid,
+    created timestamp default now(),
+    revoked timestamp
+);
+`
+
+// ddlNormURL index of normalized URLs of sources, import matches sources by it
+var ddlNormURL = `CREATE INDEX if not exists graph_nodes_norm_url ON analytics.graph_nodes (` + urlutils.NormalizeSQL("url") + `);`
@@ -0,0 +1,249 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// graphCardColumns columns of media.graphs returned as models.GraphCard
+const graphCardColumns = "graph_id, coalesce(name, '') name, cnt_elements, coalesce(description, '') description, min_links, created, coalesce(updated, created) updated, coalesce(folder, '') folder, coalesce(tags, '{}') tags"
+
+// ErrUnknownSources error that occurs when added sources are not in the link graph
+var ErrUnknownSources = errors.New("sources not found in graph")
+
+// ErrEmptyGraph error that occurs when all sources are removed from the graph
+var ErrEmptyGraph = errors.New("graph must have at least one source")
+
+// ErrNotValidOrder error that occurs when new order is not a permutation of graph sources
+var ErrNotValidOrder = errors.New("order must list every source of the graph once")
+
+// UpdateGraphCard changes name, description, folder and tags of the user graph,
+// empty folder removes the graph from its folder
+func (d *PostgresDB) UpdateGraphCard(ctx context.Context, userID uuid.UUID, update models.GraphUpdate) (models.GraphCard, error) {
+	tx, txErr := d.database.BeginTxx(ctx, nil)
+	if txErr != nil {
+		return models.GraphCard{}, txErr
+	}
+	defer tx.Rollback()
+
+	if err := lockGraph(ctx, tx, userID, update.GraphID); err != nil {
+		return models.GraphCard{}, err
+	}
+	// folder and tags only organise cards and are not kept in revisions
+	revised := update.Name != nil || update.Description != nil
+	if revised {
+		if err := ensureRevision(ctx, tx, update.GraphID, userID); err != nil {
+			return models.GraphCard{}, err
+		}
+	}
+	var tags interface{}
+	if update.Tags != nil {
+		tags = pq.Array(update.Tags)
+	}
+	_, err := tx.ExecContext(ctx, `update media.graphs
+set name = coalesce($2, name), description = coalesce($3, description),
+    folder = coalesce(nullif($4, ''), case when $4 is null then folder end), tags = coalesce($5, tags), updated = now()
+where graph_id = $1`, update.GraphID, update.Name, update.Description, update.Folder, tags)
+	if err != nil {
+		return models.GraphCard{}, err
+	}
+	if revised {
+		if err = recordRevision(ctx, tx, update.GraphID, userID, models.RevisionUpdate); err != nil {
+			return models.GraphCard{}, err
+		}
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		return models.GraphCard{}, commitErr
+	}
+	return d.getGraphCard(ctx, update.GraphID)
+}
+
+// AddGraphSources appends sources to the end of the user graph, sources already in the graph are skipped
+func (d *PostgresDB) AddGraphSources(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, sources []int64) (models.GraphCard, error) {
+	return d.editGraphSources(ctx, userID, graphID, models.RevisionAdd, func(tx *sqlx.Tx, current []int64) ([]int64, error) {
+		var known []int64
+		if err := tx.SelectContext(ctx, &known, "select id from analytics.graph_nodes where id = any($1)", pq.Array(sources)); err != nil {
+			return nil, err
+		}
+		exists := make(map[int64]bool, len(known))
+		for _, id := range known {
+			exists[id] = true
+		}
+		for _, id := range sources {
+			if !exists[id] {
+				return nil, fmt.Errorf("%w: %d", ErrUnknownSources, id)
+			}
+		}
+
+		return appendSources(current, sources), nil
+	})
+}
+
+// appendSources appends sources missing from current keeping their order
+func appendSources(current []int64, sources []int64) []int64 {
+	inGraph := make(map[int64]bool, len(current)+len(sources))
+	for _, id := range current {
+		inGraph[id] = true
+	}
+	for _, id := range sources {
+		if !inGraph[id] {
+			inGraph[id] = true
+			current = append(current, id)
+		}
+	}
+	return current
+}
+
+// RemoveGraphSources removes sources from the user graph, the graph keeps at least one source
+func (d *PostgresDB) RemoveGraphSources(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, sources []int64) (models.GraphCard, error) {
+	return d.editGraphSources(ctx, userID, graphID, models.RevisionRemove, func(tx *sqlx.Tx, current []int64) ([]int64, error) {
+		return removeSources(current, sources)
+	})
+}
+
+// removeSources current without sources, ErrEmptyGraph when nothing is left
+func removeSources(current []int64, sources []int64) ([]int64, error) {
+	removed := make(map[int64]bool, len(sources))
+	for _, id := range sources {
+		removed[id] = true
+	}
+	kept := make([]int64, 0, len(current))
+	for _, id := range current {
+		if !removed[id] {
+			kept = append(kept, id)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, ErrEmptyGraph
+	}
+	return kept, nil
+}
+
+// ReorderGraphSources sets new order of the user graph sources
+func (d *PostgresDB) ReorderGraphSources(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, sources []int64) (models.GraphCard, error) {
+	return d.editGraphSources(ctx, userID, graphID, models.RevisionReorder, func(tx *sqlx.Tx, current []int64) ([]int64, error) {
+		return reorderSources(current, sources)
+	})
+}
+
+// reorderSources checks that sources is a permutation of current, ErrNotValidOrder otherwise
+func reorderSources(current []int64, sources []int64) ([]int64, error) {
+	if len(sources) != len(current) {
+		return nil, ErrNotValidOrder
+	}
+	left := make(map[int64]bool, len(current))
+	for _, id := range current {
+		left[id] = true
+	}
+	for _, id := range sources {
+		if !left[id] {
+			return nil, ErrNotValidOrder
+		}
+		delete(left, id)
+	}
+	return sources, nil
+}
+
+// editGraphSources locks the user graph, passes its sources in order of num to edit
+// and saves the result with new cnt_elements and updated as a revision with action
+func (d *PostgresDB) editGraphSources(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, action string, edit func(tx *sqlx.Tx, current []int64) ([]int64, error)) (models.GraphCard, error) {
+	tx, txErr := d.database.BeginTxx(ctx, nil)
+	if txErr != nil {
+		return models.GraphCard{}, txErr
+	}
+	defer tx.Rollback()
+
+	if err := lockGraph(ctx, tx, userID, graphID); err != nil {
+		return models.GraphCard{}, err
+	}
+	if err := ensureRevision(ctx, tx, graphID, userID); err != nil {
+		return models.GraphCard{}, err
+	}
+
+	var current []int64
+	if err := tx.SelectContext(ctx, &current, "select node from media.graphs_elements where graph_id = $1 order by num", graphID); err != nil {
+		return models.GraphCard{}, err
+	}
+	sources, editErr := edit(tx, current)
+	if editErr != nil {
+		return models.GraphCard{}, editErr
+	}
+
+	if err := replaceGraphSources(ctx, tx, graphID, sources); err != nil {
+		return models.GraphCard{}, err
+	}
+	if _, err := tx.ExecContext(ctx, "update media.graphs set cnt_elements = $2, updated = now() where graph_id = $1", graphID, len(sources)); err != nil {
+		return models.GraphCard{}, err
+	}
+	if err := recordRevision(ctx, tx, graphID, userID, action); err != nil {
+		return models.GraphCard{}, err
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		return models.GraphCard{}, commitErr
+	}
+	log.Printf("graph %s edited: %d -> %d sources", graphID, len(current), len(sources))
+
+	return d.getGraphCard(ctx, graphID)
+}
+
+// lockGraph locks the graph till the end of transaction, ErrNoData when there is no such graph,
+// ErrForbidden when the user is neither owner nor editor of the graph
+func lockGraph(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, graphID uuid.UUID) error {
+	var locked uuid.UUID
+	err := tx.GetContext(ctx, &locked, `select graph_id from media.graphs
+where graph_id = $1
+and is_del = 0
+for update`, graphID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoData
+	}
+	if err != nil {
+		return err
+	}
+	return checkGraphRole(ctx, tx, userID, graphID, models.GraphRoleOwner, models.GraphRoleEditor)
+}
+
+// insertGraph saves new graph with its sources
+func insertGraph(ctx context.Context, tx *sqlx.Tx, graphInfo models.NewGraph, sources []int64) error {
+	resInsert, resErr := tx.NamedExecContext(ctx, "INSERT INTO media.graphs (user_id, graph_id, name, cnt_elements, description, min_links) VALUES (:user_id, :graph_id, :name, :cnt_elements, :description, :min_links) on conflict(graph_id) do nothing", &graphInfo)
+	if resErr != nil {
+		return resErr
+	}
+	if affected, _ := resInsert.RowsAffected(); affected == 0 {
+		return ErrDuplicatePK
+	}
+	return replaceGraphSources(ctx, tx, graphInfo.GraphID, sources)
+}
+
+// replaceGraphSources saves sources of the graph numbered from 1
+func replaceGraphSources(ctx context.Context, tx *sqlx.Tx, graphID uuid.UUID, sources []int64) error {
+	nums := make([]int64, len(sources))
+	for i := range sources {
+		nums[i] = int64(i + 1)
+	}
+	if _, err := tx.ExecContext(ctx, "delete from media.graphs_elements where graph_id = $1", graphID); err != nil {
+		return fmt.Errorf("clear graph elements: %w", err)
+	}
+	_, insErr := tx.ExecContext(ctx, `
+insert into media.graphs_elements (graph_id, node, num)
+select $1, unnest($2::int8[]), unnest($3::int8[]);`, graphID, pq.Array(sources), pq.Array(nums))
+	if insErr != nil {
+		return fmt.Errorf("insert graph elements: %w", insErr)
+	}
+	return nil
+}
+
+// getGraphCard card of the graph by its id
+func (d *PostgresDB) getGraphCard(ctx context.Context, graphID uuid.UUID) (models.GraphCard, error) {
+	var card models.GraphCard
+	err := d.database.GetContext(ctx, &card, "select "+graphCardColumns+" from media.graphs where graph_id = $1", graphID)
+	return card, err
+}
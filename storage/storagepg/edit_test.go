@@ -0,0 +1,75 @@
+package storagepg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		current []int64
+		sources []int64
+		want    []int64
+	}{
+		{name: "new sources go to the end", current: []int64{1, 2}, sources: []int64{4, 3}, want: []int64{1, 2, 4, 3}},
+		{name: "sources in graph are skipped", current: []int64{1, 2}, sources: []int64{2, 3, 1}, want: []int64{1, 2, 3}},
+		{name: "repeated sources are added once", current: []int64{1}, sources: []int64{3, 3}, want: []int64{1, 3}},
+		{name: "nothing new", current: []int64{1, 2}, sources: []int64{2}, want: []int64{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, appendSources(tt.current, tt.sources))
+		})
+	}
+}
+
+func TestRemoveSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		current []int64
+		sources []int64
+		want    []int64
+		err     error
+	}{
+		{name: "order is kept", current: []int64{1, 2, 3, 4}, sources: []int64{3, 1}, want: []int64{2, 4}},
+		{name: "unknown sources are ignored", current: []int64{1, 2}, sources: []int64{5}, want: []int64{1, 2}},
+		{name: "last source stays", current: []int64{1, 2}, sources: []int64{1, 2}, err: ErrEmptyGraph},
+		{name: "last source with unknown ones", current: []int64{7}, sources: []int64{7, 8}, err: ErrEmptyGraph},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := removeSources(tt.current, tt.sources)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReorderSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		current []int64
+		sources []int64
+		err     error
+	}{
+		{name: "permutation", current: []int64{1, 2, 3}, sources: []int64{3, 1, 2}},
+		{name: "same order", current: []int64{1, 2, 3}, sources: []int64{1, 2, 3}},
+		{name: "missing source", current: []int64{1, 2, 3}, sources: []int64{3, 1}, err: ErrNotValidOrder},
+		{name: "extra source", current: []int64{1, 2}, sources: []int64{2, 1, 3}, err: ErrNotValidOrder},
+		{name: "repeated source", current: []int64{1, 2, 3}, sources: []int64{1, 1, 2}, err: ErrNotValidOrder},
+		{name: "unknown source", current: []int64{1, 2}, sources: []int64{1, 5}, err: ErrNotValidOrder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reorderSources(tt.current, tt.sources)
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err == nil {
+				assert.Equal(t, tt.sources, got)
+			} else {
+				assert.Nil(t, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"context"
+	"log"
+	"sort"
+
+	"github.com/lib/pq"
+)
+
+// nodeColumns columns of a source with its calculated analytics, selected from nodeTables
+const nodeColumns = "id, url, links, coalesce(title, url) title, community, pagerank, betweenness, in_degree, out_degree"
+
+// nodeTables analytics.graph_nodes joined with the results of community detection and metrics
+const nodeTables = `analytics.graph_nodes
+left join analytics.graph_communities using (id)
+left join analytics.graph_metrics using (id)`
+
+// normalizeGraphParams fills defaults and clamps limits of graph expansion
+func normalizeGraphParams(params models.GraphParams) models.GraphParams {
+	if params.Depth <= 0 {
+		params.Depth = models.DefaultGraphDepth
+	}
+	if params.Depth > models.MaxGraphDepth {
+		params.Depth = models.MaxGraphDepth
+	}
+	if params.MaxNodes <= 0 {
+		params.MaxNodes = models.DefaultGraphNodes
+	}
+	if params.MaxNodes > models.MaxGraphNodes {
+		params.MaxNodes = models.MaxGraphNodes
+	}
+	if params.Direction == "" {
+		params.Direction = models.DirectionOut
+	}
+	if params.MinLinks <= 0 {
+		params.MinLinks = models.DefaultMinLinks
+	}
+	if params.SizeBy == "" {
+		params.SizeBy = models.SizeByLinks
+	}
+	return params
+}
+
+// applySize sets the value of nodes from the chosen metric,
+// nodes without calculated metrics get zero size
+func applySize(nodes []models.GraphNode, sizeBy string) {
+	for i := range nodes {
+		node := &nodes[i]
+		switch sizeBy {
+		case models.SizeByPageRank:
+			node.Value = 0
+			if node.PageRank != nil {
+				node.Value = *node.PageRank
+			}
+		case models.SizeByBetweenness:
+			node.Value = 0
+			if node.Betweenness != nil {
+				node.Value = *node.Betweenness
+			}
+		case models.SizeByInDegree:
+			node.Value = 0
+			if node.InDegree != nil {
+				node.Value = float64(*node.InDegree)
+			}
+		case models.SizeByOutDegree:
+			node.Value = 0
+			if node.OutDegree != nil {
+				node.Value = float64(*node.OutDegree)
+			}
+		}
+	}
+}
+
+// selectLayer selects edges adjacent to the frontier in one direction
+// edges are tagged with the direction they were followed in
+func (d *PostgresDB) selectLayer(ctx context.Context, frontier []int64, direction string, minLinks int) ([]models.GraphEdge, error) {
+	column := "id_from"
+	if direction == models.DirectionIn {
+		column = "id_to"
+	}
+
+	var layer []models.GraphEdge
+	errLayer := d.database.SelectContext(ctx, &layer, `
+select id_from, id_to, links from analytics.graph_edges
+where `+column+` = any($1)
+and links >= $2
+order by links desc, id_from, id_to;`, pq.Array(frontier), minLinks)
+	if errLayer != nil {
+		log.Println("errLayer: ", errLayer)
+		return nil, errLayer
+	}
+
+	for i := range layer {
+		layer[i].Direction = direction
+	}
+	return layer, nil
+}
+
+// selectInduced selects edges between the given nodes
+func (d *PostgresDB) selectInduced(ctx context.Context, ids []int64, minLinks int) ([]models.GraphEdge, error) {
+	var edges []models.GraphEdge
+	errInduced := d.database.SelectContext(ctx, &edges, `
+select id_from, id_to, links from analytics.graph_edges
+where id_from = any($1)
+and id_to = any($1)
+and links >= $2
+order by links desc, id_from, id_to;`, pq.Array(ids), minLinks)
+	if errInduced != nil {
+		log.Println("errInduced: ", errInduced)
+		return nil, errInduced
+	}
+	return edges, nil
+}
+
+// expandGraph walks analytics.graph_edges breadth-first starting from seeds.
+// Every hop follows the strongest links first, so when the node cap is reached
+// the weakest neighbours of the last layer are dropped.
+// Returns node ids in visiting order and the edges between visited nodes,
+// including the links among nodes of the last layer that is not expanded itself,
+// capped reports whether any neighbour was dropped by the node cap
+func (d *PostgresDB) expandGraph(ctx context.Context, seeds []int64, params models.GraphParams) (ids []int64, edges []models.GraphEdge, capped bool, err error) {
+	params = normalizeGraphParams(params)
+
+	directions := []string{params.Direction}
+	if params.Direction == models.DirectionBoth {
+		directions = []string{models.DirectionOut, models.DirectionIn}
+	}
+
+	visited := make(map[int64]bool, len(seeds))
+	var order []int64
+	for _, id := range seeds {
+		if !visited[id] {
+			visited[id] = true
+			order = append(order, id)
+		}
+	}
+
+	frontier := append([]int64(nil), order...)
+	seenEdges := make(map[[2]int64]bool)
+	for hop := 0; hop < params.Depth && len(frontier) > 0; hop++ {
+		var next []int64
+		for _, direction := range directions {
+			layer, layerErr := d.selectLayer(ctx, frontier, direction, params.MinLinks)
+			if layerErr != nil {
+				return nil, nil, false, layerErr
+			}
+
+			for _, edge := range layer {
+				key := [2]int64{edge.From, edge.To}
+				if seenEdges[key] {
+					continue
+				}
+				neighbour := edge.To
+				if direction == models.DirectionIn {
+					neighbour = edge.From
+				}
+				if !visited[neighbour] {
+					if len(order) >= params.MaxNodes {
+						capped = true
+						continue
+					}
+					visited[neighbour] = true
+					order = append(order, neighbour)
+					next = append(next, neighbour)
+				}
+				seenEdges[key] = true
+				edge.Dashes = true
+				edges = append(edges, edge)
+			}
+		}
+		frontier = next
+	}
+
+	induced, inducedErr := d.selectInduced(ctx, order, params.MinLinks)
+	if inducedErr != nil {
+		return nil, nil, false, inducedErr
+	}
+	edges = appendUnseen(edges, induced, seenEdges, inducedDirection(params.Direction))
+
+	return order, edges, capped, nil
+}
+
+// inducedDirection direction tag of edges that were not followed by the walk
+func inducedDirection(direction string) string {
+	if direction == models.DirectionIn {
+		return models.DirectionIn
+	}
+	return models.DirectionOut
+}
+
+// appendUnseen appends edges missing from seen, tagged with direction
+func appendUnseen(edges []models.GraphEdge, candidates []models.GraphEdge, seen map[[2]int64]bool, direction string) []models.GraphEdge {
+	for _, edge := range candidates {
+		key := [2]int64{edge.From, edge.To}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		edge.Direction = direction
+		edge.Dashes = true
+		edges = append(edges, edge)
+	}
+	return edges
+}
+
+// selectNodes loads nodes by ids keeping the order of ids
+func (d *PostgresDB) selectNodes(ctx context.Context, ids []int64) ([]models.GraphNode, error) {
+	var nodes []models.GraphNode
+	errNodes := d.database.SelectContext(ctx, &nodes, `
+select `+nodeColumns+` FROM `+nodeTables+`
+where id = any($1);`, pq.Array(ids))
+	if errNodes != nil {
+		log.Println("errNodes: ", errNodes)
+		return nil, errNodes
+	}
+
+	position := make(map[int64]int, len(ids))
+	for i, id := range ids {
+		position[id] = i
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return position[nodes[i].ID] < position[nodes[j].ID]
+	})
+	return nodes, nil
+}
+
+// egoGraph builds the neighbourhood of seeds up to params.Depth hops
+// seeds are marked as main nodes, everything else as sub nodes
+func (d *PostgresDB) egoGraph(ctx context.Context, seeds []int64, params models.GraphParams) (models.Graph, error) {
+	ids, edges, _, expandErr := d.expandGraph(ctx, seeds, params)
+	if expandErr != nil {
+		return models.Graph{}, expandErr
+	}
+
+	nodes, nodesErr := d.selectNodes(ctx, ids)
+	if nodesErr != nil {
+		return models.Graph{}, nodesErr
+	}
+
+	applySize(nodes, params.SizeBy)
+
+	isSeed := make(map[int64]bool, len(seeds))
+	for _, id := range seeds {
+		isSeed[id] = true
+	}
+	for i := range nodes {
+		if isSeed[nodes[i].ID] {
+			nodes[i].Kind = models.NodeKindMain
+		} else {
+			nodes[i].Kind = models.NodeKindSub
+		}
+	}
+
+	return models.Graph{
+		Nodes: nodes,
+		Edges: edges,
+	}, nil
+}
+
+// markIsolated marks the only requested source without links
+// as isolated and adds a loop edge, so it still can be drawn
+func markIsolated(graph *models.Graph) {
+	if len(graph.Edges) != 0 || len(graph.Nodes) != 1 {
+		return
+	}
+	graph.Nodes[0].Kind = models.NodeKindIsolated
+	graph.Edges = append(graph.Edges, models.GraphEdge{
+		From:   graph.Nodes[0].ID,
+		To:     graph.Nodes[0].ID,
+		Dashes: false,
+	})
+}
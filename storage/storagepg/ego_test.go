@@ -0,0 +1,24 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendUnseen(t *testing.T) {
+	edges := []models.GraphEdge{{From: 1, To: 2, Direction: models.DirectionOut, Dashes: true}}
+	seen := map[[2]int64]bool{{1, 2}: true}
+	induced := []models.GraphEdge{{From: 1, To: 2}, {From: 2, To: 3, Value: 7}, {From: 3, To: 2}}
+
+	edges = appendUnseen(edges, induced, seen, inducedDirection(models.DirectionBoth))
+
+	assert.Equal(t, []models.GraphEdge{
+		{From: 1, To: 2, Direction: models.DirectionOut, Dashes: true},
+		{From: 2, To: 3, Value: 7, Direction: models.DirectionOut, Dashes: true},
+		{From: 3, To: 2, Direction: models.DirectionOut, Dashes: true},
+	}, edges)
+	assert.True(t, seen[[2]int64{2, 3}])
+	assert.Equal(t, models.DirectionIn, inducedDirection(models.DirectionIn))
+}
@@ -0,0 +1,91 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// GetGraphTables saved graph available to the user as node and edge tables
+// with source statistics from public.srcs
+func (d *PostgresDB) GetGraphTables(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) (models.GraphTables, error) {
+	var tables models.GraphTables
+	if err := checkGraphRole(ctx, d.database, userID, graphID, models.GraphRoleOwner, models.GraphRoleEditor, models.GraphRoleViewer); err != nil {
+		return models.GraphTables{}, err
+	}
+	errGraph := d.database.GetContext(ctx, &tables.Description, "select coalesce(description, '') from media.graphs where graph_id = $1", graphID)
+	if errGraph != nil {
+		log.Println("errGraph: ", errGraph)
+		return models.GraphTables{}, errGraph
+	}
+
+	graph, graphErr := d.savedGraph(ctx, graphID, models.GraphParams{})
+	if graphErr != nil {
+		return models.GraphTables{}, graphErr
+	}
+
+	ids := make([]int64, len(graph.Nodes))
+	for i, node := range graph.Nodes {
+		ids[i] = node.ID
+	}
+	var rows []models.GraphTableNode
+	rowsErr := d.database.SelectContext(ctx, &rows, `select distinct on (n.id) n.id, n.url, coalesce(n.title, n.url) title, n.links,
+       s.country, s.category, s.subscribers, s.posts, s.forwarded_posts, s.forwarded_reactions,
+       s.avg_daily_subscribers, s.err, s.citation_index
+from analytics.graph_nodes n
+left join public.srcs s on s.base_url = n.url
+where n.id = any($1)
+order by n.id, s.id`, pq.Array(ids))
+	if rowsErr != nil {
+		log.Println("rowsErr: ", rowsErr)
+		return models.GraphTables{}, rowsErr
+	}
+	byID := make(map[int64]models.GraphTableNode, len(rows))
+	for _, row := range rows {
+		byID[row.ID] = row
+	}
+
+	neighbours := make(map[int64]map[int64]bool, len(graph.Nodes))
+	for _, edge := range graph.Edges {
+		if edge.From == edge.To {
+			continue
+		}
+		for _, pair := range [][2]int64{{edge.From, edge.To}, {edge.To, edge.From}} {
+			if neighbours[pair[0]] == nil {
+				neighbours[pair[0]] = make(map[int64]bool)
+			}
+			neighbours[pair[0]][pair[1]] = true
+		}
+	}
+
+	// nodes keep the order of the graph response: saved sources first
+	for _, node := range graph.Nodes {
+		row, ok := byID[node.ID]
+		if !ok {
+			continue
+		}
+		row.Neighbours = len(neighbours[node.ID])
+		tables.Nodes = append(tables.Nodes, row)
+	}
+	for _, edge := range graph.Edges {
+		from, fromOk := byID[edge.From]
+		to, toOk := byID[edge.To]
+		if edge.From == edge.To || !fromOk || !toOk {
+			continue
+		}
+		tables.Edges = append(tables.Edges, models.GraphTableEdge{
+			From:      from.ID,
+			FromURL:   from.URL,
+			FromTitle: from.Title,
+			To:        to.ID,
+			ToURL:     to.URL,
+			ToTitle:   to.Title,
+			Links:     edge.Value,
+		})
+	}
+
+	return tables, nil
+}
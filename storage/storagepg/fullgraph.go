@@ -0,0 +1,133 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"context"
+	"log"
+)
+
+// fullGraphNodes nodes of the full graph having at least one edge above the threshold $1
+const fullGraphNodes = `
+with 
+    all_nodes as (
+select distinct unnest(array[id_from, id_to]) ids from analytics.graph_edges
+where links >= $1)
+select ` + nodeColumns + `, x, y FROM ` + nodeTables + `
+left join analytics.graph_layout using (id)
+where 1=1
+and exists(select 1 from all_nodes where all_nodes.ids = graph_nodes.id)`
+
+// GetFullGraphPage returns one page of the full graph in keyset order:
+// nodes by id go first, then edges by (id_from, id_to).
+// Next cursor is nil on the last page
+func (d *PostgresDB) GetFullGraphPage(ctx context.Context, minLinks int, sizeBy string, cursor models.GraphCursor, limit int) (models.GraphPage, error) {
+	if minLinks <= 0 {
+		minLinks = models.DefaultMinLinks
+	}
+	if limit <= 0 || limit > models.MaxGraphPage {
+		limit = models.DefaultGraphPage
+	}
+
+	page := models.GraphPage{
+		Nodes: []models.GraphNode{},
+		Edges: []models.GraphEdge{},
+	}
+
+	if cursor.Phase != models.CursorEdges {
+		errNodes := d.database.SelectContext(ctx, &page.Nodes, fullGraphNodes+`
+and id > $2
+order by id
+limit $3;`, minLinks, cursor.From, limit)
+		if errNodes != nil {
+			log.Println("errNodes: ", errNodes)
+			return models.GraphPage{}, errNodes
+		}
+
+		applySize(page.Nodes, sizeBy)
+		for i := range page.Nodes {
+			page.Nodes[i].Kind = models.NodeKindSub
+		}
+
+		next := models.GraphCursor{Phase: models.CursorEdges}
+		if len(page.Nodes) == limit {
+			next = models.GraphCursor{Phase: models.CursorNodes, From: page.Nodes[len(page.Nodes)-1].ID}
+		}
+		page.Next = &next
+		return page, nil
+	}
+
+	errEdges := d.database.SelectContext(ctx, &page.Edges, `
+select id_from, id_to, links from analytics.graph_edges
+where links >= $1
+and (id_from, id_to) > ($2, $3)
+order by id_from, id_to
+limit $4;`, minLinks, cursor.From, cursor.To, limit)
+	if errEdges != nil {
+		log.Println("errEdges: ", errEdges)
+		return models.GraphPage{}, errEdges
+	}
+
+	for i := range page.Edges {
+		page.Edges[i].Dashes = true
+	}
+	if len(page.Edges) == limit {
+		last := page.Edges[len(page.Edges)-1]
+		page.Next = &models.GraphCursor{Phase: models.CursorEdges, From: last.From, To: last.To}
+	}
+	return page, nil
+}
+
+// StreamFullGraph reads the full graph row by row and passes every node and then every edge
+// to the callbacks without keeping the graph in memory. Streaming stops on the first callback error
+func (d *PostgresDB) StreamFullGraph(ctx context.Context, minLinks int, sizeBy string, onNode func(models.GraphNode) error, onEdge func(models.GraphEdge) error) error {
+	if minLinks <= 0 {
+		minLinks = models.DefaultMinLinks
+	}
+
+	nodeRows, nodesErr := d.database.QueryxContext(ctx, fullGraphNodes+`
+order by id;`, minLinks)
+	if nodesErr != nil {
+		log.Println("errNodes: ", nodesErr)
+		return nodesErr
+	}
+	defer nodeRows.Close()
+
+	for nodeRows.Next() {
+		var node models.GraphNode
+		if scanErr := nodeRows.StructScan(&node); scanErr != nil {
+			return scanErr
+		}
+		nodes := []models.GraphNode{node}
+		applySize(nodes, sizeBy)
+		nodes[0].Kind = models.NodeKindSub
+		if sendErr := onNode(nodes[0]); sendErr != nil {
+			return sendErr
+		}
+	}
+	if rowsErr := nodeRows.Err(); rowsErr != nil {
+		return rowsErr
+	}
+	nodeRows.Close()
+
+	edgeRows, edgesErr := d.database.QueryxContext(ctx, `
+select id_from, id_to, links from analytics.graph_edges
+where links >= $1
+order by id_from, id_to;`, minLinks)
+	if edgesErr != nil {
+		log.Println("errEdges: ", edgesErr)
+		return edgesErr
+	}
+	defer edgeRows.Close()
+
+	for edgeRows.Next() {
+		var edge models.GraphEdge
+		if scanErr := edgeRows.StructScan(&edge); scanErr != nil {
+			return scanErr
+		}
+		edge.Dashes = true
+		if sendErr := onEdge(edge); sendErr != nil {
+			return sendErr
+		}
+	}
+	return edgeRows.Err()
+}
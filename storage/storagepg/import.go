@@ -0,0 +1,92 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"AlexSarva/media/utils/urlutils"
+	"context"
+	"errors"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// ErrNoMatches error that occurs when none of imported sources is found in the link graph
+var ErrNoMatches = errors.New("no imported sources found in graph")
+
+// importedNode source of the link graph matched by normalized URL
+type importedNode struct {
+	ID  int64  `db:"id"`
+	URL string `db:"norm_url"`
+}
+
+// ImportGraph resolves imported URLs against analytics.graph_nodes by the index of normalized URLs and saves
+// matched sources as a new graph in one transaction. Sources keep the order of the file,
+// repeated sources are saved once
+func (d *PostgresDB) ImportGraph(ctx context.Context, graphInfo models.NewGraph, rows []models.ImportRow) (models.ImportResult, error) {
+	urls := make([]string, 0, len(rows))
+	for _, row := range rows {
+		urls = append(urls, urlutils.Normalize(row.URL))
+	}
+
+	tx, txErr := d.database.BeginTxx(ctx, nil)
+	if txErr != nil {
+		return models.ImportResult{}, txErr
+	}
+
+	var matches []importedNode
+	errMatches := tx.SelectContext(ctx, &matches, `select distinct on (norm_url) id, `+urlutils.NormalizeSQL("url")+` norm_url
+from analytics.graph_nodes
+where `+urlutils.NormalizeSQL("url")+` = any($1)
+order by norm_url, links desc, id`, pq.Array(urls))
+	if errMatches != nil {
+		tx.Rollback()
+		log.Println("errMatches: ", errMatches)
+		return models.ImportResult{}, errMatches
+	}
+	byURL := make(map[string]int64, len(matches))
+	for _, match := range matches {
+		byURL[match.URL] = match.ID
+	}
+
+	result := models.ImportResult{Unmatched: []models.ImportRow{}}
+	seen := make(map[int64]bool, len(matches))
+	var ids []int64
+	for i, row := range rows {
+		id, ok := byURL[urls[i]]
+		if !ok {
+			result.Unmatched = append(result.Unmatched, row)
+			continue
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		tx.Rollback()
+		return result, ErrNoMatches
+	}
+	graphInfo.Cnt = len(ids)
+	result.Matched = len(ids)
+
+	if insErr := insertGraph(ctx, tx, graphInfo, ids); insErr != nil {
+		tx.Rollback()
+		return models.ImportResult{}, insErr
+	}
+	if revisionErr := recordRevision(ctx, tx, graphInfo.GraphID, graphInfo.UserID, models.RevisionImport); revisionErr != nil {
+		tx.Rollback()
+		return models.ImportResult{}, revisionErr
+	}
+	errCreated := tx.GetContext(ctx, &result.Graph, "SELECT graph_id, coalesce(name, '') name, description, min_links, created FROM media.graphs WHERE graph_id=$1", graphInfo.GraphID)
+	if errCreated != nil {
+		tx.Rollback()
+		return models.ImportResult{}, errCreated
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		return models.ImportResult{}, commitErr
+	}
+	log.Printf("imported graph %s: %d sources, %d unmatched rows", graphInfo.GraphID, result.Matched, len(result.Unmatched))
+
+	return result, nil
+}
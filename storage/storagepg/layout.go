@@ -0,0 +1,172 @@
+package storagepg
+
+import (
+	"AlexSarva/media/analytics"
+	"AlexSarva/media/models"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// savedPosition position of a node of a saved graph in media.graphs_layout
+type savedPosition struct {
+	Node int64   `db:"node"`
+	X    float64 `db:"x"`
+	Y    float64 `db:"y"`
+}
+
+// CalculateLayout lays out the full graph with ForceAtlas2
+// and replaces the content of analytics.graph_layout with the positions
+func (d *PostgresDB) CalculateLayout(ctx context.Context, minLinks int) (models.LayoutInfo, error) {
+	if minLinks <= 0 {
+		minLinks = models.DefaultMinLinks
+	}
+
+	var edges []models.GraphEdge
+	errEdges := d.database.SelectContext(ctx, &edges, `
+select id_from, id_to, links from analytics.graph_edges
+where links >= $1
+order by id_from, id_to;`, minLinks)
+	if errEdges != nil {
+		log.Println("errEdges: ", errEdges)
+		return models.LayoutInfo{}, errEdges
+	}
+
+	started := time.Now()
+	network := analytics.NewNetwork(edges)
+	positions := network.ForceAtlas2(analytics.DefaultLayoutIterations, nil)
+
+	ids := make([]int64, 0, len(positions))
+	xs := make([]float64, 0, len(positions))
+	ys := make([]float64, 0, len(positions))
+	for _, id := range network.Nodes {
+		ids = append(ids, id)
+		xs = append(xs, positions[id].X)
+		ys = append(ys, positions[id].Y)
+	}
+	log.Printf("layout: %d nodes in %s", len(ids), time.Since(started))
+
+	tx, txErr := d.database.BeginTxx(ctx, nil)
+	if txErr != nil {
+		return models.LayoutInfo{}, txErr
+	}
+	if _, delErr := tx.ExecContext(ctx, "delete from analytics.graph_layout"); delErr != nil {
+		tx.Rollback()
+		return models.LayoutInfo{}, fmt.Errorf("clear layout: %w", delErr)
+	}
+	_, insErr := tx.ExecContext(ctx, `
+insert into analytics.graph_layout (id, x, y, min_links)
+select unnest($1::int8[]), unnest($2::float8[]), unnest($3::float8[]), $4;`, pq.Array(ids), pq.Array(xs), pq.Array(ys), minLinks)
+	if insErr != nil {
+		tx.Rollback()
+		return models.LayoutInfo{}, fmt.Errorf("insert layout: %w", insErr)
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		return models.LayoutInfo{}, commitErr
+	}
+
+	return models.LayoutInfo{
+		Nodes:    len(ids),
+		MinLinks: minLinks,
+		Updated:  time.Now(),
+	}, nil
+}
+
+// storedPositions positions of nodes of the saved graph from media.graphs_layout
+func (d *PostgresDB) storedPositions(ctx context.Context, graphID uuid.UUID) (map[int64]analytics.Position, error) {
+	var saved []savedPosition
+	errSaved := d.database.SelectContext(ctx, &saved, "select node, x, y from media.graphs_layout where graph_id = $1", graphID)
+	if errSaved != nil {
+		log.Println("errSaved: ", errSaved)
+		return nil, errSaved
+	}
+
+	positions := make(map[int64]analytics.Position, len(saved))
+	for _, position := range saved {
+		positions[position.Node] = analytics.Position{X: position.X, Y: position.Y}
+	}
+	return positions, nil
+}
+
+// setPositions sets positions of nodes found in positions, other nodes are left without one
+func setPositions(nodes []models.GraphNode, positions map[int64]analytics.Position) {
+	for i := range nodes {
+		position, ok := positions[nodes[i].ID]
+		if !ok {
+			continue
+		}
+		nodes[i].X = &position.X
+		nodes[i].Y = &position.Y
+	}
+}
+
+// layoutSavedGraph sets stored positions of nodes of the saved graph.
+// Reading a graph never lays it out, so nodes without a stored position have none,
+// positions are calculated and stored only by SaveGraphLayout
+func (d *PostgresDB) layoutSavedGraph(ctx context.Context, graphID uuid.UUID, graph *models.Graph) error {
+	positions, positionsErr := d.storedPositions(ctx, graphID)
+	if positionsErr != nil {
+		return positionsErr
+	}
+	setPositions(graph.Nodes, positions)
+	return nil
+}
+
+// SaveGraphLayout lays out nodes of the saved graph as they are shown with params around
+// the positions stored before and stores the new ones, so the graph opens with the same layout every time.
+// Available to the owner and editors of the graph
+func (d *PostgresDB) SaveGraphLayout(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, params models.GraphParams) (models.LayoutInfo, error) {
+	if err := checkGraphRole(ctx, d.database, userID, graphID, models.GraphRoleOwner, models.GraphRoleEditor); err != nil {
+		return models.LayoutInfo{}, err
+	}
+	if params.MinLinks == 0 {
+		errGraph := d.database.GetContext(ctx, &params.MinLinks, "select coalesce(min_links, $2) from media.graphs where graph_id = $1", graphID, models.DefaultMinLinks)
+		if errGraph != nil {
+			log.Println("errGraph: ", errGraph)
+			return models.LayoutInfo{}, errGraph
+		}
+	}
+
+	graph, graphErr := d.savedGraph(ctx, graphID, params)
+	if graphErr != nil {
+		return models.LayoutInfo{}, graphErr
+	}
+
+	fixed, fixedErr := d.storedPositions(ctx, graphID)
+	if fixedErr != nil {
+		return models.LayoutInfo{}, fixedErr
+	}
+
+	ids := make([]int64, 0, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		ids = append(ids, node.ID)
+	}
+	network := analytics.NewNetwork(graph.Edges)
+	network.AddNodes(ids...)
+	positions := network.ForceAtlas2(analytics.DefaultLayoutIterations, fixed)
+
+	xs := make([]float64, 0, len(ids))
+	ys := make([]float64, 0, len(ids))
+	for _, id := range ids {
+		xs = append(xs, positions[id].X)
+		ys = append(ys, positions[id].Y)
+	}
+	_, insErr := d.database.ExecContext(ctx, `
+insert into media.graphs_layout (graph_id, node, x, y)
+select $1, unnest($2::int8[]), unnest($3::float8[]), unnest($4::float8[])
+on conflict (graph_id, node) do nothing;`, graphID, pq.Array(ids), pq.Array(xs), pq.Array(ys))
+	if insErr != nil {
+		log.Println("insErr: ", insErr)
+		return models.LayoutInfo{}, insErr
+	}
+
+	return models.LayoutInfo{
+		Nodes:    len(ids),
+		MinLinks: params.MinLinks,
+		Updated:  time.Now(),
+	}, nil
+}
@@ -0,0 +1,22 @@
+package storagepg
+
+import (
+	"AlexSarva/media/analytics"
+	"AlexSarva/media/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetPositions(t *testing.T) {
+	nodes := []models.GraphNode{{ID: 1}, {ID: 2}}
+
+	setPositions(nodes, map[int64]analytics.Position{1: {X: 3, Y: -4}, 5: {X: 1, Y: 1}})
+
+	if assert.NotNil(t, nodes[0].X) && assert.NotNil(t, nodes[0].Y) {
+		assert.Equal(t, 3.0, *nodes[0].X)
+		assert.Equal(t, -4.0, *nodes[0].Y)
+	}
+	assert.Nil(t, nodes[1].X)
+	assert.Nil(t, nodes[1].Y)
+}
@@ -0,0 +1,75 @@
+package storagepg
+
+import (
+	"AlexSarva/media/analytics"
+	"AlexSarva/media/models"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// CalculateMetrics calculates PageRank, betweenness and weighted degrees of sources
+// and replaces the content of analytics.graph_metrics with the result
+func (d *PostgresDB) CalculateMetrics(ctx context.Context, minLinks int) (models.MetricsInfo, error) {
+	if minLinks <= 0 {
+		minLinks = models.DefaultMinLinks
+	}
+
+	var edges []models.GraphEdge
+	errEdges := d.database.SelectContext(ctx, &edges, `
+select id_from, id_to, links from analytics.graph_edges
+where links >= $1;`, minLinks)
+	if errEdges != nil {
+		log.Println("errEdges: ", errEdges)
+		return models.MetricsInfo{}, errEdges
+	}
+
+	started := time.Now()
+	network := analytics.NewNetwork(edges)
+	centralities := network.Centralities()
+
+	size := len(network.Nodes)
+	ids := make([]int64, 0, size)
+	ranks := make([]float64, 0, size)
+	betweenness := make([]float64, 0, size)
+	inDegrees := make([]int64, 0, size)
+	outDegrees := make([]int64, 0, size)
+	for _, id := range network.Nodes {
+		centrality := centralities[id]
+		ids = append(ids, id)
+		ranks = append(ranks, centrality.PageRank)
+		betweenness = append(betweenness, centrality.Betweenness)
+		inDegrees = append(inDegrees, centrality.InDegree)
+		outDegrees = append(outDegrees, centrality.OutDegree)
+	}
+	log.Printf("metrics: %d nodes in %s", size, time.Since(started))
+
+	tx, txErr := d.database.BeginTxx(ctx, nil)
+	if txErr != nil {
+		return models.MetricsInfo{}, txErr
+	}
+	if _, delErr := tx.ExecContext(ctx, "delete from analytics.graph_metrics"); delErr != nil {
+		tx.Rollback()
+		return models.MetricsInfo{}, fmt.Errorf("clear metrics: %w", delErr)
+	}
+	_, insErr := tx.ExecContext(ctx, `
+insert into analytics.graph_metrics (id, pagerank, betweenness, in_degree, out_degree, min_links)
+select unnest($1::int8[]), unnest($2::float8[]), unnest($3::float8[]), unnest($4::int8[]), unnest($5::int8[]), $6;`,
+		pq.Array(ids), pq.Array(ranks), pq.Array(betweenness), pq.Array(inDegrees), pq.Array(outDegrees), minLinks)
+	if insErr != nil {
+		tx.Rollback()
+		return models.MetricsInfo{}, fmt.Errorf("insert metrics: %w", insErr)
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		return models.MetricsInfo{}, commitErr
+	}
+
+	return models.MetricsInfo{
+		Nodes:    size,
+		MinLinks: minLinks,
+		Updated:  time.Now(),
+	}, nil
+}
@@ -0,0 +1,134 @@
+package storagepg
+
+import (
+	"AlexSarva/media/analytics"
+	"AlexSarva/media/models"
+	"context"
+	"errors"
+	"log"
+)
+
+// ErrNoPath error that occurs when sources are not connected within the hop limit
+var ErrNoPath = errors.New("no path between sources")
+
+// ErrPathsTruncated error that occurs when path search hits its limit before finding any path
+var ErrPathsTruncated = errors.New("path search limit is exceeded, reduce max_hops or raise min_links")
+
+// FindPaths searches the k shortest weighted paths from one source to another.
+// Only the part of analytics.graph_edges that can carry such paths is loaded:
+// half of the hops forward from the first source and the rest backward from the second one.
+// When either half hits the node cap the result is reported as truncated
+func (d *PostgresDB) FindPaths(ctx context.Context, fromID, toID, maxHops, minLinks int) (models.Graph, error) {
+	if maxHops <= 0 {
+		maxHops = models.DefaultPathHops
+	}
+	if maxHops > models.MaxPathHops {
+		maxHops = models.MaxPathHops
+	}
+
+	forwardHops := (maxHops + 1) / 2
+	_, forwardEdges, forwardCapped, forwardErr := d.expandGraph(ctx, []int64{int64(fromID)}, models.GraphParams{
+		Depth:     forwardHops,
+		MaxNodes:  models.MaxGraphNodes,
+		Direction: models.DirectionOut,
+		MinLinks:  minLinks,
+	})
+	if forwardErr != nil {
+		return models.Graph{}, forwardErr
+	}
+
+	edges := forwardEdges
+	capped := forwardCapped
+	if backwardHops := maxHops - forwardHops; backwardHops > 0 {
+		_, backwardEdges, backwardCapped, backwardErr := d.expandGraph(ctx, []int64{int64(toID)}, models.GraphParams{
+			Depth:     backwardHops,
+			MaxNodes:  models.MaxGraphNodes,
+			Direction: models.DirectionIn,
+			MinLinks:  minLinks,
+		})
+		if backwardErr != nil {
+			return models.Graph{}, backwardErr
+		}
+		edges = append(edges, backwardEdges...)
+		capped = capped || backwardCapped
+	}
+
+	network := analytics.NewNetwork(edges)
+	paths, truncated := network.FindPaths(int64(fromID), int64(toID), maxHops, models.PathsLimit)
+	// paths through neighbours dropped by the node cap are missing as well
+	truncated = truncated || capped
+	if len(paths) == 0 {
+		if truncated {
+			return models.Graph{}, ErrPathsTruncated
+		}
+		log.Printf("no path from %d to %d in %d hops", fromID, toID, maxHops)
+		return models.Graph{}, ErrNoPath
+	}
+
+	links := make(map[[2]int64]int32, len(edges))
+	for _, edge := range edges {
+		links[[2]int64{edge.From, edge.To}] = edge.Value
+	}
+
+	graph := models.Graph{PathsTruncated: truncated}
+	var ids []int64
+	known := make(map[int64]bool)
+	onShortest := make(map[[2]int64]bool)
+	seenEdges := make(map[[2]int64]bool)
+	for num, path := range paths {
+		graph.Paths = append(graph.Paths, models.GraphPath{
+			Nodes:  path.Nodes,
+			Hops:   len(path.Nodes) - 1,
+			Weight: path.Weight,
+		})
+		for i, id := range path.Nodes {
+			if !known[id] {
+				known[id] = true
+				ids = append(ids, id)
+			}
+			if i == 0 {
+				continue
+			}
+			key := [2]int64{path.Nodes[i-1], id}
+			if num == 0 {
+				onShortest[key] = true
+			}
+			if seenEdges[key] {
+				continue
+			}
+			seenEdges[key] = true
+			graph.Edges = append(graph.Edges, models.GraphEdge{
+				From:      key[0],
+				To:        key[1],
+				Value:     links[key],
+				Direction: models.DirectionOut,
+			})
+		}
+	}
+	for i := range graph.Edges {
+		graph.Edges[i].Dashes = !onShortest[[2]int64{graph.Edges[i].From, graph.Edges[i].To}]
+	}
+
+	nodes, nodesErr := d.selectNodes(ctx, ids)
+	if nodesErr != nil {
+		return models.Graph{}, nodesErr
+	}
+
+	shortest := make(map[int64]bool, len(paths[0].Nodes))
+	for _, id := range paths[0].Nodes {
+		shortest[id] = true
+	}
+	for i := range nodes {
+		switch {
+		case nodes[i].ID == int64(fromID) || nodes[i].ID == int64(toID):
+			nodes[i].Kind = models.NodeKindMain
+		case shortest[nodes[i].ID]:
+			nodes[i].Kind = models.NodeKindPath
+		default:
+			nodes[i].Kind = models.NodeKindSub
+		}
+	}
+	graph.Nodes = nodes
+
+	return graph, nil
+}
@@ -0,0 +1,230 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ErrNoRevision error that occurs when the graph has no requested revision
+var ErrNoRevision = errors.New("revision not found")
+
+// revisionRow revision with sources as postgres array
+type revisionRow struct {
+	models.GraphRevision
+	Sources pq.Int64Array `db:"sources"`
+}
+
+// revisionColumns columns of media.graphs_revisions returned as revisionRow
+const revisionColumns = "revision, action, user_id, coalesce(name, '') name, coalesce(description, '') description, min_links, sources, created"
+
+// recordRevision saves current state of the graph as the next revision,
+// must be called in the transaction that changed the graph
+func recordRevision(ctx context.Context, tx *sqlx.Tx, graphID uuid.UUID, userID uuid.UUID, action string) error {
+	_, err := tx.ExecContext(ctx, `
+insert into media.graphs_revisions (graph_id, revision, user_id, action, name, description, min_links, sources)
+select g.graph_id,
+       coalesce((select max(revision) from media.graphs_revisions r where r.graph_id = g.graph_id), 0) + 1,
+       $2, $3, g.name, g.description, g.min_links,
+       coalesce((select array_agg(e.node order by e.num) from media.graphs_elements e where e.graph_id = g.graph_id), '{}')
+from media.graphs g
+where g.graph_id = $1`, graphID, userID, action)
+	return err
+}
+
+// ensureRevision saves the state of graphs created before revisions were kept,
+// so their first change can be reverted
+func ensureRevision(ctx context.Context, tx *sqlx.Tx, graphID uuid.UUID, userID uuid.UUID) error {
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, "select exists(select 1 from media.graphs_revisions where graph_id = $1)", graphID); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	return recordRevision(ctx, tx, graphID, userID, models.RevisionInitial)
+}
+
+// GetGraphRevisions revisions of the graph available to the user, the latest first
+func (d *PostgresDB) GetGraphRevisions(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) ([]models.GraphRevision, error) {
+	if err := checkGraphRole(ctx, d.database, userID, graphID, models.GraphRoleOwner, models.GraphRoleEditor, models.GraphRoleViewer); err != nil {
+		return nil, err
+	}
+
+	var rows []revisionRow
+	err := d.database.SelectContext(ctx, &rows, "select "+revisionColumns+" from media.graphs_revisions where graph_id = $1 order by revision desc", graphID)
+	if err != nil {
+		log.Println("errRevisions: ", err)
+		return nil, err
+	}
+	revisions := make([]models.GraphRevision, len(rows))
+	for i, row := range rows {
+		revisions[i] = row.revision()
+	}
+	return revisions, nil
+}
+
+// DiffGraphRevisions difference between graphs built from two revisions of the graph available to the user
+func (d *PostgresDB) DiffGraphRevisions(ctx context.Context, userID uuid.UUID, query models.GraphDiffQuery) (models.GraphDiff, error) {
+	if err := checkGraphRole(ctx, d.database, userID, query.GraphID, models.GraphRoleOwner, models.GraphRoleEditor, models.GraphRoleViewer); err != nil {
+		return models.GraphDiff{}, err
+	}
+
+	var graphs [2]models.Graph
+	var sources [2][]int64
+	for i, number := range []int{query.From, query.To} {
+		revision, revisionErr := d.getRevision(ctx, query.GraphID, number)
+		if revisionErr != nil {
+			return models.GraphDiff{}, revisionErr
+		}
+		params := query.GraphParams
+		if params.MinLinks == 0 {
+			params.MinLinks = revision.MinLinks
+		}
+		graph, graphErr := d.egoGraph(ctx, revision.Sources, params)
+		if graphErr != nil {
+			return models.GraphDiff{}, graphErr
+		}
+		graphs[i], sources[i] = graph, revision.Sources
+	}
+
+	diff := diffGraphs(graphs[0], graphs[1])
+	diff.From, diff.To = query.From, query.To
+	diff.AddedSources, diff.RemovedSources = diffSources(sources[0], sources[1])
+	return diff, nil
+}
+
+// RestoreGraphRevision makes the state of the revision current, restoring is recorded as a new revision
+func (d *PostgresDB) RestoreGraphRevision(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, number int) (models.GraphCard, error) {
+	tx, txErr := d.database.BeginTxx(ctx, nil)
+	if txErr != nil {
+		return models.GraphCard{}, txErr
+	}
+	defer tx.Rollback()
+
+	if err := lockGraph(ctx, tx, userID, graphID); err != nil {
+		return models.GraphCard{}, err
+	}
+	var row revisionRow
+	if err := tx.GetContext(ctx, &row, "select "+revisionColumns+" from media.graphs_revisions where graph_id = $1 and revision = $2", graphID, number); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.GraphCard{}, ErrNoRevision
+		}
+		return models.GraphCard{}, err
+	}
+
+	_, updErr := tx.ExecContext(ctx, `update media.graphs
+set name = nullif($2, ''), description = $3, min_links = $4, cnt_elements = $5, updated = now()
+where graph_id = $1`, graphID, row.Name, row.Description, row.MinLinks, len(row.Sources))
+	if updErr != nil {
+		return models.GraphCard{}, updErr
+	}
+	if err := replaceGraphSources(ctx, tx, graphID, row.Sources); err != nil {
+		return models.GraphCard{}, err
+	}
+	if err := recordRevision(ctx, tx, graphID, userID, models.RevisionRestore); err != nil {
+		return models.GraphCard{}, err
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		return models.GraphCard{}, commitErr
+	}
+	log.Printf("graph %s restored to revision %d", graphID, number)
+
+	return d.getGraphCard(ctx, graphID)
+}
+
+// getRevision revision of the graph by its number
+func (d *PostgresDB) getRevision(ctx context.Context, graphID uuid.UUID, number int) (models.GraphRevision, error) {
+	var row revisionRow
+	err := d.database.GetContext(ctx, &row, "select "+revisionColumns+" from media.graphs_revisions where graph_id = $1 and revision = $2", graphID, number)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.GraphRevision{}, ErrNoRevision
+		}
+		return models.GraphRevision{}, err
+	}
+	return row.revision(), nil
+}
+
+func (row revisionRow) revision() models.GraphRevision {
+	revision := row.GraphRevision
+	revision.Sources = []int64(row.Sources)
+	if revision.Sources == nil {
+		revision.Sources = []int64{}
+	}
+	return revision
+}
+
+// diffGraphs nodes and edges added to and removed from graph from
+func diffGraphs(from, to models.Graph) models.GraphDiff {
+	diff := models.GraphDiff{
+		AddedNodes:   []models.GraphNode{},
+		RemovedNodes: []models.GraphNode{},
+		AddedEdges:   []models.GraphEdge{},
+		RemovedEdges: []models.GraphEdge{},
+	}
+
+	fromNodes := make(map[int64]bool, len(from.Nodes))
+	for _, node := range from.Nodes {
+		fromNodes[node.ID] = true
+	}
+	toNodes := make(map[int64]bool, len(to.Nodes))
+	for _, node := range to.Nodes {
+		toNodes[node.ID] = true
+		if !fromNodes[node.ID] {
+			diff.AddedNodes = append(diff.AddedNodes, node)
+		}
+	}
+	for _, node := range from.Nodes {
+		if !toNodes[node.ID] {
+			diff.RemovedNodes = append(diff.RemovedNodes, node)
+		}
+	}
+
+	type edgeKey struct{ from, to int64 }
+	fromEdges := make(map[edgeKey]bool, len(from.Edges))
+	for _, edge := range from.Edges {
+		fromEdges[edgeKey{edge.From, edge.To}] = true
+	}
+	toEdges := make(map[edgeKey]bool, len(to.Edges))
+	for _, edge := range to.Edges {
+		toEdges[edgeKey{edge.From, edge.To}] = true
+		if !fromEdges[edgeKey{edge.From, edge.To}] {
+			diff.AddedEdges = append(diff.AddedEdges, edge)
+		}
+	}
+	for _, edge := range from.Edges {
+		if !toEdges[edgeKey{edge.From, edge.To}] {
+			diff.RemovedEdges = append(diff.RemovedEdges, edge)
+		}
+	}
+	return diff
+}
+
+// diffSources saved sources added to and removed from list from
+func diffSources(from, to []int64) (added []int64, removed []int64) {
+	added, removed = []int64{}, []int64{}
+	inFrom := make(map[int64]bool, len(from))
+	for _, id := range from {
+		inFrom[id] = true
+	}
+	inTo := make(map[int64]bool, len(to))
+	for _, id := range to {
+		inTo[id] = true
+		if !inFrom[id] {
+			added = append(added, id)
+		}
+	}
+	for _, id := range from {
+		if !inTo[id] {
+			removed = append(removed, id)
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,30 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffGraphs(t *testing.T) {
+	from := models.Graph{
+		Nodes: []models.GraphNode{{ID: 1}, {ID: 2}, {ID: 3}},
+		Edges: []models.GraphEdge{{From: 1, To: 2}, {From: 2, To: 3}},
+	}
+	to := models.Graph{
+		Nodes: []models.GraphNode{{ID: 1}, {ID: 2}, {ID: 4}},
+		Edges: []models.GraphEdge{{From: 1, To: 2}, {From: 2, To: 1}, {From: 4, To: 1}},
+	}
+
+	diff := diffGraphs(from, to)
+
+	assert.Equal(t, []models.GraphNode{{ID: 4}}, diff.AddedNodes)
+	assert.Equal(t, []models.GraphNode{{ID: 3}}, diff.RemovedNodes)
+	assert.Equal(t, []models.GraphEdge{{From: 2, To: 1}, {From: 4, To: 1}}, diff.AddedEdges)
+	assert.Equal(t, []models.GraphEdge{{From: 2, To: 3}}, diff.RemovedEdges)
+
+	added, removed := diffSources([]int64{1, 2, 3}, []int64{3, 1, 5})
+	assert.Equal(t, []int64{5}, added)
+	assert.Equal(t, []int64{2}, removed)
+}
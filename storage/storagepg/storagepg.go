@@ -2,6 +2,7 @@ package storagepg
 
 import (
 	"AlexSarva/media/models"
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -26,6 +27,8 @@ func NewPostgresDBConnection(config string) *PostgresDB {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	db.MustExec(ddl)
+	db.MustExec(ddlNormURL)
 	return &PostgresDB{
 		database: db,
 	}
@@ -69,300 +72,130 @@ func (d *PostgresDB) Ping() bool {
 //	return nil
 //}
 
-func (d *PostgresDB) GetSearch(text string) ([]models.SearchRes, error) {
+func (d *PostgresDB) GetSearch(ctx context.Context, text string) ([]models.SearchRes, error) {
 	var srcs []models.SearchRes
 	log.Println(text)
 	query := "select id, url, coalesce(title, url) title from analytics.graph_nodes where search_field ilike '%" + text + "%' order by id limit 5;"
-	err := d.database.Select(&srcs, query)
+	err := d.database.SelectContext(ctx, &srcs, query)
 	if err != nil {
 		log.Println(err)
 	}
 	return srcs, err
 }
 
-func (d *PostgresDB) GetGraphByURL(text string) (models.Graph, error) {
-	var graph models.Graph
+func (d *PostgresDB) GetGraphByURL(ctx context.Context, text string, params models.GraphParams) (models.Graph, error) {
 	var mainNode models.GraphNode
-	var graphSubNodes []models.GraphNode
-	var graphNodes []models.GraphNode
-	var graphRawEdges []models.GraphEdge
-	var graphEdges []models.GraphEdge
 
-	errNode := d.database.Get(&mainNode, "SELECT id, url, links, coalesce(title, url) title FROM analytics.graph_nodes WHERE url=$1", text)
+	errNode := d.database.GetContext(ctx, &mainNode, "SELECT id, url, links, coalesce(title, url) title FROM analytics.graph_nodes WHERE url=$1", text)
 	if errNode != nil {
 		log.Println("errNode: ", errNode)
 		return models.Graph{}, errNode
 	}
-	mainNode.Color = models.GraphNodeColor{
-		Background: "rgba(8, 217, 174, 0.9)",
-		Border:     "rgba(96, 169, 191, 0.8)",
-		Highlight: models.GraphNodeColorStyle{
-			Background: "rgb(187, 163, 217)",
-			Border:     "rgb(187, 163, 217)",
-		},
-		Hover: models.GraphNodeColorStyle{
-			Background: "rgba(8, 217, 174, 0.9)",
-			Border:     "rgb(211, 114, 214)",
-		},
-	}
-	graphNodes = append(graphNodes, mainNode)
-
-	errSubNodes := d.database.Select(&graphSubNodes, `
-with main_id as (
-    select id from analytics.graph_nodes where url = $1),
-    all_nodes as (
-select distinct unnest(array[id_from, id_to]) ids from analytics.graph_edges
-where exists(select 1 from main_id where main_id.id = graph_edges.id_from)
-and links >= 5)
-select id, url, links, coalesce(title, url) title FROM analytics.graph_nodes
-where 1=1
-and not exists(select 1 from main_id where main_id.id = graph_nodes.id)
-and exists(select 1 from all_nodes where all_nodes.ids = graph_nodes.id);`, text)
-	if errSubNodes != nil {
-		log.Println("errSubNode: ", errSubNodes)
-		return models.Graph{}, errSubNodes
-	}
 
-	for _, node := range graphSubNodes {
-		node.Color = models.GraphNodeColor{
-			Background: "rgba(252, 213, 173, 0.9)",
-			Border:     "rgb(252, 213, 173)",
-			Highlight: models.GraphNodeColorStyle{
-				Background: "rgb(187, 163, 217)",
-				Border:     "rgb(187, 163, 217)",
-			},
-			Hover: models.GraphNodeColorStyle{
-				Background: "rgba(252, 213, 173, 0.9)",
-				Border:     "rgb(211, 114, 214)",
-			},
-		}
-		graphNodes = append(graphNodes, node)
+	graph, graphErr := d.egoGraph(ctx, []int64{mainNode.ID}, params)
+	if graphErr != nil {
+		return models.Graph{}, graphErr
 	}
+	markIsolated(&graph)
 
-	graph.Nodes = graphNodes
+	return graph, nil
+}
 
-	errEdges := d.database.Select(&graphRawEdges, `
-with main_id as (
-select id from analytics.graph_nodes where url = $1)
-select id_from, id_to from analytics.graph_edges
-where exists(select 1 from main_id where main_id.id = graph_edges.id_from)
-and links >= 5;`, text)
-	if errEdges != nil {
-		log.Println("errEdges: ", errEdges)
-		return models.Graph{}, errEdges
+// GetGraphByUUID saved graph available to the user as owner, editor or viewer
+func (d *PostgresDB) GetGraphByUUID(ctx context.Context, userID uuid.UUID, graphID uuid.UUID, params models.GraphParams) (models.GraphExtended, error) {
+	if err := checkGraphRole(ctx, d.database, userID, graphID, models.GraphRoleOwner, models.GraphRoleEditor, models.GraphRoleViewer); err != nil {
+		return models.GraphExtended{}, err
 	}
+	return d.savedGraph(ctx, graphID, params)
+}
 
-	for _, edge := range graphRawEdges {
-		edge.Dashes = true
-		graphEdges = append(graphEdges, edge)
-	}
+// savedSeeds sources of the saved graph in their order and params of its expansion,
+// the saved threshold of links is used unless the request overrides it
+func (d *PostgresDB) savedSeeds(ctx context.Context, graphID uuid.UUID, params models.GraphParams) ([]int64, models.GraphParams, error) {
+	var seeds []int64
+	var minLinks int
 
-	graph.Edges = graphEdges
+	errGraph := d.database.GetContext(ctx, &minLinks, "select coalesce(min_links, $2) from media.graphs where graph_id = $1", graphID, models.DefaultMinLinks)
+	if errGraph != nil {
+		log.Println("errGraph: ", errGraph)
+		return nil, params, errGraph
+	}
+	if params.MinLinks == 0 {
+		params.MinLinks = minLinks
+	}
 
-	return graph, nil
+	errSeeds := d.database.SelectContext(ctx, &seeds, `select node from media.graphs_elements
+where graph_id = $1
+order by num;`, graphID)
+	if errSeeds != nil {
+		log.Println("errSeeds: ", errSeeds)
+		return nil, params, errSeeds
+	}
+	return seeds, params, nil
 }
 
-func (d *PostgresDB) GetGraphByUUID(graphID uuid.UUID) (models.GraphExtended, error) {
-	var graph models.GraphExtended
-	var graphRawEdges []models.GraphEdge
-	var graphEdges []models.GraphEdge
-	var listNodes []models.GraphNode
-	var mainRawNodes []models.GraphNode
-	var mainNodes []models.GraphNode
-	var subRawNodes []models.GraphNode
-
-	errEdges := d.database.Select(&graphRawEdges, `with nodes as (
-select node from media.graphs_elements
-            where graph_id = $1)
-select id_from, id_to from analytics.graph_edges
-where 1=1
-and links >= 5
-and exists(select 1 from nodes where nodes.node = graph_edges.id_from);`, graphID)
-	if errEdges != nil {
-		log.Println("errEdges: ", errEdges)
-		return models.GraphExtended{}, errEdges
+// savedGraph ego graph of saved sources with stored layout
+func (d *PostgresDB) savedGraph(ctx context.Context, graphID uuid.UUID, params models.GraphParams) (models.GraphExtended, error) {
+	seeds, params, seedsErr := d.savedSeeds(ctx, graphID, params)
+	if seedsErr != nil {
+		return models.GraphExtended{}, seedsErr
 	}
 
-	for _, edge := range graphRawEdges {
-		edge.Dashes = true
-		graphEdges = append(graphEdges, edge)
+	graph, graphErr := d.egoGraph(ctx, seeds, params)
+	if graphErr != nil {
+		return models.GraphExtended{}, graphErr
 	}
 
-	errNode := d.database.Select(&mainRawNodes, `with nodes as (
-    select node, num from media.graphs_elements
-    where graph_id = $1)
-SELECT id, url, links, coalesce(title, url) title FROM analytics.graph_nodes
-inner join nodes on nodes.node = graph_nodes.id
-where 1=1
-and exists(select 1 from nodes where nodes.node = graph_nodes.id)
-order by nodes.num;`, graphID)
-	if errNode != nil {
-		log.Println("errNode: ", errNode)
-		return models.GraphExtended{}, errNode
-	}
-
-	for _, node := range mainRawNodes {
-		node.Color = models.GraphNodeColor{
-			Background: "rgba(8, 217, 174, 0.9)",
-			Border:     "rgba(96, 169, 191, 0.8)",
-			Highlight: models.GraphNodeColorStyle{
-				Background: "rgb(187, 163, 217)",
-				Border:     "rgb(187, 163, 217)",
-			},
-			Hover: models.GraphNodeColorStyle{
-				Background: "rgba(8, 217, 174, 0.9)",
-				Border:     "rgb(211, 114, 214)",
-			},
-		}
-		mainNodes = append(mainNodes, node)
-		listNodes = append(listNodes, node)
-	}
-
-	errSubNode := d.database.Select(&subRawNodes, `
-with nodes as (
-    select node from media.graphs_elements
-    where graph_id = $1),
-    all_nodes as (
-        select distinct unnest(array[id_from, id_to]) ids from analytics.graph_edges
-        where 1=1
-        and exists(select 1 from nodes where nodes.node = graph_edges.id_from)
-        and links >= 5)
-select id, url, links, coalesce(title, url) title FROM analytics.graph_nodes
-where 1=1
-  and not exists(select 1 from nodes where nodes.node = graph_nodes.id)
-  and exists(select 1 from all_nodes where all_nodes.ids = graph_nodes.id);`, graphID)
-	if errSubNode != nil {
-		log.Println("errSubNode: ", errSubNode)
-		return models.GraphExtended{}, errSubNode
-	}
-
-	for _, node := range subRawNodes {
-		node.Color = models.GraphNodeColor{
-			Background: "rgba(252, 213, 173, 0.9)",
-			Border:     "rgb(252, 213, 173)",
-			Highlight: models.GraphNodeColorStyle{
-				Background: "rgb(187, 163, 217)",
-				Border:     "rgb(187, 163, 217)",
-			},
-			Hover: models.GraphNodeColorStyle{
-				Background: "rgba(252, 213, 173, 0.9)",
-				Border:     "rgb(211, 114, 214)",
-			},
+	if layoutErr := d.layoutSavedGraph(ctx, graphID, &graph); layoutErr != nil {
+		return models.GraphExtended{}, layoutErr
+	}
+
+	// seeds are always visited first so the list of saved sources is the head of nodes
+	var listNodes []models.GraphNode
+	isSeed := make(map[int64]bool, len(seeds))
+	for _, id := range seeds {
+		isSeed[id] = true
+	}
+	for _, node := range graph.Nodes {
+		if isSeed[node.ID] {
+			listNodes = append(listNodes, node)
 		}
-		mainNodes = append(mainNodes, node)
 	}
 
-	graph.Edges = graphEdges
-	graph.Nodes = mainNodes
-	graph.NodesList = listNodes
+	annotations, annotationsErr := graphAnnotations(ctx, d.database, graphID)
+	if annotationsErr != nil {
+		return models.GraphExtended{}, annotationsErr
+	}
 
-	return graph, nil
+	return models.GraphExtended{
+		Nodes:       graph.Nodes,
+		Edges:       graph.Edges,
+		NodesList:   listNodes,
+		Annotations: annotations,
+	}, nil
 }
 
-func (d *PostgresDB) GetGraphByID(id int) (models.Graph, error) {
-	var graph models.Graph
+func (d *PostgresDB) GetGraphByID(ctx context.Context, id int, params models.GraphParams) (models.Graph, error) {
 	var mainNode models.GraphNode
-	var graphSubNodes []models.GraphNode
-	var graphNodes []models.GraphNode
-	var graphRawEdges []models.GraphEdge
-	var graphEdges []models.GraphEdge
 
-	errEdges := d.database.Select(&graphRawEdges, `
-select id_from, id_to from analytics.graph_edges
-where id_from = $1 and links >= 5;`, id)
-	if errEdges != nil {
-		log.Println("errEdges: ", errEdges)
-		return models.Graph{}, errEdges
-	}
-
-	for _, edge := range graphRawEdges {
-		edge.Dashes = true
-		graphEdges = append(graphEdges, edge)
-	}
-
-	if len(graphRawEdges) == 0 {
-		graphEdges = append(graphEdges, models.GraphEdge{
-			From: int64(id),
-			To:   int64(id),
-			//Value:  0,
-			Dashes: false,
-		})
-	}
-
-	errNode := d.database.Get(&mainNode, "SELECT id, url, links, coalesce(title, url) title FROM analytics.graph_nodes WHERE id=$1", id)
+	errNode := d.database.GetContext(ctx, &mainNode, "SELECT id, url, links, coalesce(title, url) title FROM analytics.graph_nodes WHERE id=$1", id)
 	if errNode != nil {
 		log.Println("errNode: ", errNode)
 		return models.Graph{}, errNode
 	}
-	if len(graphRawEdges) == 0 {
-		mainNode.Color = models.GraphNodeColor{
-			Background: "rgba(155, 168, 171, 0.9)",
-			Border:     "rgba(155, 168, 171, 0.9)",
-			Highlight: models.GraphNodeColorStyle{
-				Background: "rgba(155, 168, 171, 0.9)",
-				Border:     "rgba(155, 168, 171, 0.9)",
-			},
-			Hover: models.GraphNodeColorStyle{
-				Background: "rgba(8, 217, 174, 0.9)",
-				Border:     "rgb(211, 114, 214)",
-			},
-		}
-	} else {
-		mainNode.Color = models.GraphNodeColor{
-			Background: "rgba(8, 217, 174, 0.9)",
-			Border:     "rgba(96, 169, 191, 0.8)",
-			Highlight: models.GraphNodeColorStyle{
-				Background: "rgb(187, 163, 217)",
-				Border:     "rgb(187, 163, 217)",
-			},
-			Hover: models.GraphNodeColorStyle{
-				Background: "rgba(8, 217, 174, 0.9)",
-				Border:     "rgb(211, 114, 214)",
-			},
-		}
-	}
 
-	graphNodes = append(graphNodes, mainNode)
-
-	errSubNodes := d.database.Select(&graphSubNodes, `
-with 
-    all_nodes as (
-select distinct unnest(array[id_from, id_to]) ids from analytics.graph_edges
-where graph_edges.id_from = $1 and links >= 5)
-select id, url, links, coalesce(title, url) title FROM analytics.graph_nodes
-where 1=1
-and id != $1
-and exists(select 1 from all_nodes where all_nodes.ids = graph_nodes.id);`, id)
-	if errSubNodes != nil {
-		log.Println("errSubNode: ", errSubNodes)
-		return models.Graph{}, errSubNodes
+	graph, graphErr := d.egoGraph(ctx, []int64{mainNode.ID}, params)
+	if graphErr != nil {
+		return models.Graph{}, graphErr
 	}
-
-	for _, node := range graphSubNodes {
-		node.Color = models.GraphNodeColor{
-			Background: "rgba(252, 213, 173, 0.9)",
-			Border:     "rgb(252, 213, 173)",
-			Highlight: models.GraphNodeColorStyle{
-				Background: "rgb(187, 163, 217)",
-				Border:     "rgb(187, 163, 217)",
-			},
-			Hover: models.GraphNodeColorStyle{
-				Background: "rgba(252, 213, 173, 0.9)",
-				Border:     "rgb(211, 114, 214)",
-			},
-		}
-		graphNodes = append(graphNodes, node)
-	}
-
-	graph.Nodes = graphNodes
-	graph.Edges = graphEdges
+	markIsolated(&graph)
 
 	return graph, nil
 }
 
-func (d *PostgresDB) GetSourceInfoByURL(text string) (models.GraphNode, error) {
+func (d *PostgresDB) GetSourceInfoByURL(ctx context.Context, text string) (models.GraphNode, error) {
 	var srcs models.GraphNode
-	errNode := d.database.Get(&srcs, "SELECT id, url, links, coalesce(title, url) title FROM analytics.graph_nodes WHERE url=$1", text)
+	errNode := d.database.GetContext(ctx, &srcs, "SELECT "+nodeColumns+" FROM "+nodeTables+" WHERE url=$1", text)
 	if errNode != nil {
 		log.Println("errNode: ", errNode)
 		return models.GraphNode{}, errNode
@@ -370,9 +203,9 @@ func (d *PostgresDB) GetSourceInfoByURL(text string) (models.GraphNode, error) {
 	return srcs, nil
 }
 
-func (d *PostgresDB) GetSourceInfoByID(id int) (models.GraphNode, error) {
+func (d *PostgresDB) GetSourceInfoByID(ctx context.Context, id int) (models.GraphNode, error) {
 	var srcs models.GraphNode
-	errNode := d.database.Get(&srcs, "SELECT id, url, links, coalesce(title, url) title FROM analytics.graph_nodes WHERE id=$1", id)
+	errNode := d.database.GetContext(ctx, &srcs, "SELECT "+nodeColumns+" FROM "+nodeTables+" WHERE id=$1", id)
 	if errNode != nil {
 		log.Println("errNode: ", errNode)
 		return models.GraphNode{}, errNode
@@ -380,10 +213,10 @@ func (d *PostgresDB) GetSourceInfoByID(id int) (models.GraphNode, error) {
 	return srcs, nil
 }
 
-func (d *PostgresDB) AddNewGraph(graphInfo models.NewGraph) (models.NewGraphResp, error) {
+func (d *PostgresDB) AddNewGraph(ctx context.Context, graphInfo models.NewGraph) (models.NewGraphResp, error) {
 	log.Println("Работаем с базой")
-	tx := d.database.MustBegin()
-	resInsert, resErr := tx.NamedExec("INSERT INTO media.graphs (user_id, graph_id, cnt_elements, description) VALUES (:user_id, :graph_id, :cnt_elements, :description) on conflict(graph_id) do nothing", &graphInfo)
+	tx := d.database.MustBeginTx(ctx, nil)
+	resInsert, resErr := tx.NamedExecContext(ctx, "INSERT INTO media.graphs (user_id, graph_id, name, cnt_elements, description, min_links) VALUES (:user_id, :graph_id, :name, :cnt_elements, :description, :min_links) on conflict(graph_id) do nothing", &graphInfo)
 	if resErr != nil {
 		tx.Commit()
 		return models.NewGraphResp{}, resErr
@@ -400,45 +233,56 @@ func (d *PostgresDB) AddNewGraph(graphInfo models.NewGraph) (models.NewGraphResp
 
 	nodesRows := 0
 	for _, item := range graphInfo.Sources {
-		res := tx.MustExec("INSERT INTO media.graphs_elements (graph_id, node, num) VALUES ($1, $2, $3)", graphInfo.GraphID, item.ID, item.Num)
+		res := tx.MustExecContext(ctx, "INSERT INTO media.graphs_elements (graph_id, node, num) VALUES ($1, $2, $3)", graphInfo.GraphID, item.ID, item.Num)
 		rows, _ := res.RowsAffected()
 		nodesRows += int(rows)
 	}
 	log.Println("Загружено строк в media.graphs_elements: ", nodesRows)
 
+	if revisionErr := recordRevision(ctx, tx, graphInfo.GraphID, graphInfo.UserID, models.RevisionCreate); revisionErr != nil {
+		tx.Rollback()
+		return models.NewGraphResp{}, revisionErr
+	}
+
 	commitErr := tx.Commit()
 	if commitErr != nil {
 		return models.NewGraphResp{}, commitErr
 	}
 
 	var srcs models.NewGraphResp
-	errCreated := d.database.Get(&srcs, "SELECT graph_id, description, created FROM media.graphs WHERE graph_id=$1", graphInfo.GraphID)
+	errCreated := d.database.GetContext(ctx, &srcs, "SELECT graph_id, coalesce(name, '') name, description, min_links, created FROM media.graphs WHERE graph_id=$1", graphInfo.GraphID)
 	if errCreated != nil {
 		return models.NewGraphResp{}, errCreated
 	}
 	return srcs, nil
 }
 
-func (d *PostgresDB) GetGraphCards(userID uuid.UUID) ([]models.GraphCard, error) {
-	var graphCards []models.GraphCard
-	graphCardsErr := d.database.Select(&graphCards, `select graph_id, cnt_elements, description, created from media.graphs
-where 1=1
-and is_del = 0
-and user_id = $1
-order by created desc;
-`, userID)
+// GetGraphCards graphs of the user and graphs shared with the user filtered, sorted and paginated by query
+// with the number of cards matching the filter
+func (d *PostgresDB) GetGraphCards(ctx context.Context, userID uuid.UUID, query models.GraphCardsQuery) ([]models.GraphCard, int, error) {
+	where, args := graphCardsFilter(query, userID)
+
+	var total int
+	totalErr := d.database.GetContext(ctx, &total, graphCardsCTE+"select count(*) from cards"+where, args...)
+	if totalErr != nil {
+		log.Println("Нет загруженных графов ", totalErr)
+		return []models.GraphCard{}, 0, totalErr
+	}
+
+	graphCards := []models.GraphCard{}
+	graphCardsErr := d.database.SelectContext(ctx, &graphCards, graphCardsCTE+"select * from cards"+where+graphCardsOrder(query), args...)
 	if graphCardsErr != nil {
 		log.Println("Нет загруженных графов ", graphCardsErr)
-		return []models.GraphCard{}, graphCardsErr
+		return []models.GraphCard{}, 0, graphCardsErr
 	}
 
-	return graphCards, nil
+	return graphCards, total, nil
 }
 
-func (d *PostgresDB) DeleteGraphCard(userID, graphID uuid.UUID) ([]models.GraphCard, error) {
-	sqlStr := fmt.Sprintf("update media.graphs set is_del=1 where user_id = '%s' and graph_id = '%s'", userID.String(), graphID.String())
+func (d *PostgresDB) DeleteGraphCard(ctx context.Context, userID, graphID uuid.UUID) ([]models.GraphCard, error) {
+	sqlStr := fmt.Sprintf("update media.graphs set is_del=1, deleted=now() where is_del = 0 and user_id = '%s' and graph_id = '%s'", userID.String(), graphID.String())
 	log.Println(sqlStr)
-	ret, err := d.database.Exec(sqlStr)
+	ret, err := d.database.ExecContext(ctx, sqlStr)
 	if err != nil {
 		log.Printf("update failed, err:%v\n", err)
 		return []models.GraphCard{}, err
@@ -449,22 +293,11 @@ func (d *PostgresDB) DeleteGraphCard(userID, graphID uuid.UUID) ([]models.GraphC
 	}
 	log.Printf("update success, affected rows:%d\n", affectedMainRows)
 
-	var graphCards []models.GraphCard
-	graphCardsErr := d.database.Select(&graphCards, `select graph_id, cnt_elements, description, created from media.graphs
-where 1=1
-and is_del = 0
-and user_id = $1
-order by created desc;
-`, userID)
-	if graphCardsErr != nil {
-		log.Println("Нет загруженных графов ", graphCardsErr)
-		return []models.GraphCard{}, graphCardsErr
-	}
-
-	return graphCards, nil
+	graphCards, _, err := d.GetGraphCards(ctx, userID, models.GraphCardsQuery{Sort: models.GraphSortCreated, Desc: true})
+	return graphCards, err
 }
 
-func (d *PostgresDB) GetFullGraph() (models.Graph, error) {
+func (d *PostgresDB) GetFullGraph(ctx context.Context, minLinks int, sizeBy string) (models.Graph, error) {
 	var graph models.Graph
 	//var mainNode models.GraphNode
 	var graphSubNodes []models.GraphNode
@@ -472,9 +305,13 @@ func (d *PostgresDB) GetFullGraph() (models.Graph, error) {
 	var graphRawEdges []models.GraphEdge
 	var graphEdges []models.GraphEdge
 
-	errEdges := d.database.Select(&graphRawEdges, `
-select id_from, id_to from analytics.graph_edges
-where links >= 5;`)
+	if minLinks <= 0 {
+		minLinks = models.DefaultMinLinks
+	}
+
+	errEdges := d.database.SelectContext(ctx, &graphRawEdges, `
+select id_from, id_to, links from analytics.graph_edges
+where links >= $1;`, minLinks)
 	if errEdges != nil {
 		log.Println("errEdges: ", errEdges)
 		return models.Graph{}, errEdges
@@ -485,32 +322,15 @@ where links >= 5;`)
 		graphEdges = append(graphEdges, edge)
 	}
 
-	errSubNodes := d.database.Select(&graphSubNodes, `
-with 
-    all_nodes as (
-select distinct unnest(array[id_from, id_to]) ids from analytics.graph_edges
-where links >= 5)
-select id, url, links, coalesce(title, url) title FROM analytics.graph_nodes
-where 1=1
-and exists(select 1 from all_nodes where all_nodes.ids = graph_nodes.id);`)
+	errSubNodes := d.database.SelectContext(ctx, &graphSubNodes, fullGraphNodes+";", minLinks)
 	if errSubNodes != nil {
 		log.Println("errSubNode: ", errSubNodes)
 		return models.Graph{}, errSubNodes
 	}
 
+	applySize(graphSubNodes, sizeBy)
 	for _, node := range graphSubNodes {
-		node.Color = models.GraphNodeColor{
-			Background: "rgba(252, 213, 173, 0.9)",
-			Border:     "rgb(252, 213, 173)",
-			Highlight: models.GraphNodeColorStyle{
-				Background: "rgb(187, 163, 217)",
-				Border:     "rgb(187, 163, 217)",
-			},
-			Hover: models.GraphNodeColorStyle{
-				Background: "rgba(252, 213, 173, 0.9)",
-				Border:     "rgb(211, 114, 214)",
-			},
-		}
+		node.Kind = models.NodeKindSub
 		graphNodes = append(graphNodes, node)
 	}
 
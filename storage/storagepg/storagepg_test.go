@@ -0,0 +1,48 @@
+package storagepg
+
+import (
+	"AlexSarva/media/admin"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// testDB database of DATABASE_PG_URI with a new user, the test is skipped without it
+func testDB(t *testing.T) (*PostgresDB, uuid.UUID) {
+	uri := os.Getenv("DATABASE_PG_URI")
+	if uri == "" {
+		t.Skip("DATABASE_PG_URI is not set")
+	}
+	admin.NewAdminDBConnection(uri)
+	database := NewPostgresDBConnection(uri)
+	t.Cleanup(func() {
+		database.database.Close()
+	})
+	return database, testUser(t, database)
+}
+
+// testUser registers a new user, removed with its graphs after the test
+func testUser(t *testing.T, database *PostgresDB) uuid.UUID {
+	t.Helper()
+	userID := uuid.New()
+	database.database.MustExec("INSERT INTO public.users (id, username, email, passwd) VALUES ($1, 'storage-test', $2, '-')", userID, userID.String()+"@storage.test")
+	t.Cleanup(func() {
+		for _, table := range graphDependents {
+			database.database.MustExec("DELETE FROM "+table+" WHERE graph_id IN (SELECT graph_id FROM media.graphs WHERE user_id=$1)", userID)
+		}
+		database.database.MustExec("DELETE FROM media.graphs_access WHERE user_id=$1", userID)
+		database.database.MustExec("DELETE FROM media.graphs WHERE user_id=$1", userID)
+		database.database.MustExec("DELETE FROM public.users WHERE id=$1", userID)
+	})
+	return userID
+}
+
+// testGraph inserts a graph of the user with one source
+func testGraph(t *testing.T, database *PostgresDB, userID uuid.UUID) uuid.UUID {
+	t.Helper()
+	graphID := uuid.New()
+	database.database.MustExec("insert into media.graphs (user_id, graph_id, name, cnt_elements) values ($1, $2, 'test', 1)", userID, graphID)
+	database.database.MustExec("insert into media.graphs_elements (graph_id, node, num) values ($1, 1, 1)", graphID)
+	return graphID
+}
@@ -0,0 +1,105 @@
+package storagepg
+
+import (
+	"AlexSarva/media/models"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// graphDependents tables with rows of saved graphs, purged before the graph itself
+var graphDependents = []string{
+	"media.graphs_elements",
+	"media.graphs_layout",
+	"media.graphs_annotations",
+	"media.graphs_revisions",
+	"media.graphs_access",
+	"media.graphs_links",
+}
+
+// GetDeletedGraphCards graphs of the user in trash, the latest deleted first
+func (d *PostgresDB) GetDeletedGraphCards(ctx context.Context, userID uuid.UUID) ([]models.DeletedGraphCard, error) {
+	graphCards := []models.DeletedGraphCard{}
+	err := d.database.SelectContext(ctx, &graphCards, `select `+graphCardColumns+`, coalesce(deleted, updated, created) deleted from media.graphs
+where is_del = 1
+and user_id = $1
+order by deleted desc nulls last;`, userID)
+	if err != nil {
+		log.Println("errDeleted: ", err)
+		return []models.DeletedGraphCard{}, err
+	}
+	return graphCards, nil
+}
+
+// RestoreGraphCard takes the user graph out of trash
+func (d *PostgresDB) RestoreGraphCard(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) (models.GraphCard, error) {
+	res, err := d.database.ExecContext(ctx, `update media.graphs set is_del = 0, deleted = null, updated = now()
+where graph_id = $1
+and user_id = $2
+and is_del = 1`, graphID, userID)
+	if err != nil {
+		return models.GraphCard{}, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return models.GraphCard{}, ErrNoData
+	}
+	return d.getGraphCard(ctx, graphID)
+}
+
+// PurgeGraphCard permanently deletes the user graph from trash
+func (d *PostgresDB) PurgeGraphCard(ctx context.Context, userID uuid.UUID, graphID uuid.UUID) error {
+	purged, err := d.purgeGraphs(ctx, "graph_id = $1 and user_id = $2", graphID, userID)
+	if err != nil {
+		return err
+	}
+	if purged == 0 {
+		return ErrNoData
+	}
+	return nil
+}
+
+// PurgeDeletedGraphs permanently deletes graphs that are in trash since before
+func (d *PostgresDB) PurgeDeletedGraphs(ctx context.Context, before time.Time) (int64, error) {
+	return d.purgeGraphs(ctx, "deleted < $1", before)
+}
+
+// purgeGraphs deletes graphs in trash matching the condition with all their rows in one transaction.
+// Graphs are selected and locked in the same transaction, so a graph restored meanwhile is kept
+func (d *PostgresDB) purgeGraphs(ctx context.Context, condition string, args ...interface{}) (int64, error) {
+	tx, txErr := d.database.BeginTxx(ctx, nil)
+	if txErr != nil {
+		return 0, txErr
+	}
+	defer tx.Rollback()
+
+	var graphIDs []string
+	err := tx.SelectContext(ctx, &graphIDs, `select graph_id from media.graphs
+where is_del = 1
+and `+condition+`
+for update`, args...)
+	if err != nil {
+		return 0, err
+	}
+	if len(graphIDs) == 0 {
+		return 0, nil
+	}
+
+	for _, table := range graphDependents {
+		if _, err := tx.ExecContext(ctx, "delete from "+table+" where graph_id = any($1::uuid[])", pq.Array(graphIDs)); err != nil {
+			return 0, fmt.Errorf("purge %s: %w", table, err)
+		}
+	}
+	res, err := tx.ExecContext(ctx, "delete from media.graphs where graph_id = any($1::uuid[]) and is_del = 1", pq.Array(graphIDs))
+	if err != nil {
+		return 0, fmt.Errorf("purge media.graphs: %w", err)
+	}
+	purged, _ := res.RowsAffected()
+	if commitErr := tx.Commit(); commitErr != nil {
+		return 0, commitErr
+	}
+	return purged, nil
+}
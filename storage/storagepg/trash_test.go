@@ -0,0 +1,76 @@
+package storagepg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// trashedGraph inserts a graph of the user, deleted is null for a graph trashed before it was recorded
+func trashedGraph(t *testing.T, database *PostgresDB, userID uuid.UUID, created time.Time, deleted *time.Time) uuid.UUID {
+	t.Helper()
+	graphID := uuid.New()
+	database.database.MustExec(`insert into media.graphs (user_id, graph_id, cnt_elements, created, updated, deleted, is_del)
+values ($1, $2, 1, $3, $3, $4, 1)`, userID, graphID, created, deleted)
+	database.database.MustExec("insert into media.graphs_elements (graph_id, node, num) values ($1, 1, 1)", graphID)
+	return graphID
+}
+
+// graphExists the graph is still in media.graphs
+func graphExists(t *testing.T, database *PostgresDB, graphID uuid.UUID) bool {
+	t.Helper()
+	var count int
+	if err := database.database.Get(&count, "select count(*) from media.graphs where graph_id = $1", graphID); err != nil {
+		t.Fatal(err)
+	}
+	return count > 0
+}
+
+func TestPurgeDeletedGraphs(t *testing.T) {
+	database, userID := testDB(t)
+	ctx := context.Background()
+	now := time.Now()
+	retention := 720 * time.Hour
+	longAgo := now.Add(-2 * retention)
+	recently := now.Add(-time.Hour)
+
+	expired := trashedGraph(t, database, userID, longAgo, &longAgo)
+	fresh := trashedGraph(t, database, userID, longAgo, &recently)
+	unrecorded := trashedGraph(t, database, userID, longAgo, nil)
+
+	_, err := database.PurgeDeletedGraphs(ctx, now.Add(-retention))
+	assert.NoError(t, err)
+
+	assert.False(t, graphExists(t, database, expired))
+	assert.True(t, graphExists(t, database, fresh))
+	assert.True(t, graphExists(t, database, unrecorded), "graph without deleted is purged by its creation date")
+}
+
+func TestPurgeRestoredGraph(t *testing.T) {
+	database, userID := testDB(t)
+	ctx := context.Background()
+	longAgo := time.Now().Add(-1440 * time.Hour)
+	graphID := trashedGraph(t, database, userID, longAgo, &longAgo)
+
+	// restoring holds the row while the retention job runs
+	restore, txErr := database.database.BeginTxx(ctx, nil)
+	if !assert.NoError(t, txErr) {
+		return
+	}
+	restore.MustExec("update media.graphs set is_del = 0, deleted = null where graph_id = $1", graphID)
+	purged := make(chan error)
+	go func() {
+		_, err := database.PurgeDeletedGraphs(ctx, time.Now())
+		purged <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, restore.Commit())
+
+	assert.NoError(t, <-purged)
+	assert.True(t, graphExists(t, database, graphID))
+	assert.ErrorIs(t, database.PurgeGraphCard(ctx, userID, graphID), ErrNoData)
+	assert.True(t, graphExists(t, database, graphID))
+}
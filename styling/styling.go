@@ -0,0 +1,263 @@
+// Package styling colours nodes returned by the repository.
+// A style is a theme and a rule that picks the colour of every node:
+// by its kind, platform, community or one of the calculated metrics.
+package styling
+
+import (
+	"AlexSarva/media/graphio"
+	"AlexSarva/media/models"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Rules of node colouring
+const (
+	// RuleKind colours requested sources, neighbours and path nodes differently
+	RuleKind = "kind"
+	// RulePlatform colours nodes by the platform of their URL
+	RulePlatform = "platform"
+	// RuleCommunity colours nodes by the community found by community detection
+	RuleCommunity = "community"
+)
+
+// Platforms of sources recognised by the host of their URL
+const (
+	PlatformTelegram  = "telegram"
+	PlatformVK        = "vk"
+	PlatformYouTube   = "youtube"
+	PlatformTwitter   = "twitter"
+	PlatformOK        = "ok"
+	PlatformDzen      = "dzen"
+	PlatformInstagram = "instagram"
+	PlatformWeb       = "web"
+)
+
+// platforms order of platforms in the palette and hosts of each of them
+var platforms = []struct {
+	name  string
+	hosts []string
+}{
+	{PlatformTelegram, []string{"t.me", "telegram.me", "telegram.org"}},
+	{PlatformVK, []string{"vk.com", "vk.ru", "m.vk.com"}},
+	{PlatformYouTube, []string{"youtube.com", "youtu.be", "m.youtube.com"}},
+	{PlatformTwitter, []string{"twitter.com", "x.com"}},
+	{PlatformOK, []string{"ok.ru"}},
+	{PlatformDzen, []string{"dzen.ru", "zen.yandex.ru"}},
+	{PlatformInstagram, []string{"instagram.com"}},
+	{PlatformWeb, nil},
+}
+
+// metricRules rules colouring nodes by a gradient of the metric
+var metricRules = map[string]func(node models.GraphNode) (float64, bool){
+	models.SizeByPageRank: func(node models.GraphNode) (float64, bool) {
+		if node.PageRank == nil {
+			return 0, false
+		}
+		return *node.PageRank, true
+	},
+	models.SizeByBetweenness: func(node models.GraphNode) (float64, bool) {
+		if node.Betweenness == nil {
+			return 0, false
+		}
+		return *node.Betweenness, true
+	},
+	models.SizeByInDegree: func(node models.GraphNode) (float64, bool) {
+		if node.InDegree == nil {
+			return 0, false
+		}
+		return float64(*node.InDegree), true
+	},
+	models.SizeByOutDegree: func(node models.GraphNode) (float64, bool) {
+		if node.OutDegree == nil {
+			return 0, false
+		}
+		return float64(*node.OutDegree), true
+	},
+}
+
+// ErrUnknownTheme error that occurs when the requested theme does not exist
+var ErrUnknownTheme = errors.New("unknown theme")
+
+// ErrUnknownRule error that occurs when the requested colouring rule does not exist
+var ErrUnknownRule = errors.New("unknown color rule")
+
+// Style theme and rule applied to nodes
+type Style struct {
+	Theme Theme
+	Rule  string
+}
+
+// New style of the theme and rule, empty theme is light and empty rule is kind
+func New(theme, rule string) (Style, error) {
+	if len(theme) == 0 {
+		theme = ThemeLight
+	}
+	if len(rule) == 0 {
+		rule = RuleKind
+	}
+	selected, ok := themes[theme]
+	if !ok {
+		return Style{}, fmt.Errorf("%w %q, theme must be one of %s", ErrUnknownTheme, theme, strings.Join(ThemeNames(), ", "))
+	}
+	if !IsRule(rule) {
+		return Style{}, fmt.Errorf("%w %q, color_by must be one of %s", ErrUnknownRule, rule, strings.Join(RuleNames(), ", "))
+	}
+	return Style{Theme: selected, Rule: rule}, nil
+}
+
+// RuleNames names of the available colouring rules
+func RuleNames() []string {
+	return []string{RuleKind, RulePlatform, RuleCommunity,
+		models.SizeByPageRank, models.SizeByBetweenness, models.SizeByInDegree, models.SizeByOutDegree}
+}
+
+// IsRule reports whether the rule exists
+func IsRule(rule string) bool {
+	switch rule {
+	case RuleKind, RulePlatform, RuleCommunity:
+		return true
+	}
+	return IsMetric(rule)
+}
+
+// IsMetric reports whether the rule colours by a metric, such rules need all nodes of the graph at once
+func IsMetric(rule string) bool {
+	_, ok := metricRules[rule]
+	return ok
+}
+
+// Apply colours the nodes, metric gradient is stretched between the smallest and the largest value of the nodes
+func (s Style) Apply(nodes []models.GraphNode) {
+	if metric, ok := metricRules[s.Rule]; ok {
+		s.applyMetric(nodes, metric)
+		return
+	}
+	for i := range nodes {
+		s.ApplyNode(&nodes[i])
+	}
+}
+
+// ApplyExtended colours nodes of saved graph, the list of saved sources gets the colours of the same nodes of the graph
+func (s Style) ApplyExtended(graph *models.GraphExtended) {
+	s.Apply(graph.Nodes)
+	colors := make(map[int64]interface{}, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		colors[node.ID] = node.Color
+	}
+	for i := range graph.NodesList {
+		graph.NodesList[i].Color = colors[graph.NodesList[i].ID]
+	}
+}
+
+// ApplyNode colours one node, metric rules colour it by its kind as there is nothing to compare with
+func (s Style) ApplyNode(node *models.GraphNode) {
+	switch s.Rule {
+	case RulePlatform:
+		node.Color = s.paletteColor(platformIndex(Platform(node.Label)))
+	case RuleCommunity:
+		if node.Community != nil {
+			node.Color = s.paletteColor(*node.Community)
+			return
+		}
+		node.Color = s.kindColor(node.Kind)
+	default:
+		node.Color = s.kindColor(node.Kind)
+	}
+}
+
+func (s Style) applyMetric(nodes []models.GraphNode, metric func(node models.GraphNode) (float64, bool)) {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, node := range nodes {
+		if value, ok := metric(node); ok {
+			low, high = math.Min(low, value), math.Max(high, value)
+		}
+	}
+	from, _ := graphio.ParseColor(s.Theme.Low)
+	to, _ := graphio.ParseColor(s.Theme.High)
+	for i := range nodes {
+		value, ok := metric(nodes[i])
+		if !ok {
+			nodes[i].Color = s.kindColor(nodes[i].Kind)
+			continue
+		}
+		share := 1.0
+		if high > low {
+			share = (value - low) / (high - low)
+		}
+		nodes[i].Color = s.backgroundColor(formatColor(mix(from, to, share)))
+	}
+}
+
+// kindColor style of the node kind, nodes without kind are styled as neighbours
+func (s Style) kindColor(kind string) models.GraphNodeColor {
+	if color, ok := s.Theme.Kinds[kind]; ok {
+		return color
+	}
+	return s.Theme.Kinds[models.NodeKindSub]
+}
+
+// paletteColor style of the category with the background from the palette, repeated for large indexes
+func (s Style) paletteColor(index int) models.GraphNodeColor {
+	if index < 0 {
+		index = -index
+	}
+	return s.backgroundColor(s.Theme.Palette[index%len(s.Theme.Palette)])
+}
+
+// backgroundColor style with the background used as border and hover too
+func (s Style) backgroundColor(background string) models.GraphNodeColor {
+	return kindColor(background, background, s.Theme.Highlight, s.Theme.HoverBorder)
+}
+
+// Platform platform of the source by the host of its URL, unknown hosts are web
+func Platform(url string) string {
+	host := strings.ToLower(strings.TrimSpace(url))
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	host = strings.TrimPrefix(host, "www.")
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	for _, platform := range platforms {
+		for _, known := range platform.hosts {
+			if host == known {
+				return platform.name
+			}
+		}
+	}
+	return PlatformWeb
+}
+
+// platformIndex position of the platform in the palette
+func platformIndex(name string) int {
+	for i, platform := range platforms {
+		if platform.name == name {
+			return i
+		}
+	}
+	return len(platforms) - 1
+}
+
+// mix linear interpolation between two colours
+func mix(from, to graphio.RGBA, share float64) graphio.RGBA {
+	channel := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*share))
+	}
+	return graphio.RGBA{
+		R: channel(from.R, to.R),
+		G: channel(from.G, to.G),
+		B: channel(from.B, to.B),
+		A: from.A + (to.A-from.A)*share,
+	}
+}
+
+// formatColor css representation of the colour
+func formatColor(color graphio.RGBA) string {
+	if color.A >= 1 {
+		return fmt.Sprintf("rgb(%d, %d, %d)", color.R, color.G, color.B)
+	}
+	return fmt.Sprintf("rgba(%d, %d, %d, %g)", color.R, color.G, color.B, math.Round(color.A*100)/100)
+}
@@ -0,0 +1,87 @@
+package styling
+
+import (
+	"AlexSarva/media/models"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		theme   string
+		rule    string
+		want    string
+		wantErr error
+	}{
+		{name: "defaults", want: ThemeLight},
+		{name: "colour blind by pagerank", theme: ThemeColorBlind, rule: models.SizeByPageRank, want: ThemeColorBlind},
+		{name: "unknown theme", theme: "neon", wantErr: ErrUnknownTheme},
+		{name: "unknown rule", rule: "size", wantErr: ErrUnknownRule},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style, err := New(tt.theme, tt.rule)
+			assert.True(t, errors.Is(err, tt.wantErr))
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.want, style.Theme.Name)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	community := 11
+	low, high := 0.1, 0.3
+	nodes := []models.GraphNode{
+		{ID: 1, Label: "https://t.me/news", Kind: models.NodeKindMain, PageRank: &low},
+		{ID: 2, Label: "vk.com/club1", Kind: models.NodeKindSub, Community: &community, PageRank: &high},
+		{ID: 3, Label: "example.com/feed", Kind: models.NodeKindIsolated},
+	}
+	background := func(node models.GraphNode) string {
+		return node.Color.(models.GraphNodeColor).Background
+	}
+
+	kind, _ := New(ThemeLight, RuleKind)
+	kind.Apply(nodes)
+	assert.Equal(t, "rgba(8, 217, 174, 0.9)", background(nodes[0]))
+	assert.Equal(t, "rgba(252, 213, 173, 0.9)", background(nodes[1]))
+	assert.Equal(t, "rgba(155, 168, 171, 0.9)", background(nodes[2]))
+
+	byCommunity, _ := New(ThemeLight, RuleCommunity)
+	byCommunity.Apply(nodes)
+	assert.Equal(t, "rgba(8, 217, 174, 0.9)", background(nodes[0]))
+	assert.Equal(t, "rgba(252, 213, 173, 0.9)", background(nodes[1]))
+
+	byPlatform, _ := New(ThemeColorBlind, RulePlatform)
+	byPlatform.Apply(nodes)
+	assert.Equal(t, "rgb(0, 114, 178)", background(nodes[0]))
+	assert.Equal(t, "rgb(230, 159, 0)", background(nodes[1]))
+	assert.Equal(t, "rgb(153, 153, 153)", background(nodes[2]))
+
+	byPageRank, _ := New(ThemePrint, models.SizeByPageRank)
+	byPageRank.Apply(nodes)
+	assert.Equal(t, "rgb(238, 238, 238)", background(nodes[0]))
+	assert.Equal(t, "rgb(33, 33, 33)", background(nodes[1]))
+	assert.Equal(t, "rgb(255, 255, 255)", background(nodes[2]))
+}
+
+func TestPlatform(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://t.me/news", want: PlatformTelegram},
+		{url: "www.youtube.com/@channel", want: PlatformYouTube},
+		{url: "VK.com/club1", want: PlatformVK},
+		{url: "https://x.com/user?lang=ru", want: PlatformTwitter},
+		{url: "https://lenta.ru/rss", want: PlatformWeb},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.want, Platform(tt.url))
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package styling
+
+import "AlexSarva/media/models"
+
+// Names of the themes
+const (
+	ThemeLight      = "light"
+	ThemeDark       = "dark"
+	ThemePrint      = "print"
+	ThemeColorBlind = "colorblind"
+)
+
+// Theme colours of one visual theme: styles of node kinds, palette of categories
+// used for communities and platforms and the gradient of metrics from Low to High
+type Theme struct {
+	Name        string
+	Kinds       map[string]models.GraphNodeColor
+	Palette     []string
+	Highlight   string
+	HoverBorder string
+	Low         string
+	High        string
+}
+
+// kindColor style of a node kind in the theme with the same border, highlight and hover as palette colours
+func kindColor(background, border, highlight, hoverBorder string) models.GraphNodeColor {
+	return models.GraphNodeColor{
+		Background: background,
+		Border:     border,
+		Highlight: models.GraphNodeColorStyle{
+			Background: highlight,
+			Border:     highlight,
+		},
+		Hover: models.GraphNodeColorStyle{
+			Background: background,
+			Border:     hoverBorder,
+		},
+	}
+}
+
+// themes available themes by name, light keeps the colours the frontend was designed with
+var themes = map[string]Theme{
+	ThemeLight: {
+		Name: ThemeLight,
+		Kinds: map[string]models.GraphNodeColor{
+			models.NodeKindMain: kindColor("rgba(8, 217, 174, 0.9)", "rgba(96, 169, 191, 0.8)", "rgb(187, 163, 217)", "rgb(211, 114, 214)"),
+			models.NodeKindSub:  kindColor("rgba(252, 213, 173, 0.9)", "rgb(252, 213, 173)", "rgb(187, 163, 217)", "rgb(211, 114, 214)"),
+			models.NodeKindPath: kindColor("rgb(187, 163, 217)", "rgb(211, 114, 214)", "rgb(187, 163, 217)", "rgb(211, 114, 214)"),
+			models.NodeKindIsolated: {
+				Background: "rgba(155, 168, 171, 0.9)",
+				Border:     "rgba(155, 168, 171, 0.9)",
+				Highlight: models.GraphNodeColorStyle{
+					Background: "rgba(155, 168, 171, 0.9)",
+					Border:     "rgba(155, 168, 171, 0.9)",
+				},
+				Hover: models.GraphNodeColorStyle{
+					Background: "rgba(8, 217, 174, 0.9)",
+					Border:     "rgb(211, 114, 214)",
+				},
+			},
+		},
+		Palette: []string{
+			"rgba(8, 217, 174, 0.9)",
+			"rgba(252, 213, 173, 0.9)",
+			"rgba(187, 163, 217, 0.9)",
+			"rgba(133, 193, 233, 0.9)",
+			"rgba(247, 143, 143, 0.9)",
+			"rgba(169, 223, 191, 0.9)",
+			"rgba(249, 231, 159, 0.9)",
+			"rgba(215, 189, 226, 0.9)",
+			"rgba(174, 182, 191, 0.9)",
+			"rgba(240, 178, 122, 0.9)",
+		},
+		Highlight:   "rgb(187, 163, 217)",
+		HoverBorder: "rgb(211, 114, 214)",
+		Low:         "rgba(252, 213, 173, 0.9)",
+		High:        "rgba(8, 217, 174, 0.9)",
+	},
+	ThemeDark: {
+		Name: ThemeDark,
+		Kinds: map[string]models.GraphNodeColor{
+			models.NodeKindMain:     kindColor("rgba(29, 233, 182, 0.9)", "rgb(167, 255, 235)", "rgb(179, 157, 219)", "rgb(255, 255, 255)"),
+			models.NodeKindSub:      kindColor("rgba(92, 107, 192, 0.9)", "rgb(121, 134, 203)", "rgb(179, 157, 219)", "rgb(255, 255, 255)"),
+			models.NodeKindPath:     kindColor("rgb(255, 202, 40)", "rgb(255, 224, 130)", "rgb(179, 157, 219)", "rgb(255, 255, 255)"),
+			models.NodeKindIsolated: kindColor("rgba(97, 97, 97, 0.9)", "rgb(117, 117, 117)", "rgb(179, 157, 219)", "rgb(255, 255, 255)"),
+		},
+		Palette: []string{
+			"rgba(29, 233, 182, 0.9)",
+			"rgba(255, 202, 40, 0.9)",
+			"rgba(179, 157, 219, 0.9)",
+			"rgba(79, 195, 247, 0.9)",
+			"rgba(239, 83, 80, 0.9)",
+			"rgba(156, 204, 101, 0.9)",
+			"rgba(255, 138, 101, 0.9)",
+			"rgba(240, 98, 146, 0.9)",
+			"rgba(144, 164, 174, 0.9)",
+			"rgba(77, 208, 225, 0.9)",
+		},
+		Highlight:   "rgb(179, 157, 219)",
+		HoverBorder: "rgb(255, 255, 255)",
+		Low:         "rgba(57, 73, 171, 0.9)",
+		High:        "rgba(29, 233, 182, 0.9)",
+	},
+	ThemePrint: {
+		Name: ThemePrint,
+		Kinds: map[string]models.GraphNodeColor{
+			models.NodeKindMain:     kindColor("rgb(33, 33, 33)", "rgb(0, 0, 0)", "rgb(97, 97, 97)", "rgb(0, 0, 0)"),
+			models.NodeKindSub:      kindColor("rgb(224, 224, 224)", "rgb(97, 97, 97)", "rgb(97, 97, 97)", "rgb(0, 0, 0)"),
+			models.NodeKindPath:     kindColor("rgb(117, 117, 117)", "rgb(0, 0, 0)", "rgb(97, 97, 97)", "rgb(0, 0, 0)"),
+			models.NodeKindIsolated: kindColor("rgb(255, 255, 255)", "rgb(97, 97, 97)", "rgb(97, 97, 97)", "rgb(0, 0, 0)"),
+		},
+		Palette: []string{
+			"rgb(33, 33, 33)",
+			"rgb(189, 189, 189)",
+			"rgb(97, 97, 97)",
+			"rgb(238, 238, 238)",
+			"rgb(66, 66, 66)",
+			"rgb(158, 158, 158)",
+			"rgb(117, 117, 117)",
+			"rgb(224, 224, 224)",
+		},
+		Highlight:   "rgb(97, 97, 97)",
+		HoverBorder: "rgb(0, 0, 0)",
+		Low:         "rgb(238, 238, 238)",
+		High:        "rgb(33, 33, 33)",
+	},
+	// Okabe-Ito palette distinguishable with the common forms of colour blindness
+	ThemeColorBlind: {
+		Name: ThemeColorBlind,
+		Kinds: map[string]models.GraphNodeColor{
+			models.NodeKindMain:     kindColor("rgb(0, 114, 178)", "rgb(0, 0, 0)", "rgb(204, 121, 167)", "rgb(0, 0, 0)"),
+			models.NodeKindSub:      kindColor("rgb(230, 159, 0)", "rgb(230, 159, 0)", "rgb(204, 121, 167)", "rgb(0, 0, 0)"),
+			models.NodeKindPath:     kindColor("rgb(204, 121, 167)", "rgb(0, 0, 0)", "rgb(204, 121, 167)", "rgb(0, 0, 0)"),
+			models.NodeKindIsolated: kindColor("rgb(153, 153, 153)", "rgb(153, 153, 153)", "rgb(204, 121, 167)", "rgb(0, 0, 0)"),
+		},
+		Palette: []string{
+			"rgb(0, 114, 178)",
+			"rgb(230, 159, 0)",
+			"rgb(0, 158, 115)",
+			"rgb(86, 180, 233)",
+			"rgb(213, 94, 0)",
+			"rgb(240, 228, 66)",
+			"rgb(204, 121, 167)",
+			"rgb(153, 153, 153)",
+		},
+		Highlight:   "rgb(204, 121, 167)",
+		HoverBorder: "rgb(0, 0, 0)",
+		Low:         "rgb(240, 228, 66)",
+		High:        "rgb(0, 114, 178)",
+	},
+}
+
+// ThemeNames names of the available themes
+func ThemeNames() []string {
+	return []string{ThemeLight, ThemeDark, ThemePrint, ThemeColorBlind}
+}
@@ -0,0 +1,45 @@
+package urlutils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// normalizeSteps patterns removed one after another from the lower-cased URL:
+// surrounding spaces, query and fragment, scheme, www. and trailing slashes.
+// Normalize and NormalizeSQL are both built from them, the patterns use only the syntax
+// that means the same in Go and in postgres regular expressions
+var normalizeSteps = []string{
+	`^[ \t\r\n]+|[ \t\r\n]+$`,
+	`(?s)[?#].*$`,
+	`^[a-z0-9+.-]*://`,
+	`^www\.`,
+	`/+$`,
+}
+
+var normalizeRegexps = func() []*regexp.Regexp {
+	regexps := make([]*regexp.Regexp, 0, len(normalizeSteps))
+	for _, step := range normalizeSteps {
+		regexps = append(regexps, regexp.MustCompile(step))
+	}
+	return regexps
+}()
+
+// Normalize brings source URL to the form used for matching with analytics.graph_nodes:
+// lower case, without surrounding spaces, scheme, www., query, fragment and trailing slashes
+func Normalize(raw string) string {
+	url := strings.ToLower(raw)
+	for _, step := range normalizeRegexps {
+		url = step.ReplaceAllString(url, "")
+	}
+	return url
+}
+
+// NormalizeSQL postgres expression of Normalize for the column
+func NormalizeSQL(column string) string {
+	expression := "lower(" + column + ")"
+	for _, step := range normalizeSteps {
+		expression = "regexp_replace(" + expression + ", '" + step + "', '', 'g')"
+	}
+	return expression
+}
@@ -0,0 +1,54 @@
+package urlutils
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// normalizeCases URLs with their normalized form
+var normalizeCases = []struct {
+	raw  string
+	want string
+}{
+	{"https://t.me/channel", "t.me/channel"},
+	{" HTTP://WWW.Lenta.ru/ ", "lenta.ru"},
+	{"\thttps://t.me/channel\r\n", "t.me/channel"},
+	{"vk.com/club1?from=search#top", "vk.com/club1"},
+	{"vk.com/club1#top?from=search", "vk.com/club1"},
+	{"https://site.ru/?r=http://other.ru", "site.ru"},
+	{"t.me/channel//", "t.me/channel"},
+	{"site.ru/go/http://other.ru", "site.ru/go/http://other.ru"},
+	{"www.www.site.ru", "www.site.ru"},
+	{"svn+ssh://Repo.ru/", "repo.ru"},
+	{"", ""},
+}
+
+func TestNormalize(t *testing.T) {
+	for _, tt := range normalizeCases {
+		assert.Equal(t, tt.want, Normalize(tt.raw), tt.raw)
+	}
+}
+
+// TestNormalizeSQL compares NormalizeSQL with Normalize in postgres of DATABASE_PG_URI
+func TestNormalizeSQL(t *testing.T) {
+	uri := os.Getenv("DATABASE_PG_URI")
+	if uri == "" {
+		t.Skip("DATABASE_PG_URI is not set")
+	}
+	db, err := sql.Open("postgres", uri)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	for _, tt := range normalizeCases {
+		var got string
+		if assert.NoError(t, db.QueryRow("select "+NormalizeSQL("$1::text"), tt.raw).Scan(&got)) {
+			assert.Equal(t, Normalize(tt.raw), got, tt.raw)
+		}
+	}
+}